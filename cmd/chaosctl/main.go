@@ -0,0 +1,261 @@
+// Command chaosctl lists, inspects, watches and cancels chaos workflows using the workflows service API.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/iskorotkov/chaos-workflows/pkg/client"
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+	"golang.org/x/term"
+)
+
+const usage = `Usage: chaosctl <command> [flags] [args]
+
+Commands:
+  list                      list workflows
+  get <namespace> <name>    show workflow with its stages and steps
+  watch [namespace [name]]  show workflow events as they happen
+  cancel <namespace> <name> stop workflow and run its exit handlers
+
+Server address and token are read from CHAOSCTL_SERVER and CHAOSCTL_TOKEN env vars if flags aren't set.
+Run "chaosctl <command> -h" to see command flags.
+`
+
+// requestTimeout is a default timeout of commands sending a single request.
+const requestTimeout = 30 * time.Second
+
+// commonOptions are flags supported by all commands.
+type commonOptions struct {
+	server  string
+	token   string
+	output  string
+	timeout time.Duration
+}
+
+// addCommonFlags adds flags supported by all commands to fs.
+func addCommonFlags(fs *flag.FlagSet, timeout time.Duration) *commonOptions {
+	server := os.Getenv("CHAOSCTL_SERVER")
+	if server == "" {
+		server = "http://localhost:8811/api/v1"
+	}
+
+	opts := &commonOptions{}
+	fs.StringVar(&opts.server, "server", server, "base URL of the workflows API")
+	fs.StringVar(&opts.token, "token", os.Getenv("CHAOSCTL_TOKEN"), "bearer token sent to the server")
+	fs.StringVar(&opts.output, "o", formatTable, "output format: table, json or yaml")
+	fs.DurationVar(&opts.timeout, "timeout", timeout, "timeout of the command; it isn't limited if 0")
+	return opts
+}
+
+// client returns API client configured with common options.
+func (o commonOptions) client() client.Client {
+	token := o.token
+	if token != "" && !strings.Contains(token, " ") {
+		token = "Bearer " + token
+	}
+
+	return client.New(o.server, client.Options{Token: token})
+}
+
+// context returns context cancelled on interrupt or when timeout expires.
+func (o commonOptions) context() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	if o.timeout == 0 {
+		return ctx, stop
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, o.timeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+// run executes command given its arguments.
+func run(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return errors.New("command is required")
+	}
+
+	commands := map[string]func([]string, io.Writer) error{
+		"list":   runList,
+		"get":    runGet,
+		"watch":  runWatch,
+		"cancel": runCancel,
+	}
+
+	command, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+
+	return command(args[1:], out)
+}
+
+func runList(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	common := addCommonFlags(fs, requestTimeout)
+
+	var opts client.ListOptions
+	var statuses string
+	var since time.Duration
+	fs.StringVar(&opts.Namespace, "n", "", "namespace; all namespaces are used if empty")
+	fs.StringVar(&opts.Selector, "l", "", "label selector, e.g. team=payments")
+	fs.StringVar(&statuses, "status", "", "comma-separated statuses, e.g. running,failed")
+	fs.StringVar(&opts.ChaosType, "type", "", "chaos type of at least one step")
+	fs.StringVar(&opts.Severity, "severity", "", "severity of at least one step")
+	fs.DurationVar(&since, "since", 0, "list only workflows started within duration, e.g. 24h")
+	fs.StringVar(&opts.Sort, "sort", "-startedAt", "sort order: name, startedAt or finishedAt, prefixed with - for descending order; pages aren't sorted unless it's set")
+	fs.Int64Var(&opts.Limit, "limit", 0, "max number of workflows to return")
+	fs.StringVar(&opts.Continue, "continue", "", "token of the next page")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// Server doesn't sort pages, so the default order is dropped when paging.
+	sortSet := false
+	fs.Visit(func(f *flag.Flag) {
+		sortSet = sortSet || f.Name == "sort"
+	})
+
+	if !sortSet && (opts.Limit != 0 || opts.Continue != "") {
+		opts.Sort = ""
+	}
+
+	if statuses != "" {
+		opts.Statuses = strings.Split(statuses, ",")
+	}
+
+	if since != 0 {
+		opts.StartedAfter = time.Now().Add(-since)
+	}
+
+	ctx, cancel := common.context()
+	defer cancel()
+
+	workflows, next, err := common.client().List(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	if err := printWorkflows(out, common.output, workflows); err != nil {
+		return err
+	}
+
+	if next != "" {
+		fmt.Fprintf(os.Stderr, "more workflows available, use -continue %s\n", next)
+	}
+
+	return nil
+}
+
+func runGet(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("get", flag.ContinueOnError)
+	common := addCommonFlags(fs, requestTimeout)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 2 {
+		return errors.New("namespace and name are required")
+	}
+
+	ctx, cancel := common.context()
+	defer cancel()
+
+	wf, err := common.client().Get(ctx, fs.Arg(0), fs.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	return printWorkflow(out, common.output, wf)
+}
+
+func runCancel(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("cancel", flag.ContinueOnError)
+	common := addCommonFlags(fs, requestTimeout)
+	reason := fs.String("reason", "", "reason of cancellation saved in workflow message")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 2 {
+		return errors.New("namespace and name are required")
+	}
+
+	ctx, cancel := common.context()
+	defer cancel()
+
+	wf, err := common.client().Cancel(ctx, fs.Arg(0), fs.Arg(1), *reason)
+	if err != nil {
+		return err
+	}
+
+	return printWorkflow(out, common.output, wf)
+}
+
+func runWatch(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	common := addCommonFlags(fs, 0)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() > 2 {
+		return errors.New("too many arguments")
+	}
+
+	namespace, name := fs.Arg(0), fs.Arg(1)
+
+	ctx, cancel := common.context()
+	defer cancel()
+
+	reader, err := common.client().Watch(ctx, namespace, name)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	// Table is redrawn in place on terminals and printed after every event otherwise.
+	live := false
+	if f, ok := out.(*os.File); ok && common.output == formatTable {
+		live = term.IsTerminal(int(f.Fd()))
+	}
+
+	view := newWatchView(out, common.output, name != "", live)
+	for {
+		ev, err := reader.Read()
+		if err != nil && err != event.ErrAllRead {
+			if errors.Is(err, event.ErrDeadlineExceeded) && ctx.Err() != nil {
+				return nil
+			}
+
+			return err
+		}
+
+		if err := view.show(ev); err != nil {
+			return err
+		}
+
+		if err == event.ErrAllRead {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+	"sigs.k8s.io/yaml"
+)
+
+// Output formats.
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatYAML  = "yaml"
+)
+
+// clearScreen moves cursor to the top left corner and clears terminal.
+const clearScreen = "\033[H\033[2J"
+
+// printWorkflows prints list of workflows in format.
+func printWorkflows(out io.Writer, format string, workflows []event.Workflow) error {
+	if format == formatTable {
+		return writeTable(out, workflows)
+	}
+
+	if workflows == nil {
+		workflows = []event.Workflow{}
+	}
+
+	return writeValue(out, format, workflows)
+}
+
+// printWorkflow prints workflow in format. Table format shows tree of stages and steps.
+func printWorkflow(out io.Writer, format string, wf event.Workflow) error {
+	if format == formatTable {
+		return writeTree(out, wf)
+	}
+
+	return writeValue(out, format, wf)
+}
+
+// writeValue writes v as indented JSON or YAML.
+func writeValue(out io.Writer, format string, v interface{}) error {
+	var b []byte
+	var err error
+	switch format {
+	case formatJSON:
+		b, err = json.MarshalIndent(v, "", "  ")
+		b = append(b, '\n')
+	case formatYAML:
+		b, err = yaml.Marshal(v)
+	default:
+		return fmt.Errorf("unsupported output format %q", format)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	_, err = out.Write(b)
+	return err
+}
+
+// writeTable writes workflows as a table with a row per workflow.
+func writeTable(out io.Writer, workflows []event.Workflow) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tNAME\tSTATUS\tSTARTED\tDURATION")
+
+	for _, wf := range workflows {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", wf.Namespace, wf.Name, wf.Status, formatTime(wf.StartedAt), duration(wf.StartedAt, wf.FinishedAt))
+	}
+
+	return w.Flush()
+}
+
+// writeTree writes workflow with its stages and steps as a tree.
+func writeTree(out io.Writer, wf event.Workflow) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s/%s  %s  started %s  %s\n", wf.Namespace, wf.Name, wf.Status, formatTime(wf.StartedAt), duration(wf.StartedAt, wf.FinishedAt))
+	writeStages(&b, "", wf.Stages)
+
+	_, err := io.WriteString(out, b.String())
+	return err
+}
+
+// writeStages writes stages and their steps as tree branches prefixed with indent.
+func writeStages(b *strings.Builder, indent string, stages []event.Stage) {
+	for i, stage := range stages {
+		branch, next := treeBranch(indent, i == len(stages)-1)
+		fmt.Fprintf(b, "%sStage %d  %s  %s%s\n", branch, i+1, stage.Status, duration(stage.StartedAt, stage.FinishedAt), messageSuffix(stage.Message))
+
+		for j, step := range stage.Steps {
+			writeStep(b, next, step, j == len(stage.Steps)-1)
+		}
+	}
+}
+
+// writeStep writes step with its attempts and nested stages.
+func writeStep(b *strings.Builder, indent string, step event.Step, last bool) {
+	branch, next := treeBranch(indent, last)
+
+	name := step.DisplayName
+	if name == "" {
+		name = step.Name
+	}
+
+	chaos := ""
+	if step.Type != "" || step.Severity != "" {
+		chaos = fmt.Sprintf("  [%s/%s]", step.Type, step.Severity)
+	}
+
+	fmt.Fprintf(b, "%s%s  %s%s  %s%s\n", branch, name, step.Status, chaos, duration(step.StartedAt, step.FinishedAt), messageSuffix(step.Message))
+
+	for i, attempt := range step.Attempts {
+		attemptBranch, _ := treeBranch(next, i == len(step.Attempts)-1 && len(step.Stages) == 0)
+		fmt.Fprintf(b, "%sattempt %d  %s  %s%s\n", attemptBranch, attempt.Number, attempt.Status, duration(attempt.StartedAt, attempt.FinishedAt), messageSuffix(attempt.Message))
+	}
+
+	writeStages(b, next, step.Stages)
+}
+
+// treeBranch returns prefix of a tree node and indent of its children.
+func treeBranch(indent string, last bool) (string, string) {
+	if last {
+		return indent + "└── ", indent + "    "
+	}
+
+	return indent + "├── ", indent + "│   "
+}
+
+// messageSuffix returns message formatted to be appended to a tree node.
+func messageSuffix(message string) string {
+	if message == "" {
+		return ""
+	}
+
+	return "  " + strings.ReplaceAll(message, "\n", " ")
+}
+
+// formatTime returns t in local time zone, or "-" if t is zero.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+
+	return t.Local().Format("2006-01-02 15:04:05")
+}
+
+// duration returns time elapsed between start and finish, or between start and now if finish isn't set.
+func duration(start time.Time, finish *time.Time) string {
+	if start.IsZero() {
+		return "-"
+	}
+
+	end := time.Now()
+	if finish != nil {
+		end = *finish
+	}
+
+	return end.Sub(start).Truncate(time.Second).String()
+}
+
+// watchView shows workflow events.
+type watchView struct {
+	out    io.Writer
+	format string
+	// single is set when events of one workflow are shown.
+	single bool
+	// live is set when table is redrawn in place.
+	live bool
+
+	workflows map[string]event.Workflow
+}
+
+func newWatchView(out io.Writer, format string, single, live bool) *watchView {
+	return &watchView{
+		out:       out,
+		format:    format,
+		single:    single,
+		live:      live,
+		workflows: make(map[string]event.Workflow),
+	}
+}
+
+// show writes event.
+func (v *watchView) show(ev event.Workflow) error {
+	switch v.format {
+	case formatJSON:
+		// Events are written on separate lines, so output can be processed line by line.
+		b, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintf(v.out, "%s\n", b)
+		return err
+	case formatYAML:
+		if _, err := io.WriteString(v.out, "---\n"); err != nil {
+			return err
+		}
+
+		return writeValue(v.out, formatYAML, ev)
+	case formatTable:
+	default:
+		return fmt.Errorf("unsupported output format %q", v.format)
+	}
+
+	if v.single {
+		if v.live {
+			if _, err := io.WriteString(v.out, clearScreen); err != nil {
+				return err
+			}
+		}
+
+		return writeTree(v.out, ev)
+	}
+
+	if !v.live {
+		_, err := fmt.Fprintf(v.out, "%-9s %s/%s  %s  %s\n", ev.Type, ev.Namespace, ev.Name, ev.Status, duration(ev.StartedAt, ev.FinishedAt))
+		return err
+	}
+
+	key := ev.Namespace + "/" + ev.Name
+	if ev.Type == "DELETED" {
+		delete(v.workflows, key)
+	} else {
+		v.workflows[key] = ev
+	}
+
+	workflows := make([]event.Workflow, 0, len(v.workflows))
+	for _, wf := range v.workflows {
+		workflows = append(workflows, wf)
+	}
+
+	sort.Slice(workflows, func(i, j int) bool {
+		if !workflows[i].StartedAt.Equal(workflows[j].StartedAt) {
+			return workflows[i].StartedAt.After(workflows[j].StartedAt)
+		}
+
+		return workflows[i].Name < workflows[j].Name
+	})
+
+	if _, err := io.WriteString(v.out, clearScreen); err != nil {
+		return err
+	}
+
+	return writeTable(v.out, workflows)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+	"sigs.k8s.io/yaml"
+)
+
+func testWorkflow() event.Workflow {
+	start := time.Date(2021, 11, 1, 0, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		t := start.Add(d)
+		return &t
+	}
+
+	return event.Workflow{
+		Name:       "pod-delete-x7k2f",
+		Namespace:  "chaos",
+		StartedAt:  start,
+		FinishedAt: at(time.Minute),
+		Status:     "failed",
+		Stages: []event.Stage{
+			{
+				Status:     "succeeded",
+				StartedAt:  start,
+				FinishedAt: at(30 * time.Second),
+				Steps: []event.Step{
+					{DisplayName: "pod-delete", Type: "pod-delete", Severity: "critical", Status: "succeeded", StartedAt: start, FinishedAt: at(12 * time.Second)},
+					{
+						DisplayName: "checks",
+						Status:      "succeeded",
+						StartedAt:   start,
+						FinishedAt:  at(30 * time.Second),
+						Stages: []event.Stage{
+							{Status: "succeeded", StartedAt: start, FinishedAt: at(30 * time.Second), Steps: []event.Step{
+								{DisplayName: "http-check", Status: "succeeded", StartedAt: start, FinishedAt: at(30 * time.Second)},
+							}},
+						},
+					},
+				},
+			},
+			{
+				Status:     "failed",
+				Message:    "child failed",
+				StartedAt:  *at(30 * time.Second),
+				FinishedAt: at(time.Minute),
+				Steps: []event.Step{
+					{
+						DisplayName: "network-loss",
+						Status:      "failed",
+						StartedAt:   *at(30 * time.Second),
+						FinishedAt:  at(time.Minute),
+						Attempts: []event.Attempt{
+							{Number: 1, Status: "failed", Message: "exit code 1", StartedAt: *at(30 * time.Second), FinishedAt: at(time.Minute)},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func Test_writeTree(t *testing.T) {
+	t.Parallel()
+
+	wf := testWorkflow()
+
+	var b strings.Builder
+	if err := writeTree(&b, wf); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "chaos/pod-delete-x7k2f  failed  started " + formatTime(wf.StartedAt) + "  1m0s\n" +
+		"├── Stage 1  succeeded  30s\n" +
+		"│   ├── pod-delete  succeeded  [pod-delete/critical]  12s\n" +
+		"│   └── checks  succeeded  30s\n" +
+		"│       └── Stage 1  succeeded  30s\n" +
+		"│           └── http-check  succeeded  30s\n" +
+		"└── Stage 2  failed  30s  child failed\n" +
+		"    └── network-loss  failed  30s\n" +
+		"        └── attempt 1  failed  30s  exit code 1\n"
+
+	if got := b.String(); got != want {
+		t.Errorf("writeTree() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func Test_printWorkflows(t *testing.T) {
+	t.Parallel()
+
+	workflows := []event.Workflow{testWorkflow()}
+
+	tests := []struct {
+		format string
+		check  func(output string) bool
+	}{
+		{format: formatTable, check: func(output string) bool {
+			lines := strings.Split(strings.TrimSpace(output), "\n")
+			return len(lines) == 2 && strings.HasPrefix(lines[0], "NAMESPACE") && strings.HasPrefix(lines[1], "chaos ")
+		}},
+		{format: formatJSON, check: func(output string) bool {
+			var decoded []event.Workflow
+			return json.Unmarshal([]byte(output), &decoded) == nil && len(decoded) == 1 && decoded[0].Stages[1].Message == "child failed"
+		}},
+		{format: formatYAML, check: func(output string) bool {
+			var decoded []event.Workflow
+			return yaml.Unmarshal([]byte(output), &decoded) == nil && len(decoded) == 1 && decoded[0].Name == "pod-delete-x7k2f"
+		}},
+		{format: "xml", check: func(output string) bool { return false }},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.format, func(t *testing.T) {
+			t.Parallel()
+
+			var b strings.Builder
+			err := printWorkflows(&b, tt.format, workflows)
+			if (err != nil) != (tt.format == "xml") {
+				t.Fatalf("printWorkflows() error = %v", err)
+			}
+
+			if err == nil && !tt.check(b.String()) {
+				t.Errorf("unexpected output:\n%s", b.String())
+			}
+		})
+	}
+}
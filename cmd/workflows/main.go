@@ -1,10 +1,14 @@
 package main
 
 import (
+	"context"
+	"io"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"runtime/debug"
+	"syscall"
 	"time"
 
 	"github.com/go-chi/chi"
@@ -13,11 +17,20 @@ import (
 	"github.com/iskorotkov/chaos-workflows/internal/config"
 	"github.com/iskorotkov/chaos-workflows/internal/handlers"
 	"github.com/iskorotkov/chaos-workflows/pkg/argo"
+	"github.com/iskorotkov/chaos-workflows/pkg/eventhub"
+	"github.com/iskorotkov/chaos-workflows/pkg/eventrec"
+	"github.com/iskorotkov/chaos-workflows/pkg/eventsse"
 	"github.com/iskorotkov/chaos-workflows/pkg/eventws"
+	"github.com/iskorotkov/chaos-workflows/pkg/history"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	_ "go.uber.org/automaxprocs"
 	"go.uber.org/zap"
 )
 
+// metricsTimeout limits time spent on listing workflows during metrics scrape.
+const metricsTimeout = 10 * time.Second
+
 func main() {
 	// Handle panics.
 	defer func() {
@@ -39,31 +52,164 @@ func main() {
 	logger := createLogger(cfg)
 	defer syncLogger(logger)
 
-	logger.Infow("config loaded from environment", "config", cfg)
+	logger.Infow("config loaded from environment", "config", cfg.Redacted())
+
+	tp, err := setupTracing(cfg)
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
 
 	logger.Debug("setup external dependencies")
-	argoClient, err := argo.NewClient(cfg.ArgoServer, logger.Named("argo"))
+	argoClient, err := argo.NewClient(argo.Options{
+		URL:                cfg.ArgoServer,
+		HTTP1:              cfg.ArgoHTTP1,
+		Secure:             cfg.ArgoSecure,
+		InsecureSkipVerify: cfg.ArgoInsecureSkipVerify,
+		CAFile:             cfg.ArgoCAFile,
+		Token:              cfg.ArgoToken,
+		TokenFile:          cfg.ArgoTokenFile,
+		ForwardToken:       cfg.ArgoForwardToken,
+		Kubeconfig:         cfg.Kubeconfig,
+		Retry: argo.RetryOptions{
+			Attempts:   cfg.ArgoWatchRetries,
+			Backoff:    cfg.ArgoWatchBackoff,
+			MaxBackoff: cfg.ArgoWatchMaxBackoff,
+		},
+		Archive: cfg.ArgoArchive,
+	}, logger.Named("argo"))
 	if err != nil {
 		logger.Fatal("couldn't create reader and/or writer")
 	}
 
-	wsFactory := eventws.NewWebsocketFactory(logger.Named("websockets"))
+	// Tokens of different users must not share the same Argo stream.
+	var partition func(ctx context.Context) string
+	if cfg.ArgoForwardToken {
+		partition = argo.ForwardedToken
+	}
+
+	hub := eventhub.NewHub(argoClient, partition, logger.Named("hub"))
+
+	// Context is cancelled when the server is asked to stop, e.g. during rolling update.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	// History is optional, as it requires a persistent volume.
+	var store *history.Store
+	if cfg.HistoryPath != "" {
+		s, err := history.NewStore(cfg.HistoryPath, logger.Named("history"))
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+		defer closeWithLogger(s, logger)
+
+		store = &s
+
+		// Sweeps aren't made on behalf of users, so they require the service token like the metrics collector.
+		switch {
+		case cfg.HistorySweepInterval == 0:
+		case !argoClient.ServiceAuthorized():
+			logger.Warn("history sweeping is disabled: ARGO_FORWARD_TOKEN is set without ARGO_TOKEN or ARGO_TOKEN_FILE")
+		default:
+			sweeper := history.NewSweeper(argoClient, s, cfg.HistorySweepInterval, cfg.HistoryRetention, logger.Named("sweeper"))
+			go sweeper.Run(ctx)
+		}
+	}
+
+	// Recordings are used in development to replay event streams without Argo.
+	var recordings handlers.Recordings
+	if cfg.RecordingsDir != "" {
+		dir, err := eventrec.NewDir(cfg.RecordingsDir)
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+
+		recordings = dir
+	}
+
+	wsFactory := eventws.NewWebsocketFactory(cfg.WebsocketPingInterval, cfg.WebsocketPongTimeout, logger.Named("websockets"))
+	sseFactory := eventsse.NewSSEFactory(cfg.SSEHeartbeat, cfg.SSERetry, logger.Named("sse"))
 	logger.Debugw("all dependencies were initialized",
 		"argo client", argoClient,
-		"websocket factory", wsFactory)
+		"event hub", hub,
+		"websocket factory", wsFactory,
+		"sse factory", sseFactory)
+
+	// Running workflows are listed on every scrape using the service token.
+	if argoClient.ServiceAuthorized() {
+		prometheus.MustRegister(argo.NewWorkflowsCollector(argoClient, metricsTimeout, logger.Named("metrics")))
+	} else {
+		logger.Warn("running workflows metric is disabled: ARGO_FORWARD_TOKEN is set without ARGO_TOKEN or ARGO_TOKEN_FILE")
+	}
+
+	sessions := handlers.NewSessions()
+	health := handlers.NewHealth(argoClient, cfg.ReadinessCacheTTL, logger.Named("health"))
 
 	logger.Debug("creating router")
-	r := createRouter(argoClient, wsFactory, logger)
+	timeouts := handlers.Timeouts{
+		Request: cfg.RequestTimeout,
+		Action:  cfg.ActionTimeout,
+		Stream:  cfg.StreamTimeout,
+	}
+
+	r := createRouter(argoClient, hub, wsFactory, sseFactory, store, recordings, sessions, health, timeouts, logger)
 	logger.Debug("router created")
 
-	logger.Debug("server started listening")
-	if err = http.ListenAndServe(":8811", r); err != nil {
-		logger.Fatal(err.Error())
+	server := &http.Server{Addr: ":8811", Handler: r}
+	go func() {
+		logger.Debug("server started listening")
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Fatal(err.Error())
+		}
+	}()
+
+	<-ctx.Done()
+	shutdown(server, sessions, health, cfg.ShutdownDelay, cfg.ShutdownTimeout, logger)
+
+	closeWithLogger(hub, logger)
+	closeWithLogger(argoClient, logger)
+	shutdownTracing(tp, logger)
+}
+
+// shutdown marks the server as not ready and keeps serving requests for delay, so load balancers stop sending new ones.
+// Then it stops accepting new connections, ends streaming sessions and waits for active requests until timeout expires.
+// Timeout includes delay.
+func shutdown(server *http.Server, sessions *handlers.Sessions, health *handlers.Health, delay, timeout time.Duration, logger *zap.SugaredLogger) {
+	logger.Infow("shutting down", "delay", delay, "timeout", timeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	health.Shutdown()
+
+	select {
+	case <-time.After(delay):
+	case <-ctx.Done():
+	}
+
+	// Server stops listening at once, but it doesn't wait for websocket connections, as they are hijacked.
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.Shutdown(ctx)
+	}()
+
+	if err := sessions.Drain(ctx); err != nil {
+		logger.Warnw("streams weren't ended before timeout", "active", sessions.Active())
 	}
+
+	if err := <-errs; err != nil {
+		logger.Warnf("requests weren't completed before timeout: %v", err)
+
+		if err := server.Close(); err != nil {
+			logger.Error(err.Error())
+		}
+	}
+
+	logger.Info("server stopped")
 }
 
-// createRouter returns configured chi router.
-func createRouter(argoClient argo.Client, wsFactory eventws.WebsocketFactory, logger *zap.SugaredLogger) *chi.Mux {
+// createRouter returns configured chi router. History routes are added only if store isn't nil.
+// Requests aren't limited by a global timeout, as streams outlive it; timeouts are applied by handlers instead.
+func createRouter(argoClient argo.Client, hub eventhub.Hub, wsFactory eventws.WebsocketFactory, sseFactory eventsse.SSEFactory, store *history.Store, recordings handlers.Recordings, sessions *handlers.Sessions, health *handlers.Health, timeouts handlers.Timeouts, logger *zap.SugaredLogger) *chi.Mux {
 	r := chi.NewRouter()
 
 	logger.Debug("adding middleware")
@@ -71,16 +217,39 @@ func createRouter(argoClient argo.Client, wsFactory eventws.WebsocketFactory, lo
 	r.Use(middleware.RealIP)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.Timeout(10 * time.Second))
+	r.Use(handlers.Metrics)
+	r.Use(handlers.Tracing)
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins: []string{"*"},
+		// Default headers, tokens forwarded to Argo and W3C trace context sent by instrumented browsers.
+		AllowedHeaders: []string{"Origin", "Accept", "Content-Type", "X-Requested-With", "Authorization", "traceparent", "tracestate"},
+		ExposedHeaders: []string{"X-Continue-Token", "X-Recording-Id"},
 	}))
 	logger.Debug("middleware added")
 
 	logger.Debug("setting routes")
+	r.Get("/healthz", health.Liveness)
+	r.Get("/readyz", health.Readiness)
+	r.Handle("/metrics", promhttp.Handler())
+
 	r.Route("/api", func(r chi.Router) {
 		r.Route("/v1", func(r chi.Router) {
-			r.Mount("/workflows", handlers.WorkflowsRouter(argoClient, wsFactory, logger.Named("workflows")))
+			var recorder handlers.Recorder
+			if store != nil {
+				recorder = store
+
+				// History is read from local store, so with forwarded tokens access to namespaces is checked in Argo.
+				var lister history.Lister
+				if argoClient.ForwardsToken() {
+					lister = argoClient
+				}
+
+				r.Mount("/history", handlers.HistoryRouter(*store, lister, timeouts.Request, logger.Named("history")))
+			}
+
+			r.Mount("/workflows", handlers.WorkflowsRouter(argoClient, hub, wsFactory, sseFactory, recorder, recordings, sessions, timeouts, logger.Named("workflows")))
+			r.Mount("/templates", handlers.TemplatesRouter(argoClient, timeouts.Request, logger.Named("templates")))
+			r.Get("/openapi.json", handlers.OpenAPI)
 		})
 	})
 	logger.Debug("routes set")
@@ -107,6 +276,13 @@ func createLogger(cfg *config.Config) *zap.SugaredLogger {
 	return logger.Sugar()
 }
 
+// closeWithLogger closes c, printing an error if it failed.
+func closeWithLogger(c io.Closer, logger *zap.SugaredLogger) {
+	if err := c.Close(); err != nil {
+		logger.Error(err.Error())
+	}
+}
+
 // syncLogger flushes zap logger.
 func syncLogger(logger *zap.SugaredLogger) {
 	err := logger.Sync()
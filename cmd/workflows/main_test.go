@@ -0,0 +1,810 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	"github.com/gorilla/websocket"
+	"github.com/iskorotkov/chaos-workflows/internal/handlers"
+	"github.com/iskorotkov/chaos-workflows/pkg/argo"
+	"github.com/iskorotkov/chaos-workflows/pkg/argo/argotest"
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+	"github.com/iskorotkov/chaos-workflows/pkg/eventhub"
+	"github.com/iskorotkov/chaos-workflows/pkg/eventsse"
+	"github.com/iskorotkov/chaos-workflows/pkg/eventws"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// testServer is the full router connected to fake Argo server.
+type testServer struct {
+	*httptest.Server
+	fake     *argotest.Server
+	sessions *handlers.Sessions
+	health   *handlers.Health
+}
+
+// shutdown stops the server without delay.
+func (s testServer) shutdown() {
+	shutdown(s.Config, s.sessions, s.health, 0, 5*time.Second, zap.NewNop().Sugar())
+}
+
+// testTimeouts are timeouts of the test server.
+var testTimeouts = handlers.Timeouts{Request: 10 * time.Second, Action: 10 * time.Second, Stream: time.Minute}
+
+// newTestServer returns the full router connected to fake Argo server with workflows.
+func newTestServer(t *testing.T, workflows ...v1alpha1.Workflow) testServer {
+	t.Helper()
+
+	fake, err := argotest.NewServer(workflows...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(fake.Close)
+
+	logger := zap.NewNop().Sugar()
+
+	argoClient, err := argo.NewClient(argo.Options{URL: fake.URL}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = argoClient.Close()
+	})
+
+	hub := eventhub.NewHub(argoClient, nil, logger)
+	wsFactory := eventws.NewWebsocketFactory(time.Minute, 2*time.Minute, logger)
+	sseFactory := eventsse.NewSSEFactory(time.Minute, time.Second, logger)
+
+	sessions := handlers.NewSessions()
+	health := handlers.NewHealth(argoClient, time.Minute, logger)
+	server := httptest.NewServer(createRouter(argoClient, hub, wsFactory, sseFactory, nil, nil, sessions, health, testTimeouts, logger))
+	t.Cleanup(server.Close)
+
+	return testServer{Server: server, fake: fake, sessions: sessions, health: health}
+}
+
+func testWorkflow(namespace, name string, phase v1alpha1.WorkflowPhase) v1alpha1.Workflow {
+	return v1alpha1.Workflow{
+		ObjectMeta: v1.ObjectMeta{Name: name, Namespace: namespace},
+		Status: v1alpha1.WorkflowStatus{
+			Phase:     phase,
+			StartedAt: v1.Now(),
+		},
+	}
+}
+
+// getJSON sends request and decodes JSON response.
+func getJSON(t *testing.T, method, url string, v interface{}) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("%s %s returned %s", method, url, resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRouter_list(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t,
+		testWorkflow("chaos", "a", v1alpha1.WorkflowRunning),
+		testWorkflow("chaos", "b", v1alpha1.WorkflowSucceeded),
+		testWorkflow("other", "c", v1alpha1.WorkflowFailed))
+
+	var workflows []event.Workflow
+	getJSON(t, http.MethodGet, server.URL+"/api/v1/workflows?namespace=chaos&status=succeeded,running&sort=name", &workflows)
+
+	if len(workflows) != 2 || workflows[0].Name != "a" || workflows[1].Name != "b" || workflows[1].Status != "succeeded" {
+		t.Errorf("unexpected workflows %+v", workflows)
+	}
+}
+
+// TestRouter_listPages tests that pages filtered after listing in Argo are filled up and continue after the last workflow.
+func TestRouter_listPages(t *testing.T) {
+	t.Parallel()
+
+	old := v1.NewTime(time.Date(2021, 11, 1, 0, 0, 0, 0, time.UTC))
+	var workflows []v1alpha1.Workflow
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g"} {
+		wf := testWorkflow("chaos", name, v1alpha1.WorkflowSucceeded)
+		if name == "a" || name == "c" || name == "d" || name == "f" {
+			wf.Status.StartedAt = old
+		}
+
+		workflows = append(workflows, wf)
+	}
+
+	server := newTestServer(t, workflows...)
+
+	page := func(token string) ([]string, string) {
+		resp, err := http.Get(server.URL + "/api/v1/workflows?limit=2&startedAfter=2021-11-02T00:00:00Z&continue=" + token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("listing page %q returned %s", token, resp.Status)
+		}
+
+		var workflows []event.Workflow
+		if err := json.NewDecoder(resp.Body).Decode(&workflows); err != nil {
+			t.Fatal(err)
+		}
+
+		var names []string
+		for _, wf := range workflows {
+			names = append(names, wf.Name)
+		}
+
+		return names, resp.Header.Get("X-Continue-Token")
+	}
+
+	names, token := page("")
+	if strings.Join(names, ",") != "b,e" || token == "" {
+		t.Fatalf("first page = %v with token %q, want [b e] with token", names, token)
+	}
+
+	names, token = page(token)
+	if strings.Join(names, ",") != "g" || token != "" {
+		t.Errorf("second page = %v with token %q, want [g] without token", names, token)
+	}
+
+	resp, err := http.Get(server.URL + "/api/v1/workflows?limit=2&sort=name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("sorting a page returned %s, want %d", resp.Status, http.StatusBadRequest)
+	}
+}
+
+func TestRouter_get(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t,
+		testWorkflow("chaos", "a", v1alpha1.WorkflowRunning),
+		testWorkflow("chaos", "watch", v1alpha1.WorkflowSucceeded))
+
+	var wf event.Workflow
+	getJSON(t, http.MethodGet, server.URL+"/api/v1/workflows/chaos/a", &wf)
+
+	if wf.Name != "a" || wf.Namespace != "chaos" || wf.Status != "running" {
+		t.Errorf("unexpected workflow %+v", wf)
+	}
+
+	// Workflow named "watch" isn't shadowed by watch routes.
+	getJSON(t, http.MethodGet, server.URL+"/api/v1/workflows/chaos/watch", &wf)
+
+	if wf.Name != "watch" || wf.Status != "succeeded" {
+		t.Errorf("unexpected workflow %+v", wf)
+	}
+
+	resp, err := http.Get(server.URL + "/api/v1/workflows/chaos/missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != http.StatusNotFound || body.Code != "NotFound" {
+		t.Errorf("getting missing workflow returned %s with code %q, want %d NotFound", resp.Status, body.Code, http.StatusNotFound)
+	}
+}
+
+// TestRouter_watch tests that scripted transitions are streamed until the workflow finishes.
+func TestRouter_watch(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t, testWorkflow("chaos", "a", v1alpha1.WorkflowPending))
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/api/v1/workflows/chaos/a/watch", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	server.fake.Play("chaos", "a",
+		argotest.Transition{Delay: 50 * time.Millisecond, Phase: v1alpha1.WorkflowRunning},
+		argotest.Transition{Delay: 50 * time.Millisecond, Phase: v1alpha1.WorkflowSucceeded})
+
+	var statuses []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data := strings.TrimPrefix(scanner.Text(), "data: ")
+		if data == scanner.Text() {
+			continue
+		}
+
+		var ev event.Workflow
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			t.Fatal(err)
+		}
+
+		statuses = append(statuses, ev.Status)
+	}
+
+	if got := strings.Join(statuses, ","); got != "pending,running,succeeded" {
+		t.Errorf("streamed statuses %s, want pending,running,succeeded", got)
+	}
+}
+
+// TestRouter_watchSelector tests that namespace watch streams events of matching workflows and isn't closed when they finish.
+func TestRouter_watchSelector(t *testing.T) {
+	t.Parallel()
+
+	labeled := func(namespace, name, team string) v1alpha1.Workflow {
+		wf := testWorkflow(namespace, name, v1alpha1.WorkflowPending)
+		wf.Labels = map[string]string{"team": team}
+		return wf
+	}
+
+	server := newTestServer(t,
+		labeled("chaos", "a", "payments"),
+		labeled("chaos", "b", "payments"),
+		labeled("chaos", "c", "search"),
+		labeled("other", "d", "payments"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/v1/workflows/watch?namespace=chaos&selector=team%3Dpayments", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	// Workflows in other namespace or with other labels change before matching workflows finish.
+	server.fake.Play("chaos", "c",
+		argotest.Transition{Delay: 20 * time.Millisecond, Phase: v1alpha1.WorkflowRunning},
+		argotest.Transition{Delay: 20 * time.Millisecond, Phase: v1alpha1.WorkflowFailed})
+	server.fake.Play("other", "d",
+		argotest.Transition{Delay: 20 * time.Millisecond, Phase: v1alpha1.WorkflowRunning},
+		argotest.Transition{Delay: 20 * time.Millisecond, Phase: v1alpha1.WorkflowFailed})
+	server.fake.Play("chaos", "a",
+		argotest.Transition{Delay: 50 * time.Millisecond, Phase: v1alpha1.WorkflowRunning},
+		argotest.Transition{Delay: 50 * time.Millisecond, Phase: v1alpha1.WorkflowSucceeded})
+	server.fake.Play("chaos", "b",
+		argotest.Transition{Delay: 75 * time.Millisecond, Phase: v1alpha1.WorkflowRunning},
+		argotest.Transition{Delay: 75 * time.Millisecond, Phase: v1alpha1.WorkflowFailed})
+
+	statuses := make(map[string][]string)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data := strings.TrimPrefix(scanner.Text(), "data: ")
+		if data == scanner.Text() {
+			continue
+		}
+
+		var ev event.Workflow
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			t.Fatal(err)
+		}
+
+		statuses[ev.Name] = append(statuses[ev.Name], ev.Status)
+
+		// b finishes last, and the stream stays open after it.
+		if ev.Name == "b" && ev.Finished() {
+			break
+		}
+	}
+
+	want := map[string][]string{
+		"a": {"pending", "running", "succeeded"},
+		"b": {"pending", "running", "failed"},
+	}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Errorf("streamed statuses %v, want %v", statuses, want)
+	}
+
+	if err := ctx.Err(); err != nil {
+		t.Errorf("stream wasn't open until the last event: %v", err)
+	}
+}
+
+func TestRouter_cancel(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t, testWorkflow("chaos", "a", v1alpha1.WorkflowRunning))
+
+	var wf event.Workflow
+	getJSON(t, http.MethodPost, server.URL+"/api/v1/workflows/chaos/a/cancel?reason=test", &wf)
+
+	if wf.Status != "failed" || wf.FinishedAt == nil {
+		t.Errorf("unexpected workflow %+v", wf)
+	}
+
+	resp, err := http.Post(server.URL+"/api/v1/workflows/chaos/a/cancel", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("cancelling finished workflow returned %s, want %d", resp.Status, http.StatusConflict)
+	}
+
+	resp, err = http.Post(server.URL+"/api/v1/workflows/chaos/a/retry", "application/json", strings.NewReader(`{"reason": "test"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("retrying workflow with reason returned %s, want %d", resp.Status, http.StatusBadRequest)
+	}
+}
+
+// TestRouter_actions tests actions changing workflow lifecycle.
+func TestRouter_actions(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t,
+		testWorkflow("chaos", "failed", v1alpha1.WorkflowFailed),
+		testWorkflow("chaos", "running", v1alpha1.WorkflowRunning),
+		testWorkflow("chaos", "old", v1alpha1.WorkflowSucceeded))
+
+	suspended := func(name string) bool {
+		wf, err := server.fake.Update("chaos", name, func(*v1alpha1.Workflow) {})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return wf.Spec.Suspend != nil && *wf.Spec.Suspend
+	}
+
+	var wf event.Workflow
+	getJSON(t, http.MethodPost, server.URL+"/api/v1/workflows/chaos/failed/retry", &wf)
+	if wf.Name != "failed" || wf.Status != "running" || wf.FinishedAt != nil {
+		t.Errorf("retried workflow = %+v, want running workflow", wf)
+	}
+
+	getJSON(t, http.MethodPost, server.URL+"/api/v1/workflows/chaos/old/resubmit", &wf)
+	if !strings.HasPrefix(wf.Name, "old-") || wf.Status != "pending" {
+		t.Errorf("resubmitted workflow = %+v, want new pending workflow", wf)
+	}
+
+	getJSON(t, http.MethodPost, server.URL+"/api/v1/workflows/chaos/running/suspend", &wf)
+	if !suspended("running") {
+		t.Error("workflow wasn't suspended")
+	}
+
+	getJSON(t, http.MethodPost, server.URL+"/api/v1/workflows/chaos/running/resume", &wf)
+	if wf.Name != "running" || suspended("running") {
+		t.Error("workflow wasn't resumed")
+	}
+
+	getJSON(t, http.MethodDelete, server.URL+"/api/v1/workflows/chaos/old", &wf)
+	if wf.Name != "old" {
+		t.Errorf("deleted workflow = %+v, want old", wf)
+	}
+
+	resp, err := http.Get(server.URL + "/api/v1/workflows/chaos/old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("getting deleted workflow returned %s, want %d", resp.Status, http.StatusNotFound)
+	}
+
+	resp, err = http.Post(server.URL+"/api/v1/workflows/chaos/running/retry", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("retrying running workflow returned %s, want %d", resp.Status, http.StatusConflict)
+	}
+}
+
+// TestRouter_logs tests that step logs are returned as plain text and errors of log requests keep their status.
+func TestRouter_logs(t *testing.T) {
+	t.Parallel()
+
+	wf := testWorkflow("chaos", "a", v1alpha1.WorkflowRunning)
+	wf.Status.Nodes = v1alpha1.Nodes{
+		"a-1": {ID: "a-1", Name: "a[0].pod-delete", DisplayName: "pod-delete", Type: v1alpha1.NodeTypePod},
+	}
+
+	server := newTestServer(t, wf)
+	server.fake.SetLogs("chaos", "a-1", "main", "injecting chaos", "chaos injected")
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "main container", path: "/steps/pod-delete/logs", wantStatus: http.StatusOK, wantBody: "injecting chaos\nchaos injected\n"},
+		{name: "unknown container", path: "/steps/pod-delete/logs?container=sidecar", wantStatus: http.StatusBadRequest},
+		{name: "unknown step", path: "/steps/network-delay/logs", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			resp, err := http.Get(server.URL + "/api/v1/workflows/chaos/a" + tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %s, want %d", resp.Status, tt.wantStatus)
+			}
+
+			if tt.wantBody != "" && string(body) != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+// TestShutdown_sse tests that SSE clients receive shutdown event and the stream is closed.
+func TestShutdown_sse(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t, testWorkflow("chaos", "a", v1alpha1.WorkflowRunning))
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/api/v1/workflows/chaos/a/watch", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	// Wait for the first event, so the session is started.
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() && !strings.HasPrefix(scanner.Text(), "data: ") {
+	}
+
+	go server.shutdown()
+
+	var lines []string
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" && !strings.HasPrefix(line, ":") {
+			lines = append(lines, line)
+		}
+	}
+
+	want := []string{"event: shutdown", `data: {"reason":"server restarting"}`}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("stream ended with %q, want %q", lines, want)
+	}
+}
+
+// TestShutdown_websocket tests that websocket clients receive close frame with restart code.
+func TestShutdown_websocket(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t, testWorkflow("chaos", "a", v1alpha1.WorkflowRunning))
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/workflows/chaos/a/watch"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	var ev event.Workflow
+	if err := conn.ReadJSON(&ev); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		server.shutdown()
+		close(done)
+	}()
+
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseServiceRestart) {
+		t.Errorf("read returned %v, want close error with code %d", err, websocket.CloseServiceRestart)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Error("shutdown didn't finish in time")
+	}
+
+	if server.sessions.Active() != 0 {
+		t.Errorf("%d sessions are active after shutdown", server.sessions.Active())
+	}
+}
+
+// waitSessions waits until number of active sessions becomes n.
+func waitSessions(t *testing.T, sessions *handlers.Sessions, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for sessions.Active() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d sessions are active, want %d", sessions.Active(), n)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestRouter_watchDisconnect tests that watch sessions end when clients disconnect.
+func TestRouter_watchDisconnect(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		connect func(t *testing.T, url string) io.Closer
+	}{
+		{
+			name: "websocket",
+			connect: func(t *testing.T, url string) io.Closer {
+				conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(url, "http"), nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				return conn
+			},
+		},
+		{
+			name: "sse",
+			connect: func(t *testing.T, url string) io.Closer {
+				req, err := http.NewRequest(http.MethodGet, url, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				req.Header.Set("Accept", "text/event-stream")
+
+				resp, err := http.DefaultClient.Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				return resp.Body
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := newTestServer(t, testWorkflow("chaos", "a", v1alpha1.WorkflowRunning))
+
+			conn := tt.connect(t, server.URL+"/api/v1/workflows/chaos/a/watch")
+			waitSessions(t, server.sessions, 1)
+
+			_ = conn.Close()
+			waitSessions(t, server.sessions, 0)
+		})
+	}
+}
+
+// TestRouter_health tests that readiness reports Argo version and fails during shutdown, while liveness doesn't.
+func TestRouter_health(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t)
+
+	var ready struct {
+		Status      string `json:"status"`
+		ArgoVersion string `json:"argoVersion"`
+	}
+	getJSON(t, http.MethodGet, server.URL+"/readyz", &ready)
+
+	if ready.Status != "ok" || ready.ArgoVersion != argotest.Version {
+		t.Errorf("unexpected readiness %+v", ready)
+	}
+
+	server.health.Shutdown()
+
+	for path, want := range map[string]int{
+		"/healthz": http.StatusOK,
+		"/readyz":  http.StatusServiceUnavailable,
+	} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+
+		if resp.StatusCode != want {
+			t.Errorf("%s returned %s during shutdown, want %d", path, resp.Status, want)
+		}
+	}
+}
+
+// TestRouter_cors tests that browsers may send default, authorization and tracing headers.
+func TestRouter_cors(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t)
+
+	req, err := http.NewRequest(http.MethodOptions, server.URL+"/api/v1/workflows", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Origin", "https://dashboard.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	req.Header.Set("Access-Control-Request-Headers", "Accept, Content-Type, X-Requested-With, Authorization, traceparent, tracestate")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	allowed := strings.ToLower(resp.Header.Get("Access-Control-Allow-Headers"))
+	for _, header := range []string{"accept", "content-type", "x-requested-with", "authorization", "traceparent", "tracestate"} {
+		if !strings.Contains(allowed, header) {
+			t.Errorf("header %s isn't allowed: %q", header, allowed)
+		}
+	}
+}
+
+// TestRouter_metrics tests that requests are recorded by route pattern, so workflow names don't create new series.
+func TestRouter_metrics(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t)
+
+	resp, err := http.Get(server.URL + "/api/v1/workflows/chaos/missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	resp, err = http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `chaos_workflows_http_request_duration_seconds_count{method="GET",route="/api/v1/workflows/{namespace}/{name}",status="404"}`
+	if !strings.Contains(string(b), want) {
+		t.Errorf("metrics don't contain %s:\n%s", want, b)
+	}
+
+	if strings.Contains(string(b), "missing") {
+		t.Error("metrics contain workflow name")
+	}
+}
+
+var (
+	spanRecorderOnce sync.Once
+	spanRecorder     *tracetest.SpanRecorder
+)
+
+// recordSpans sets global tracer provider recording all spans. Spans of other tests are recorded too,
+// so they must be filtered by trace ID.
+func recordSpans() *tracetest.SpanRecorder {
+	spanRecorderOnce.Do(func() {
+		spanRecorder = tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)))
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+	})
+
+	return spanRecorder
+}
+
+// TestRouter_tracing tests that trace context of the request is propagated to handlers and Argo calls.
+func TestRouter_tracing(t *testing.T) {
+	t.Parallel()
+
+	recorder := recordSpans()
+	server := newTestServer(t, testWorkflow("chaos", "a", v1alpha1.WorkflowRunning))
+
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID  = "00f067aa0ba902b7"
+	)
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/api/v1/workflows/chaos/a", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("traceparent", "00-"+traceID+"-"+spanID+"-01")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		if span.SpanContext().TraceID().String() == traceID {
+			spans[span.Name()] = span
+		}
+	}
+
+	serverSpan, ok := spans["GET /api/v1/workflows/{namespace}/{name}"]
+	if !ok {
+		t.Fatalf("server span wasn't recorded, got %v", spans)
+	}
+
+	if serverSpan.Parent().SpanID().String() != spanID {
+		t.Errorf("server span parent = %s, want %s", serverSpan.Parent().SpanID(), spanID)
+	}
+
+	for _, name := range []string{"workflow.WorkflowService/ListWorkflows", "convert workflow"} {
+		span, ok := spans[name]
+		if !ok {
+			t.Errorf("span %q wasn't recorded", name)
+			continue
+		}
+
+		if span.Parent().SpanID() != serverSpan.SpanContext().SpanID() {
+			t.Errorf("span %q isn't a child of server span", name)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/iskorotkov/chaos-workflows/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.uber.org/zap"
+)
+
+// Span exporters.
+const (
+	// exporterOTLP sends spans to OpenTelemetry collector over gRPC.
+	exporterOTLP = "otlp"
+	// exporterFile writes spans to a local file as JSON lines.
+	exporterFile = "file"
+)
+
+// tracingShutdownTimeout limits time spent on exporting remaining spans on exit.
+const tracingShutdownTimeout = 5 * time.Second
+
+// setupTracing sets global propagator of W3C trace context and global tracer provider. Trace context is propagated
+// even if tracing is disabled, so Argo calls are still added to traces of clients. Returned provider is nil if
+// tracing is disabled.
+func setupTracing(cfg *config.Config) (*sdktrace.TracerProvider, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if cfg.TracingExporter == "" {
+		return nil, nil
+	}
+
+	tp, err := newTracerProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	otel.SetTracerProvider(tp)
+	return tp, nil
+}
+
+// newTracerProvider returns tracer provider sampling and exporting spans as configured.
+func newTracerProvider(cfg *config.Config) (*sdktrace.TracerProvider, error) {
+	exporter, err := newSpanExporter(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	res := resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String("chaos-workflows"))
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		// Sampling decisions of clients are respected.
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.TracingSampleRatio)))), nil
+}
+
+// newSpanExporter returns exporter selected in config.
+func newSpanExporter(cfg *config.Config) (sdktrace.SpanExporter, error) {
+	switch cfg.TracingExporter {
+	case exporterOTLP:
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.TracingEndpoint)}
+		if cfg.TracingInsecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+
+		// Connection is established in background, so collector being unavailable doesn't prevent start.
+		return otlptracegrpc.New(context.Background(), opts...)
+	case exporterFile:
+		f, err := os.OpenFile(cfg.TracingFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't open traces file: %w", err)
+		}
+
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+
+		return fileExporter{Exporter: exporter, file: f}, nil
+	default:
+		return nil, fmt.Errorf("unsupported tracing exporter %q", cfg.TracingExporter)
+	}
+}
+
+// fileExporter writes spans to a file and closes it on shutdown.
+type fileExporter struct {
+	*stdouttrace.Exporter
+	file *os.File
+}
+
+func (e fileExporter) Shutdown(ctx context.Context) error {
+	if err := e.Exporter.Shutdown(ctx); err != nil {
+		_ = e.file.Close()
+		return err
+	}
+
+	return e.file.Close()
+}
+
+// shutdownTracing exports remaining spans and stops tracer provider, printing an error if it failed.
+func shutdownTracing(tp *sdktrace.TracerProvider, logger *zap.SugaredLogger) {
+	if tp == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+	defer cancel()
+
+	if err := tp.Shutdown(ctx); err != nil {
+		logger.Error(err.Error())
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iskorotkov/chaos-workflows/internal/config"
+)
+
+// Test_newTracerProvider tests that spans are written to file with the file exporter.
+func Test_newTracerProvider(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+
+	tp, err := newTracerProvider(&config.Config{TracingExporter: exporterFile, TracingFile: path, TracingSampleRatio: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, span := tp.Tracer("test").Start(context.Background(), "test span")
+	span.End()
+
+	if err := tp.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(b), `"Name":"test span"`) {
+		t.Errorf("span wasn't exported:\n%s", b)
+	}
+
+	if _, err := newTracerProvider(&config.Config{TracingExporter: "zipkin"}); err == nil {
+		t.Error("unsupported exporter was accepted")
+	}
+}
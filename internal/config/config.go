@@ -7,6 +7,7 @@ import (
 	"github.com/caarlos0/env"
 	"math/rand"
 	"reflect"
+	"time"
 )
 
 var (
@@ -14,20 +15,95 @@ var (
 )
 
 type Config struct {
-	ArgoServer  string `env:"ARGO_SERVER"`
-	Development bool   `env:"DEVELOPMENT"`
+	ArgoServer             string        `env:"ARGO_SERVER"`
+	ArgoHTTP1              bool          `env:"ARGO_HTTP1"`
+	ArgoSecure             bool          `env:"ARGO_SECURE" envDefault:"true"`
+	ArgoInsecureSkipVerify bool          `env:"ARGO_INSECURE_SKIP_VERIFY" envDefault:"true"`
+	ArgoCAFile             string        `env:"ARGO_CA_FILE"`
+	ArgoToken              string        `env:"ARGO_TOKEN"`
+	ArgoTokenFile          string        `env:"ARGO_TOKEN_FILE"`
+	ArgoForwardToken       bool          `env:"ARGO_FORWARD_TOKEN"`
+	Kubeconfig             string        `env:"KUBECONFIG"`
+	ArgoWatchRetries       int           `env:"ARGO_WATCH_RETRIES" envDefault:"5"`
+	ArgoWatchBackoff       time.Duration `env:"ARGO_WATCH_BACKOFF" envDefault:"1s"`
+	ArgoWatchMaxBackoff    time.Duration `env:"ARGO_WATCH_MAX_BACKOFF" envDefault:"30s"`
+	ArgoArchive            bool          `env:"ARGO_ARCHIVE"`
+	HistoryPath            string        `env:"HISTORY_PATH"`
+	HistoryRetention       time.Duration `env:"HISTORY_RETENTION" envDefault:"720h"`
+	HistorySweepInterval   time.Duration `env:"HISTORY_SWEEP_INTERVAL" envDefault:"5m"`
+	RecordingsDir          string        `env:"RECORDINGS_DIR"`
+	SSEHeartbeat           time.Duration `env:"SSE_HEARTBEAT" envDefault:"15s"`
+	SSERetry               time.Duration `env:"SSE_RETRY" envDefault:"3s"`
+	WebsocketPingInterval  time.Duration `env:"WEBSOCKET_PING_INTERVAL" envDefault:"15s"`
+	WebsocketPongTimeout   time.Duration `env:"WEBSOCKET_PONG_TIMEOUT" envDefault:"30s"`
+	ShutdownTimeout        time.Duration `env:"SHUTDOWN_TIMEOUT" envDefault:"25s"`
+	ShutdownDelay          time.Duration `env:"SHUTDOWN_DELAY" envDefault:"5s"`
+	ReadinessCacheTTL      time.Duration `env:"READINESS_CACHE_TTL" envDefault:"5s"`
+	RequestTimeout         time.Duration `env:"REQUEST_TIMEOUT" envDefault:"30s"`
+	ActionTimeout          time.Duration `env:"ACTION_TIMEOUT" envDefault:"30s"`
+	StreamTimeout          time.Duration `env:"STREAM_TIMEOUT" envDefault:"1h"`
+	TracingExporter        string        `env:"TRACING_EXPORTER"`
+	TracingEndpoint        string        `env:"TRACING_ENDPOINT" envDefault:"localhost:4317"`
+	TracingInsecure        bool          `env:"TRACING_INSECURE" envDefault:"true"`
+	TracingFile            string        `env:"TRACING_FILE" envDefault:"traces.jsonl"`
+	TracingSampleRatio     float64       `env:"TRACING_SAMPLE_RATIO" envDefault:"1"`
+	Development            bool          `env:"DEVELOPMENT"`
 }
 
 func (c Config) Generate(r *rand.Rand, _ int) reflect.Value {
 	rs := func(prefix string) string {
 		return fmt.Sprintf("%s-%d", prefix, r.Intn(100))
 	}
+	pingInterval := time.Duration(r.Intn(60)) * time.Second
 	return reflect.ValueOf(Config{
-		ArgoServer:  rs("argo-server"),
-		Development: r.Int()%2 == 0,
+		ArgoServer:             rs("argo-server"),
+		ArgoHTTP1:              r.Int()%2 == 0,
+		ArgoSecure:             r.Int()%2 == 0,
+		ArgoInsecureSkipVerify: r.Int()%2 == 0,
+		ArgoCAFile:             rs("ca-file"),
+		ArgoToken:              rs("token"),
+		ArgoTokenFile:          rs("token-file"),
+		ArgoForwardToken:       r.Int()%2 == 0,
+		Kubeconfig:             rs("kubeconfig"),
+		ArgoWatchRetries:       r.Intn(10),
+		ArgoWatchBackoff:       time.Duration(r.Intn(10)) * time.Second,
+		ArgoWatchMaxBackoff:    time.Duration(r.Intn(60)) * time.Second,
+		ArgoArchive:            r.Int()%2 == 0,
+		HistoryPath:            rs("history-path"),
+		HistoryRetention:       time.Duration(r.Intn(1000)) * time.Hour,
+		HistorySweepInterval:   time.Duration(r.Intn(10)) * time.Minute,
+		RecordingsDir:          rs("recordings-dir"),
+		SSEHeartbeat:           time.Duration(r.Intn(60)) * time.Second,
+		SSERetry:               time.Duration(r.Intn(10)) * time.Second,
+		WebsocketPingInterval:  pingInterval,
+		WebsocketPongTimeout:   pingInterval + time.Duration(1+r.Intn(60))*time.Second,
+		ShutdownTimeout:        time.Duration(r.Intn(60)) * time.Second,
+		ShutdownDelay:          time.Duration(r.Intn(10)) * time.Second,
+		ReadinessCacheTTL:      time.Duration(r.Intn(10)) * time.Second,
+		RequestTimeout:         time.Duration(r.Intn(60)) * time.Second,
+		ActionTimeout:          time.Duration(r.Intn(60)) * time.Second,
+		StreamTimeout:          time.Duration(r.Intn(10)) * time.Hour,
+		TracingExporter:        rs("tracing-exporter"),
+		TracingEndpoint:        rs("tracing-endpoint"),
+		TracingInsecure:        r.Int()%2 == 0,
+		TracingFile:            rs("tracing-file"),
+		TracingSampleRatio:     r.Float64(),
+		Development:            r.Int()%2 == 0,
 	})
 }
 
+// Redacted returns a copy of Config safe to be logged.
+// Paths to files with credentials are redacted too, as they may reveal where secrets are mounted.
+func (c Config) Redacted() Config {
+	for _, value := range []*string{&c.ArgoToken, &c.ArgoTokenFile, &c.Kubeconfig} {
+		if *value != "" {
+			*value = "<redacted>"
+		}
+	}
+
+	return c
+}
+
 // FromEnvironment returns Config created from environment variables.
 func FromEnvironment() (*Config, error) {
 	cfg := &Config{}
@@ -35,5 +111,25 @@ func FromEnvironment() (*Config, error) {
 		return nil, ErrParse
 	}
 
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrParse, err)
+	}
+
 	return cfg, nil
 }
+
+// validate checks settings which depend on each other.
+func (c Config) validate() error {
+	// Pong may arrive only after the next ping is sent, so shorter timeout would end healthy connections.
+	if c.WebsocketPingInterval > 0 && c.WebsocketPongTimeout <= c.WebsocketPingInterval {
+		return fmt.Errorf("WEBSOCKET_PONG_TIMEOUT (%v) must be greater than WEBSOCKET_PING_INTERVAL (%v)",
+			c.WebsocketPongTimeout, c.WebsocketPingInterval)
+	}
+
+	// Kubernetes API is called with credentials from kubeconfig, so user tokens can't be forwarded to it.
+	if c.ArgoForwardToken && c.ArgoServer == "" {
+		return errors.New("ARGO_FORWARD_TOKEN requires ARGO_SERVER")
+	}
+
+	return nil
+}
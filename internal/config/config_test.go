@@ -0,0 +1,90 @@
+package config
+
+import (
+	"errors"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+func TestConfig_validate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		pingInterval time.Duration
+		pongTimeout  time.Duration
+		argoServer   string
+		forwardToken bool
+		wantErr      bool
+	}{
+		{name: "defaults", pingInterval: 15 * time.Second, pongTimeout: 30 * time.Second},
+		{name: "forwarding to argo server", argoServer: "argo:2746", forwardToken: true},
+		{name: "forwarding to kubernetes", forwardToken: true, wantErr: true},
+		{name: "pings disabled", pingInterval: 0, pongTimeout: 0},
+		{name: "pong timeout equal to ping interval", pingInterval: 15 * time.Second, pongTimeout: 15 * time.Second, wantErr: true},
+		{name: "pong timeout shorter than ping interval", pingInterval: 30 * time.Second, pongTimeout: 15 * time.Second, wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := Config{
+				WebsocketPingInterval: tt.pingInterval,
+				WebsocketPongTimeout:  tt.pongTimeout,
+				ArgoServer:            tt.argoServer,
+				ArgoForwardToken:      tt.forwardToken,
+			}
+			if err := c.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfig_Generate(t *testing.T) {
+	t.Parallel()
+
+	f := func(c Config) bool {
+		return c.validate() == nil
+	}
+
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestConfig_Redacted tests that credentials and paths to them are redacted and other settings are kept.
+func TestConfig_Redacted(t *testing.T) {
+	t.Parallel()
+
+	f := func(c Config) bool {
+		r := c.Redacted()
+		if r.ArgoToken != "<redacted>" || r.ArgoTokenFile != "<redacted>" || r.Kubeconfig != "<redacted>" {
+			return false
+		}
+
+		r.ArgoToken, r.ArgoTokenFile, r.Kubeconfig = c.ArgoToken, c.ArgoTokenFile, c.Kubeconfig
+		return r == c
+	}
+
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+
+	if r := (Config{}).Redacted(); r.ArgoToken != "" || r.ArgoTokenFile != "" || r.Kubeconfig != "" {
+		t.Errorf("empty values were redacted: %+v", r)
+	}
+}
+
+// TestFromEnvironment_invalid tests that invalid combination of settings isn't accepted.
+// Environment is changed, so the test isn't parallel.
+func TestFromEnvironment_invalid(t *testing.T) {
+	t.Setenv("WEBSOCKET_PING_INTERVAL", "30s")
+	t.Setenv("WEBSOCKET_PONG_TIMEOUT", "10s")
+
+	if _, err := FromEnvironment(); !errors.Is(err, ErrParse) {
+		t.Errorf("FromEnvironment() error = %v, want %v", err, ErrParse)
+	}
+}
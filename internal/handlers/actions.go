@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/iskorotkov/chaos-workflows/pkg/argo"
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+	"go.uber.org/zap"
+)
+
+// maxActionBodySize limits size of action request body.
+const maxActionBodySize = 64 * 1024
+
+// workflowAction performs an action on a workflow and returns the resulting workflow.
+type workflowAction func(ctx context.Context, client argo.Client, namespace, name, reason string) (v1alpha1.Workflow, error)
+
+// reasonActions lists actions which keep reason. Argo saves it only as a message of a stopped workflow,
+// so reason passed to other actions is rejected instead of being silently dropped.
+var reasonActions = map[string]bool{
+	"cancel": true,
+}
+
+// workflowActions lists supported actions by their names used in URLs.
+var workflowActions = map[string]workflowAction{
+	"cancel": func(ctx context.Context, client argo.Client, namespace, name, reason string) (v1alpha1.Workflow, error) {
+		return client.Stop(ctx, namespace, name, reason)
+	},
+	"terminate": func(ctx context.Context, client argo.Client, namespace, name, _ string) (v1alpha1.Workflow, error) {
+		return client.Terminate(ctx, namespace, name)
+	},
+	"suspend": func(ctx context.Context, client argo.Client, namespace, name, _ string) (v1alpha1.Workflow, error) {
+		return client.Suspend(ctx, namespace, name)
+	},
+	"resume": func(ctx context.Context, client argo.Client, namespace, name, _ string) (v1alpha1.Workflow, error) {
+		return client.Resume(ctx, namespace, name)
+	},
+	"retry": func(ctx context.Context, client argo.Client, namespace, name, _ string) (v1alpha1.Workflow, error) {
+		return client.Retry(ctx, namespace, name)
+	},
+	"resubmit": func(ctx context.Context, client argo.Client, namespace, name, _ string) (v1alpha1.Workflow, error) {
+		return client.Resubmit(ctx, namespace, name)
+	},
+	"delete": func(ctx context.Context, client argo.Client, namespace, name, _ string) (v1alpha1.Workflow, error) {
+		return client.Delete(ctx, namespace, name)
+	},
+}
+
+// actionRequest is an optional body of action request.
+type actionRequest struct {
+	Reason string `json:"reason"`
+}
+
+// parseReason returns reason passed in "reason" query parameter or in JSON body.
+func parseReason(r *http.Request) (string, error) {
+	if reason := r.URL.Query().Get("reason"); reason != "" {
+		return reason, nil
+	}
+
+	var req actionRequest
+	err := json.NewDecoder(io.LimitReader(r.Body, maxActionBodySize)).Decode(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("invalid request body: %w", err)
+	}
+
+	return req.Reason, nil
+}
+
+func workflowActionHandler(w http.ResponseWriter, r *http.Request, client argo.Client, action string, timeout time.Duration, log *zap.SugaredLogger) {
+	namespace, name := chi.URLParam(r, "namespace"), chi.URLParam(r, "name")
+
+	if namespace == "" || name == "" {
+		log.Infof("namespace or name is empty")
+		writeError(w, r, event.ErrInvalidRequest, "namespace or name is empty")
+		return
+	}
+
+	reason, err := parseReason(r)
+	if err != nil {
+		log.Infof("error parsing reason: %v", err)
+		writeError(w, r, event.ErrInvalidRequest, err.Error())
+		return
+	}
+
+	if reason != "" && !reasonActions[action] {
+		log.Infof("reason passed to action %s which doesn't keep it", action)
+		writeError(w, r, event.ErrInvalidRequest, fmt.Sprintf("reason isn't supported by action %s", action))
+		return
+	}
+
+	ctx, cancel := argoContext(r, timeout)
+	defer cancel()
+
+	log.Infow("performing workflow action", "action", action, "namespace", namespace, "name", name, "reason", reason,
+		"request", middleware.GetReqID(r.Context()))
+
+	dto, err := workflowActions[action](ctx, client, namespace, name, reason)
+	if err != nil {
+		log.Infof("error performing action %s on workflow %s in namespace %s: %v", action, name, namespace, err)
+		writeError(w, r, err, fmt.Sprintf("error performing action %s on workflow", action))
+		return
+	}
+
+	workflow, ok := convertWorkflow(ctx, dto)
+	if !ok {
+		log.Infof("error converting raw workflow to custom type")
+		writeError(w, r, event.ErrInvalidEvent, "error converting raw workflow to custom type")
+		return
+	}
+
+	b, err := json.Marshal(workflow)
+	if err != nil {
+		log.Infof("error marshaling workflows: %v", err)
+		writeError(w, r, err, "error marshaling workflows")
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+
+	if _, err := w.Write(b); err != nil {
+		log.Infof("error writing response: %v", err)
+		writeError(w, r, err, "error writing response")
+		return
+	}
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Test_parseReason tests reading of action reason from query and body.
+func Test_parseReason(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		target  string
+		body    string
+		want    string
+		wantErr bool
+	}{
+		{name: "no reason", target: "/", want: ""},
+		{name: "query", target: "/?reason=flaky%20node", want: "flaky node"},
+		{name: "body", target: "/", body: `{"reason": "flaky node"}`, want: "flaky node"},
+		{name: "query takes precedence", target: "/?reason=query", body: `{"reason": "body"}`, want: "query"},
+		{name: "invalid body", target: "/", body: "reason", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest("POST", tt.target, strings.NewReader(tt.body))
+			got, err := parseReason(r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseReason() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("parseReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+)
+
+// errorResponse is a body of error responses.
+type errorResponse struct {
+	// Code is a machine-readable kind of error, e.g. "NotFound".
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// RequestID is an ID of request to find it in logs.
+	RequestID string `json:"requestId,omitempty"`
+	// Retryable is set when the same request may succeed later.
+	Retryable bool `json:"retryable"`
+}
+
+// errorKind describes responses to errors of a kind.
+type errorKind struct {
+	err       error
+	status    int
+	code      string
+	retryable bool
+}
+
+// errorKinds lists kinds of errors returned to clients. Other errors are internal.
+var errorKinds = []errorKind{
+	{err: event.ErrInvalidRequest, status: http.StatusBadRequest, code: "InvalidRequest"},
+	{err: event.ErrForbidden, status: http.StatusForbidden, code: "Forbidden"},
+	{err: event.ErrNotFound, status: http.StatusNotFound, code: "NotFound"},
+	{err: event.ErrConflict, status: http.StatusConflict, code: "Conflict"},
+	{err: event.ErrConnectionFailed, status: http.StatusServiceUnavailable, code: "Unavailable", retryable: true},
+	{err: event.ErrDeadlineExceeded, status: http.StatusGatewayTimeout, code: "Timeout", retryable: true},
+}
+
+// internalError describes responses to internal errors and errors of unknown kind.
+var internalError = errorKind{err: event.ErrInternal, status: http.StatusInternalServerError, code: "Internal"}
+
+// kindOf returns kind of error.
+func kindOf(err error) errorKind {
+	for _, kind := range errorKinds {
+		if errors.Is(err, kind.err) {
+			return kind
+		}
+	}
+
+	return internalError
+}
+
+// writeError sends JSON error response with status code matching kind of err. Message is sent to client instead of err,
+// as err may contain details which must not be exposed.
+func writeError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	kind := kindOf(err)
+
+	b, _ := json.Marshal(errorResponse{
+		Code:      kind.code,
+		Message:   message,
+		RequestID: middleware.GetReqID(r.Context()),
+		Retryable: kind.retryable,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(kind.status)
+	_, _ = w.Write(b)
+}
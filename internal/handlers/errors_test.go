@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+)
+
+func Test_writeError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+		code string
+		// retryable is expected value of retryable field.
+		retryable bool
+	}{
+		{"not found", fmt.Errorf("workflow a in namespace b %w", event.ErrNotFound), http.StatusNotFound, "NotFound", false},
+		{"forbidden", event.ErrForbidden, http.StatusForbidden, "Forbidden", false},
+		{"conflict", event.ErrConflict, http.StatusConflict, "Conflict", false},
+		{"invalid", event.ErrInvalidRequest, http.StatusBadRequest, "InvalidRequest", false},
+		{"unavailable", event.ErrConnectionFailed, http.StatusServiceUnavailable, "Unavailable", true},
+		{"timeout", event.ErrDeadlineExceeded, http.StatusGatewayTimeout, "Timeout", true},
+		{"internal", event.ErrInternal, http.StatusInternalServerError, "Internal", false},
+		{"unknown", event.ErrInvalidEvent, http.StatusInternalServerError, "Internal", false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			router := chi.NewRouter()
+			router.Use(middleware.RequestID)
+			router.Get("/", func(w http.ResponseWriter, r *http.Request) {
+				writeError(w, r, tt.err, "message")
+			})
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			var got errorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+
+			if rec.Code != tt.want || got.Code != tt.code || got.Message != "message" ||
+				got.Retryable != tt.retryable || got.RequestID == "" {
+				t.Errorf("writeError() = %d %+v, want %d with code %s", rec.Code, got, tt.want, tt.code)
+			}
+		})
+	}
+}
@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"context"
 	"encoding/json"
 	"net/http"
 	"time"
@@ -12,36 +11,36 @@ import (
 	"go.uber.org/zap"
 )
 
-func getWorkflow(w http.ResponseWriter, r *http.Request, client argo.Client, log *zap.SugaredLogger) {
+func getWorkflow(w http.ResponseWriter, r *http.Request, client argo.Client, timeout time.Duration, log *zap.SugaredLogger) {
 	namespace, name := chi.URLParam(r, "namespace"), chi.URLParam(r, "name")
 
 	if namespace == "" || name == "" {
 		log.Infof("namespace or name is empty")
-		http.Error(w, "namespace or name is empty", http.StatusBadRequest)
+		writeError(w, r, event.ErrInvalidRequest, "namespace or name is empty")
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	ctx, cancel := argoContext(r, timeout)
 	defer cancel()
 
 	dto, err := client.Get(ctx, namespace, name)
 	if err != nil {
 		log.Infof("error getting workflow %s in namespace %s: %v", name, namespace, err)
-		http.Error(w, "error getting workflow", http.StatusInternalServerError)
+		writeError(w, r, err, "error getting workflow")
 		return
 	}
 
-	workflow, ok := event.FromWorkflow(dto)
+	workflow, ok := convertWorkflow(ctx, dto)
 	if !ok {
 		log.Infof("error converting raw workflow to custom type")
-		http.Error(w, "error converting raw workflow to custom type", http.StatusInternalServerError)
+		writeError(w, r, event.ErrInvalidEvent, "error converting raw workflow to custom type")
 		return
 	}
 
 	b, err := json.Marshal(workflow)
 	if err != nil {
 		log.Infof("error marshaling workflows: %v", err)
-		http.Error(w, "error marshaling workflows", http.StatusInternalServerError)
+		writeError(w, r, err, "error marshaling workflows")
 		return
 	}
 
@@ -49,7 +48,7 @@ func getWorkflow(w http.ResponseWriter, r *http.Request, client argo.Client, log
 
 	if _, err := w.Write(b); err != nil {
 		log.Infof("error writing response: %v", err)
-		http.Error(w, "error writing response", http.StatusInternalServerError)
+		writeError(w, r, err, "error writing response")
 		return
 	}
 }
@@ -2,30 +2,85 @@
 package handlers
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi"
 	"github.com/iskorotkov/chaos-workflows/pkg/argo"
+	"github.com/iskorotkov/chaos-workflows/pkg/eventsse"
 	"github.com/iskorotkov/chaos-workflows/pkg/eventws"
 	"go.uber.org/zap"
 )
 
-// WorkflowsRouter returns configured router.
-func WorkflowsRouter(argoClient argo.Client, wsFactory eventws.WebsocketFactory, log *zap.SugaredLogger) http.Handler {
+// Timeouts limit duration of requests, including Argo calls made by them. Requests aren't limited if timeout is 0.
+type Timeouts struct {
+	// Request limits requests reading workflows, templates and logs.
+	Request time.Duration
+	// Action limits requests submitting, deleting and changing workflows.
+	Action time.Duration
+	// Stream limits watch streams and followed logs.
+	Stream time.Duration
+}
+
+// WorkflowsRouter returns configured router. Finished workflows returned by list and watch requests are passed to recorder,
+// which may be nil. Watch streams can be recorded and replayed only if recordings isn't nil.
+// Watch and log streams are tracked in sessions, which may be nil, so they can be drained on shutdown.
+func WorkflowsRouter(argoClient argo.Client, readerFactory ReaderFactory, wsFactory eventws.WebsocketFactory, sseFactory eventsse.SSEFactory, recorder Recorder, recordings Recordings, sessions *Sessions, timeouts Timeouts, log *zap.SugaredLogger) http.Handler {
 	r := chi.NewRouter()
 
+	if recorder == nil {
+		recorder = nopRecorder{}
+	}
+
+	writerFactory := writerSelector{websocket: wsFactory, sse: sseFactory}
+
 	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
-		listWorkflows(w, argoClient, log.Named("list"))
+		listWorkflows(w, r, argoClient, recorder, timeouts.Request, log.Named("list"))
+	})
+	// Namespace is passed in query, as /{namespace}/watch would be shadowed by a workflow named "watch".
+	r.Get("/watch", func(w http.ResponseWriter, r *http.Request) {
+		watchSelectorWS(w, r, argoClient, writerFactory, recorder, recordings, sessions, timeouts.Stream, log.Named("watch-selector"))
+	})
+	r.Post("/{namespace}", func(w http.ResponseWriter, r *http.Request) {
+		submitWorkflow(w, r, argoClient, timeouts.Action, log.Named("submit"))
 	})
 	r.Get("/{namespace}/{name}", func(w http.ResponseWriter, r *http.Request) {
-		getWorkflow(w, r, argoClient, log.Named("get"))
+		getWorkflow(w, r, argoClient, timeouts.Request, log.Named("get"))
 	})
 	r.Get("/{namespace}/{name}/watch", func(w http.ResponseWriter, r *http.Request) {
-		watchWS(w, r, argoClient, wsFactory, log.Named("watch"))
+		watchWS(w, r, readerFactory, writerFactory, recorder, recordings, sessions, timeouts.Stream, log.Named("watch"))
+	})
+	r.Get("/{namespace}/{name}/steps/{step}/logs", func(w http.ResponseWriter, r *http.Request) {
+		stepLogs(w, r, argoClient, writerFactory, sessions, timeouts, log.Named("logs"))
 	})
-	r.Post("/{namespace}/{name}/cancel", func(w http.ResponseWriter, r *http.Request) {
-		cancelWorkflow(w, r, argoClient, log.Named("cancel"))
+	r.Delete("/{namespace}/{name}", func(w http.ResponseWriter, r *http.Request) {
+		workflowActionHandler(w, r, argoClient, "delete", timeouts.Action, log.Named("delete"))
 	})
 
+	for _, action := range []string{"cancel", "terminate", "suspend", "resume", "retry", "resubmit"} {
+		action := action
+		r.Post(fmt.Sprintf("/{namespace}/{name}/%s", action), func(w http.ResponseWriter, r *http.Request) {
+			workflowActionHandler(w, r, argoClient, action, timeouts.Action, log.Named(action))
+		})
+	}
+
 	return r
 }
+
+// requestContext returns context of the request limited by timeout unless it's 0. Context is cancelled when the client
+// disconnects, except for hijacked connections, e.g. websockets.
+func requestContext(r *http.Request, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout == 0 {
+		return context.WithCancel(r.Context())
+	}
+
+	return context.WithTimeout(r.Context(), timeout)
+}
+
+// argoContext returns context of the request for Argo calls carrying token of the user who made the request.
+func argoContext(r *http.Request, timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := requestContext(r, timeout)
+	return argo.WithForwardedToken(ctx, r.Header.Get("Authorization")), cancel
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Test_argoContext tests that Argo calls are cancelled with the request and limited by timeout.
+func Test_argoContext(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		timeout      time.Duration
+		wantDeadline bool
+	}{
+		{name: "with timeout", timeout: time.Minute, wantDeadline: true},
+		{name: "without timeout", timeout: 0, wantDeadline: false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			reqCtx, cancelRequest := context.WithCancel(context.Background())
+			r := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(reqCtx)
+
+			ctx, cancel := argoContext(r, tt.timeout)
+			defer cancel()
+
+			if _, ok := ctx.Deadline(); ok != tt.wantDeadline {
+				t.Errorf("context has deadline = %v, want %v", ok, tt.wantDeadline)
+			}
+
+			cancelRequest()
+
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+				t.Error("context wasn't cancelled with the request")
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/iskorotkov/chaos-workflows/pkg/argo"
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+	"go.uber.org/zap"
+)
+
+var (
+	errShuttingDown    = errors.New("server is shutting down")
+	errArgoUnavailable = errors.New("argo is unavailable")
+)
+
+// readinessTimeout limits time spent on checking connection to Argo.
+const readinessTimeout = 3 * time.Second
+
+// VersionChecker checks connection to Argo and returns its version.
+type VersionChecker interface {
+	Version(ctx context.Context) (string, error)
+}
+
+// healthResponse is a body of successful health check responses.
+type healthResponse struct {
+	Status string `json:"status"`
+	// ArgoVersion is empty when Argo is accessed through Kubernetes API.
+	ArgoVersion string `json:"argoVersion,omitempty"`
+}
+
+// Health reports whether the service is alive and ready to handle requests.
+// Results of Argo checks are cached, so frequent probes don't load Argo server.
+type Health struct {
+	checker VersionChecker
+	ttl     time.Duration
+	logger  *zap.SugaredLogger
+
+	m            sync.Mutex
+	checkedAt    time.Time
+	version      string
+	err          error
+	shuttingDown bool
+}
+
+// NewHealth returns health checks using checker. Results of checks are reused for ttl.
+func NewHealth(checker VersionChecker, ttl time.Duration, logger *zap.SugaredLogger) *Health {
+	return &Health{
+		checker: checker,
+		ttl:     ttl,
+		logger:  logger,
+	}
+}
+
+// Shutdown marks the service as not ready, so it's removed from load balancing before it stops.
+func (h *Health) Shutdown() {
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	h.shuttingDown = true
+}
+
+// check returns Argo version if the service is ready. Result of Argo check is reused until it expires.
+func (h *Health) check() (string, error) {
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	if h.shuttingDown {
+		return "", errShuttingDown
+	}
+
+	if h.checkedAt.IsZero() || time.Since(h.checkedAt) >= h.ttl {
+		h.refresh()
+	}
+
+	if h.err != nil {
+		return "", errArgoUnavailable
+	}
+
+	return h.version, nil
+}
+
+// refresh checks connection to Argo. It must be called with mutex locked.
+func (h *Health) refresh() {
+	// Probe requests must not cancel the check, as its result is shared. Probes carry no user token.
+	ctx, cancel := context.WithTimeout(argo.WithServiceToken(context.Background()), readinessTimeout)
+	defer cancel()
+
+	h.version, h.err = h.checker.Version(ctx)
+	h.checkedAt = time.Now()
+
+	if h.err != nil {
+		h.logger.Warnf("argo check failed: %v", h.err)
+	}
+}
+
+// Liveness responds with 200 while the process is running.
+func (h *Health) Liveness(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, r, healthResponse{Status: "ok"}, h.logger)
+}
+
+// Readiness responds with 200 and Argo version if Argo is available, and with 503 if it isn't or the service is stopping.
+func (h *Health) Readiness(w http.ResponseWriter, r *http.Request) {
+	version, err := h.check()
+	if err != nil {
+		writeError(w, r, event.ErrConnectionFailed, err.Error())
+		return
+	}
+
+	writeHealth(w, r, healthResponse{Status: "ok", ArgoVersion: version}, h.logger)
+}
+
+// writeHealth writes successful health check response.
+func writeHealth(w http.ResponseWriter, r *http.Request, resp healthResponse, logger *zap.SugaredLogger) {
+	b, err := json.Marshal(resp)
+	if err != nil {
+		logger.Infof("error marshaling health response: %v", err)
+		writeError(w, r, err, "error marshaling health response")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+
+	if _, err := w.Write(b); err != nil {
+		logger.Infof("error writing response: %v", err)
+	}
+}
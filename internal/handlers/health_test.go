@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+	"go.uber.org/zap"
+)
+
+// testChecker mocks VersionChecker.
+type testChecker struct {
+	version string
+	err     error
+	calls   int
+}
+
+func (c *testChecker) Version(context.Context) (string, error) {
+	c.calls++
+	return c.version, c.err
+}
+
+func TestHealth_Readiness(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		err        error
+		ttl        time.Duration
+		wantStatus int
+		wantCalls  int
+	}{
+		{name: "cached success", ttl: time.Minute, wantStatus: http.StatusOK, wantCalls: 1},
+		{name: "cached failure", err: event.ErrConnectionFailed, ttl: time.Minute, wantStatus: http.StatusServiceUnavailable, wantCalls: 1},
+		{name: "no cache", ttl: 0, wantStatus: http.StatusOK, wantCalls: 3},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			checker := &testChecker{version: "v3.2.4", err: tt.err}
+			health := NewHealth(checker, tt.ttl, zap.NewNop().Sugar())
+
+			for i := 0; i < 3; i++ {
+				w := httptest.NewRecorder()
+				health.Readiness(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+				if w.Code != tt.wantStatus {
+					t.Errorf("Readiness() status = %d, want %d", w.Code, tt.wantStatus)
+				}
+			}
+
+			if checker.calls != tt.wantCalls {
+				t.Errorf("argo was checked %d times, want %d", checker.calls, tt.wantCalls)
+			}
+		})
+	}
+}
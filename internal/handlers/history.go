@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/iskorotkov/chaos-workflows/pkg/argo"
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+	"github.com/iskorotkov/chaos-workflows/pkg/history"
+	"go.uber.org/zap"
+)
+
+// Recorder records workflows observed while handling requests.
+// Workflows passed in a single call are recorded together.
+type Recorder interface {
+	Record(workflows ...event.Workflow)
+}
+
+// nopRecorder is used when history is disabled.
+type nopRecorder struct{}
+
+func (nopRecorder) Record(...event.Workflow) {}
+
+// recordingReader records every read event.
+type recordingReader struct {
+	event.Reader
+	recorder Recorder
+}
+
+func (r recordingReader) Read() (event.Workflow, error) {
+	ev, err := r.Reader.Read()
+	if err == nil || err == event.ErrAllRead {
+		r.recorder.Record(ev)
+	}
+
+	return ev, err
+}
+
+// HistoryRouter returns configured router for finished workflows history.
+// If lister isn't nil, callers see only workflows in namespaces they can list workflows in using forwarded token.
+func HistoryRouter(store history.Store, lister history.Lister, timeout time.Duration, log *zap.SugaredLogger) http.Handler {
+	r := chi.NewRouter()
+
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		listHistory(w, r, store, lister, timeout, log.Named("list"))
+	})
+
+	return r
+}
+
+// parseHistoryQuery returns history query from URL query.
+func parseHistoryQuery(values url.Values) (history.Query, error) {
+	q := history.Query{
+		Namespace: values.Get("namespace"),
+		ChaosType: values.Get("type"),
+		Severity:  values.Get("severity"),
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			return history.Query{}, fmt.Errorf("limit must be a non-negative integer")
+		}
+
+		q.Limit = n
+	}
+
+	for param, t := range map[string]*time.Time{
+		"startedAfter":  &q.StartedAfter,
+		"startedBefore": &q.StartedBefore,
+	} {
+		if value := values.Get(param); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return history.Query{}, fmt.Errorf("%s must be in RFC3339 format", param)
+			}
+
+			*t = parsed
+		}
+	}
+
+	return q, nil
+}
+
+// authorizedNamespaces returns which namespaces caller can list workflows in.
+// Access is checked with a single workflow list request per namespace.
+func authorizedNamespaces(ctx context.Context, lister history.Lister, namespaces []string) (map[string]bool, error) {
+	authorized := make(map[string]bool)
+	for _, namespace := range namespaces {
+		if _, ok := authorized[namespace]; ok {
+			continue
+		}
+
+		_, _, err := lister.List(ctx, argo.ListOptions{Namespace: namespace, Limit: 1})
+		switch {
+		case err == nil:
+			authorized[namespace] = true
+		case errors.Is(err, event.ErrForbidden):
+			authorized[namespace] = false
+		default:
+			return nil, err
+		}
+	}
+
+	return authorized, nil
+}
+
+// authorizedHistory returns stored workflows matching query in namespaces caller has access to.
+// If lister isn't nil, callers without a forwarded token are forbidden.
+func authorizedHistory(ctx context.Context, store history.Store, lister history.Lister, query history.Query) ([]event.Workflow, error) {
+	if lister == nil {
+		return store.List(query)
+	}
+
+	if argo.ForwardedToken(ctx) == "" {
+		return nil, fmt.Errorf("no token to check access to history: %w", event.ErrForbidden)
+	}
+
+	if query.Namespace != "" {
+		authorized, err := authorizedNamespaces(ctx, lister, []string{query.Namespace})
+		if err != nil {
+			return nil, err
+		}
+
+		if !authorized[query.Namespace] {
+			return nil, event.ErrForbidden
+		}
+
+		return store.List(query)
+	}
+
+	// Limit is applied after filtering out inaccessible namespaces.
+	limit := query.Limit
+	query.Limit = 0
+
+	workflows, err := store.List(query)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaces := make([]string, len(workflows))
+	for i, w := range workflows {
+		namespaces[i] = w.Namespace
+	}
+
+	authorized, err := authorizedNamespaces(ctx, lister, namespaces)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make([]event.Workflow, 0, len(workflows))
+	for _, w := range workflows {
+		if authorized[w.Namespace] {
+			filtered = append(filtered, w)
+		}
+	}
+
+	if limit != 0 && len(filtered) > limit {
+		filtered = filtered[:limit]
+	}
+
+	return filtered, nil
+}
+
+func listHistory(w http.ResponseWriter, r *http.Request, store history.Store, lister history.Lister, timeout time.Duration, log *zap.SugaredLogger) {
+	query, err := parseHistoryQuery(r.URL.Query())
+	if err != nil {
+		log.Infof("invalid history query: %v", err)
+		writeError(w, r, event.ErrInvalidRequest, err.Error())
+		return
+	}
+
+	ctx, cancel := argoContext(r, timeout)
+	defer cancel()
+
+	workflows, err := authorizedHistory(ctx, store, lister, query)
+	if err != nil {
+		log.Infof("error listing history: %v", err)
+		writeError(w, r, err, "error listing history")
+		return
+	}
+
+	b, err := json.Marshal(workflows)
+	if err != nil {
+		log.Infof("error marshaling workflows: %v", err)
+		writeError(w, r, err, "error marshaling workflows")
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+
+	if _, err := w.Write(b); err != nil {
+		log.Infof("error writing response: %v", err)
+		writeError(w, r, err, "error writing response")
+		return
+	}
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	"github.com/iskorotkov/chaos-workflows/pkg/argo"
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+	"github.com/iskorotkov/chaos-workflows/pkg/history"
+	"go.uber.org/zap"
+)
+
+func Test_parseHistoryQuery(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		query   string
+		want    history.Query
+		wantErr bool
+	}{
+		{name: "no filters", query: "", want: history.Query{}},
+		{
+			name:  "all filters",
+			query: "namespace=litmus&type=pod-delete&severity=critical&startedAfter=2021-11-01T00:00:00Z&startedBefore=2021-11-02T00:00:00Z&limit=10",
+			want: history.Query{
+				Namespace:     "litmus",
+				ChaosType:     "pod-delete",
+				Severity:      "critical",
+				StartedAfter:  time.Date(2021, 11, 1, 0, 0, 0, 0, time.UTC),
+				StartedBefore: time.Date(2021, 11, 2, 0, 0, 0, 0, time.UTC),
+				Limit:         10,
+			},
+		},
+		{name: "invalid time", query: "startedBefore=today", wantErr: true},
+		{name: "invalid limit", query: "limit=ten", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			values, _ := url.ParseQuery(tt.query)
+			got, err := parseHistoryQuery(values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseHistoryQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("parseHistoryQuery() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// testRecorder collects recorded workflows.
+type testRecorder struct {
+	recorded []event.Workflow
+}
+
+func (t *testRecorder) Record(workflows ...event.Workflow) {
+	t.recorded = append(t.recorded, workflows...)
+}
+
+// Test_recordingReader tests that events are recorded including the last one, but not failed reads.
+func Test_recordingReader(t *testing.T) {
+	t.Parallel()
+
+	recorder := &testRecorder{}
+	reader := recordingReader{
+		Reader:   &event.TestReader{Events: []event.Workflow{{Name: "a"}, {Name: "b"}}},
+		recorder: recorder,
+	}
+
+	for {
+		if _, err := reader.Read(); err != nil {
+			break
+		}
+	}
+
+	failing := recordingReader{Reader: &event.TestReader{ErrRead: event.ErrConnectionFailed}, recorder: recorder}
+	if _, err := failing.Read(); err == nil {
+		t.Fatal("read error wasn't returned")
+	}
+
+	if len(recorder.recorded) != 2 || recorder.recorded[0].Name != "a" || recorder.recorded[1].Name != "b" {
+		t.Errorf("recorded %+v, want workflows a and b", recorder.recorded)
+	}
+}
+
+// testLister allows listing workflows only in accessible namespaces.
+type testLister struct {
+	accessible map[string]bool
+}
+
+func (l testLister) List(_ context.Context, opts argo.ListOptions) ([]v1alpha1.Workflow, string, error) {
+	if !l.accessible[opts.Namespace] {
+		return nil, "", event.ErrForbidden
+	}
+
+	return nil, "", nil
+}
+
+// Test_authorizedHistory tests that only workflows in namespaces accessible to caller are returned.
+func Test_authorizedHistory(t *testing.T) {
+	t.Parallel()
+
+	store, err := history.NewStore(filepath.Join(t.TempDir(), "history.db"), zap.NewNop().Sugar())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = store.Close()
+	})
+
+	start := time.Now()
+	for i, namespace := range []string{"open", "secret", "open"} {
+		startedAt := start.Add(time.Duration(i) * time.Second)
+		finishedAt := startedAt.Add(time.Minute)
+		if err := store.Save(event.Workflow{Namespace: namespace, Name: "wf", StartedAt: startedAt, FinishedAt: &finishedAt}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lister := testLister{accessible: map[string]bool{"open": true}}
+	user := argo.WithForwardedToken(context.Background(), "Bearer user")
+
+	tests := []struct {
+		name       string
+		lister     history.Lister
+		ctx        context.Context
+		query      history.Query
+		wantCount  int
+		wantErr    error
+		wantHidden string
+	}{
+		{name: "no authorization", lister: nil, ctx: context.Background(), query: history.Query{}, wantCount: 3},
+		{name: "all namespaces", lister: lister, ctx: user, query: history.Query{}, wantCount: 2, wantHidden: "secret"},
+		{name: "limit after filtering", lister: lister, ctx: user, query: history.Query{Limit: 2}, wantCount: 2, wantHidden: "secret"},
+		{name: "accessible namespace", lister: lister, ctx: user, query: history.Query{Namespace: "open"}, wantCount: 2},
+		{name: "inaccessible namespace", lister: lister, ctx: user, query: history.Query{Namespace: "secret"}, wantErr: event.ErrForbidden},
+		{name: "no token", lister: lister, ctx: context.Background(), query: history.Query{Namespace: "open"}, wantErr: event.ErrForbidden},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := authorizedHistory(tt.ctx, store, tt.lister, tt.query)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("authorizedHistory() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if len(got) != tt.wantCount {
+				t.Errorf("authorizedHistory() returned %d workflows, want %d", len(got), tt.wantCount)
+			}
+
+			for _, w := range got {
+				if w.Namespace == tt.wantHidden {
+					t.Errorf("authorizedHistory() returned workflow in namespace %s", w.Namespace)
+				}
+			}
+		})
+	}
+}
+
+// TestHistoryRouter_forbidden tests that history isn't returned to callers without Authorization header
+// when access is checked in Argo.
+func TestHistoryRouter_forbidden(t *testing.T) {
+	t.Parallel()
+
+	store, err := history.NewStore(filepath.Join(t.TempDir(), "history.db"), zap.NewNop().Sugar())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = store.Close()
+	})
+
+	router := HistoryRouter(store, testLister{accessible: map[string]bool{"open": true}}, time.Minute, zap.NewNop().Sugar())
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantStatus    int
+	}{
+		{name: "no authorization", wantStatus: http.StatusForbidden},
+		{name: "forwarded token", authorization: "Bearer user", wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/?namespace=open", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -3,48 +3,228 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/iskorotkov/chaos-workflows/pkg/argo"
 	"github.com/iskorotkov/chaos-workflows/pkg/event"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
-func listWorkflows(w http.ResponseWriter, client argo.Client, log *zap.SugaredLogger) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+// continueHeader is a response header with a token to get the next page of workflows.
+const continueHeader = "X-Continue-Token"
+
+// listQuery is a parsed query of list request.
+type listQuery struct {
+	argo argo.ListOptions
+
+	chaosType     string
+	severity      string
+	startedAfter  time.Time
+	startedBefore time.Time
+	sort          string
+}
+
+// sortOrders lists supported sort orders. Leading "-" means descending order.
+var sortOrders = map[string]func(a, b event.Workflow) bool{
+	"name": func(a, b event.Workflow) bool {
+		return a.Namespace < b.Namespace || a.Namespace == b.Namespace && a.Name < b.Name
+	},
+	"startedAt": func(a, b event.Workflow) bool {
+		return a.StartedAt.Before(b.StartedAt)
+	},
+	"finishedAt": func(a, b event.Workflow) bool {
+		if a.FinishedAt == nil || b.FinishedAt == nil {
+			return a.FinishedAt != nil
+		}
+
+		return a.FinishedAt.Before(*b.FinishedAt)
+	},
+}
+
+// parseListQuery returns list request parameters from URL query.
+func parseListQuery(values url.Values) (listQuery, error) {
+	q := listQuery{
+		argo: argo.ListOptions{
+			Namespace:     values.Get("namespace"),
+			LabelSelector: values.Get("selector"),
+			Continue:      values.Get("continue"),
+		},
+		chaosType: values.Get("type"),
+		severity:  values.Get("severity"),
+		sort:      values.Get("sort"),
+	}
+
+	// Chaos filters match steps built from nodes, which archived workflows include only when requested.
+	q.argo.ArchivedNodes = q.chaosType != "" || q.severity != ""
+
+	if _, err := labels.Parse(q.argo.LabelSelector); err != nil {
+		return listQuery{}, fmt.Errorf("invalid label selector: %w", err)
+	}
+
+	for _, phases := range values["status"] {
+		for _, phase := range strings.Split(phases, ",") {
+			if phase = strings.TrimSpace(phase); phase != "" {
+				q.argo.Phases = append(q.argo.Phases, phase)
+			}
+		}
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.ParseInt(limit, 10, 64)
+		if err != nil || n < 0 {
+			return listQuery{}, fmt.Errorf("limit must be a non-negative integer")
+		}
+
+		q.argo.Limit = n
+	}
+
+	for param, t := range map[string]*time.Time{
+		"startedAfter":  &q.startedAfter,
+		"startedBefore": &q.startedBefore,
+	} {
+		if value := values.Get(param); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return listQuery{}, fmt.Errorf("%s must be in RFC3339 format", param)
+			}
+
+			*t = parsed
+		}
+	}
+
+	if _, ok := sortOrders[strings.TrimPrefix(q.sort, "-")]; q.sort != "" && !ok {
+		return listQuery{}, fmt.Errorf("unsupported sort order %q", q.sort)
+	}
+
+	// Workflows are sorted after listing, so sorting a page wouldn't order them across pages.
+	if q.sort != "" && (q.argo.Limit != 0 || q.argo.Continue != "") {
+		return listQuery{}, fmt.Errorf("sort can't be combined with limit or continue")
+	}
+
+	return q, nil
+}
+
+// matches returns whether workflow satisfies filters not supported by Argo.
+func (q listQuery) matches(w event.Workflow) bool {
+	if !q.startedAfter.IsZero() && !w.StartedAt.After(q.startedAfter) ||
+		!q.startedBefore.IsZero() && !w.StartedAt.Before(q.startedBefore) {
+		return false
+	}
+
+	if q.chaosType == "" && q.severity == "" {
+		return true
+	}
+
+	// Chaos annotations are set on steps, so workflow matches if any of its steps matches.
+	return w.HasStep(q.chaosType, q.severity)
+}
+
+// sortWorkflows sorts workflows in requested order.
+func (q listQuery) sortWorkflows(workflows []event.Workflow) {
+	if q.sort == "" {
+		return
+	}
+
+	less := sortOrders[strings.TrimPrefix(q.sort, "-")]
+	descending := strings.HasPrefix(q.sort, "-")
+	sort.SliceStable(workflows, func(i, j int) bool {
+		if descending {
+			return less(workflows[j], workflows[i])
+		}
+
+		return less(workflows[i], workflows[j])
+	})
+}
+
+func listWorkflows(w http.ResponseWriter, r *http.Request, client argo.Client, recorder Recorder, timeout time.Duration, log *zap.SugaredLogger) {
+	query, err := parseListQuery(r.URL.Query())
+	if err != nil {
+		log.Infof("invalid list query: %v", err)
+		writeError(w, r, event.ErrInvalidRequest, err.Error())
+		return
+	}
+
+	ctx, cancel := argoContext(r, timeout)
 	defer cancel()
 
-	workflowsDTOs, err := client.List(ctx)
+	workflows, converted, continueToken, err := collectWorkflows(ctx, client, query, log)
 	if err != nil {
 		log.Infof("error listing workflows: %v", err)
-		http.Error(w, "error listing workflows", http.StatusInternalServerError)
+		writeError(w, r, err, "error listing workflows")
 		return
 	}
 
-	var workflows []event.Workflow
-	for _, dto := range workflowsDTOs {
-		w, ok := event.FromWorkflow(dto)
-		if !ok {
-			log.Infof("skipping workflow: error converting raw workflow to custom type")
-			continue
-		}
+	recorder.Record(converted...)
 
-		workflows = append(workflows, w)
-	}
+	query.sortWorkflows(workflows)
 
 	b, err := json.Marshal(workflows)
 	if err != nil {
 		log.Infof("error marshaling workflows: %v", err)
-		http.Error(w, "error marshaling workflows", http.StatusInternalServerError)
+		writeError(w, r, err, "error marshaling workflows")
 		return
 	}
 
 	w.Header().Add("Content-Type", "application/json")
+	if continueToken != "" {
+		w.Header().Add(continueHeader, continueToken)
+	}
 
 	if _, err := w.Write(b); err != nil {
 		log.Infof("error writing response: %v", err)
-		http.Error(w, "error writing response", http.StatusInternalServerError)
+		writeError(w, r, err, "error writing response")
 		return
 	}
 }
+
+// collectWorkflows returns up to limit workflows matching query, all converted workflows and a token to get the next page.
+// Argo pages are requested until enough workflows match filters not supported by Argo. Each page is limited to the number
+// of missing workflows, so the token continues right after the last returned workflow.
+func collectWorkflows(ctx context.Context, client argo.Client, query listQuery, log *zap.SugaredLogger) ([]event.Workflow, []event.Workflow, string, error) {
+	var workflows, converted []event.Workflow
+
+	opts := query.argo
+	for {
+		if query.argo.Limit != 0 {
+			opts.Limit = query.argo.Limit - int64(len(workflows))
+		}
+
+		workflowsDTOs, continueToken, err := client.List(ctx, opts)
+		if err != nil {
+			return nil, nil, "", err
+		}
+
+		_, span := tracer.Start(ctx, "convert workflows", trace.WithAttributes(attribute.Int("workflows.count", len(workflowsDTOs))))
+
+		for _, dto := range workflowsDTOs {
+			w, ok := event.FromWorkflow(dto)
+			if !ok {
+				log.Infof("skipping workflow: error converting raw workflow to custom type")
+				continue
+			}
+
+			converted = append(converted, w)
+
+			if query.matches(w) {
+				workflows = append(workflows, w)
+			}
+		}
+
+		span.End()
+
+		if continueToken == "" || query.argo.Limit == 0 || int64(len(workflows)) >= query.argo.Limit {
+			return workflows, converted, continueToken, nil
+		}
+
+		opts.Continue = continueToken
+	}
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+)
+
+// Test_parseListQuery tests filtering and sorting of listed workflows.
+func Test_parseListQuery(t *testing.T) {
+	t.Parallel()
+
+	started := func(hour int) time.Time {
+		return time.Date(2021, 11, 1, hour, 0, 0, 0, time.UTC)
+	}
+	workflow := func(name string, hour int, chaosType, severity string) event.Workflow {
+		return event.Workflow{
+			Name:      name,
+			StartedAt: started(hour),
+			Stages: []event.Stage{{
+				Steps: []event.Step{{Type: chaosType, Severity: severity}},
+			}},
+		}
+	}
+
+	nested := workflow("d", 4, "", "")
+	nested.Stages[0].Steps[0].Stages = []event.Stage{{
+		Steps: []event.Step{{Type: "pod-cpu-hog", Severity: "light"}},
+	}}
+
+	workflows := []event.Workflow{
+		workflow("b", 3, "pod-delete", "critical"),
+		workflow("a", 1, "pod-delete", "light"),
+		workflow("c", 2, "network-loss", "critical"),
+		nested,
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		wantNames []string
+		wantErr   bool
+	}{
+		{name: "no filters", query: "", wantNames: []string{"b", "a", "c", "d"}},
+		{name: "chaos type", query: "type=pod-delete", wantNames: []string{"b", "a"}},
+		{name: "type and severity on the same step", query: "type=pod-delete&severity=critical", wantNames: []string{"b"}},
+		{name: "nested step", query: "type=pod-cpu-hog", wantNames: []string{"d"}},
+		{name: "started after", query: "startedAfter=2021-11-01T01:30:00Z&sort=startedAt", wantNames: []string{"c", "b", "d"}},
+		{name: "started before", query: "startedBefore=2021-11-01T02:30:00Z&sort=-name", wantNames: []string{"c", "a"}},
+		{name: "sort by name", query: "sort=name", wantNames: []string{"a", "b", "c", "d"}},
+		{name: "sort by start descending", query: "sort=-startedAt", wantNames: []string{"d", "b", "c", "a"}},
+		{name: "unknown sort order", query: "sort=severity", wantErr: true},
+		{name: "sort with limit", query: "sort=name&limit=10", wantErr: true},
+		{name: "sort with continue", query: "sort=name&continue=token", wantErr: true},
+		{name: "invalid time", query: "startedAfter=yesterday", wantErr: true},
+		{name: "invalid limit", query: "limit=-1", wantErr: true},
+		{name: "invalid selector", query: "selector=team%3D%3Dpay%20ments", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			values, _ := url.ParseQuery(tt.query)
+			q, err := parseListQuery(values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseListQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			var result []event.Workflow
+			for _, w := range workflows {
+				if q.matches(w) {
+					result = append(result, w)
+				}
+			}
+
+			q.sortWorkflows(result)
+
+			var names []string
+			for _, w := range result {
+				names = append(names, w.Name)
+			}
+
+			if !reflect.DeepEqual(names, tt.wantNames) {
+				t.Errorf("names = %v, want %v", names, tt.wantNames)
+			}
+		})
+	}
+}
+
+// Test_parseListQuery_argo tests options passed to Argo.
+func Test_parseListQuery_argo(t *testing.T) {
+	t.Parallel()
+
+	values, _ := url.ParseQuery("namespace=litmus&status=running,failed&status=pending&selector=team%3Dpayments&limit=20&continue=token")
+	q, err := parseListQuery(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if q.argo.Namespace != "litmus" ||
+		!reflect.DeepEqual(q.argo.Phases, []string{"running", "failed", "pending"}) ||
+		q.argo.LabelSelector != "team=payments" ||
+		q.argo.Limit != 20 ||
+		q.argo.Continue != "token" ||
+		q.argo.ArchivedNodes {
+		t.Errorf("unexpected argo options: %+v", q.argo)
+	}
+
+	for _, query := range []string{"type=pod-delete", "severity=critical"} {
+		values, _ := url.ParseQuery(query)
+		q, err := parseListQuery(values)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !q.argo.ArchivedNodes {
+			t.Errorf("archived nodes aren't requested for query %q", query)
+		}
+	}
+}
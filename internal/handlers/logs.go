@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/iskorotkov/chaos-workflows/pkg/argo"
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+	"go.uber.org/zap"
+)
+
+// defaultContainer is a container of step pod running the step template.
+const defaultContainer = "main"
+
+// LogWriterFactory creates log entries writers.
+type LogWriterFactory interface {
+	NewLogWriter(w http.ResponseWriter, r *http.Request) (event.LogWriter, error)
+}
+
+// logWriterFactory creates both workflow events and log entries writers.
+type logWriterFactory interface {
+	WriterFactory
+	LogWriterFactory
+}
+
+func (s writerSelector) NewLogWriter(w http.ResponseWriter, r *http.Request) (event.LogWriter, error) {
+	if wantsSSE(r) {
+		return s.sse.NewLogWriter(w, r)
+	}
+
+	return s.websocket.NewLogWriter(w, r)
+}
+
+// parseLogOptions returns log options from URL query.
+func parseLogOptions(values url.Values) (argo.LogOptions, error) {
+	opts := argo.LogOptions{Container: values.Get("container")}
+	if opts.Container == "" {
+		opts.Container = defaultContainer
+	}
+
+	if follow := values.Get("follow"); follow != "" {
+		f, err := strconv.ParseBool(follow)
+		if err != nil {
+			return argo.LogOptions{}, fmt.Errorf("follow must be a boolean")
+		}
+
+		opts.Follow = f
+	}
+
+	if tail := values.Get("tail"); tail != "" {
+		n, err := strconv.ParseInt(tail, 10, 64)
+		if err != nil || n < 0 {
+			return argo.LogOptions{}, fmt.Errorf("tail must be a non-negative integer")
+		}
+
+		opts.TailLines = &n
+	}
+
+	if since := values.Get("sinceTime"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return argo.LogOptions{}, fmt.Errorf("sinceTime must be in RFC3339 format")
+		}
+
+		opts.SinceTime = &t
+	}
+
+	return opts, nil
+}
+
+// isStreamRequest returns whether client asked for websocket or SSE stream.
+func isStreamRequest(r *http.Request) bool {
+	return wantsSSE(r) || r.Header.Get("Upgrade") != ""
+}
+
+// stepLogs handles requests to get step logs. Logs are sent as plain text, or as a stream of JSON entries
+// to websocket and SSE clients. Streams always follow logs.
+func stepLogs(w http.ResponseWriter, r *http.Request, client argo.Client, lwf LogWriterFactory, sessions *Sessions, timeouts Timeouts, logger *zap.SugaredLogger) {
+	namespace, name, step := chi.URLParam(r, "namespace"), chi.URLParam(r, "name"), chi.URLParam(r, "step")
+	if namespace == "" || name == "" || step == "" {
+		logger.Infof("namespace, name or step is empty")
+		writeError(w, r, event.ErrInvalidRequest, "namespace, name or step is empty")
+		return
+	}
+
+	opts, err := parseLogOptions(r.URL.Query())
+	if err != nil {
+		logger.Infof("invalid log options: %v", err)
+		writeError(w, r, event.ErrInvalidRequest, err.Error())
+		return
+	}
+
+	stream := isStreamRequest(r)
+	opts.Follow = opts.Follow || stream
+
+	timeout := timeouts.Request
+	if opts.Follow {
+		timeout = timeouts.Stream
+	}
+
+	ctx, cancel := argoContext(r, timeout)
+	defer cancel()
+
+	if opts.Follow {
+		var done func()
+		ctx, done = sessions.Track(ctx)
+		defer done()
+	}
+
+	dto, err := client.Get(ctx, namespace, name)
+	if err != nil {
+		logger.Infof("error getting workflow %s in namespace %s: %v", name, namespace, err)
+		writeError(w, r, err, "error getting workflow")
+		return
+	}
+
+	podName, ok := event.StepPodName(dto, step)
+	if !ok {
+		logger.Infow("step pod not found", "namespace", namespace, "name", name, "step", step)
+		writeError(w, r, event.ErrNotFound, "step pod not found")
+		return
+	}
+
+	reader, err := client.Logs(ctx, namespace, name, podName, opts)
+	if err != nil {
+		logger.Error(err)
+		writeError(w, r, err, "error reading logs")
+		return
+	}
+	defer closeWithLogger(reader, logger)
+
+	if stream {
+		writer, err := lwf.NewLogWriter(w, r)
+		if err != nil {
+			logger.Error(err)
+			writeError(w, r, err, "error opening stream")
+			return
+		}
+		defer closeWithLogger(writer, logger)
+
+		cancelOnDisconnect(ctx, writer, cancel)
+		transmitLogs(ctx, reader, writer, logger)
+
+		if sessions.Draining() {
+			notifyShutdown(writer, logger)
+		}
+
+		return
+	}
+
+	writePlainLogs(w, r, reader, opts.Follow, logger)
+}
+
+// transmitLogs reads log entries from reader and passes them to writer.
+func transmitLogs(ctx context.Context, reader event.LogReader, writer event.LogWriter, logger *zap.SugaredLogger) {
+	defer logger.Info("all log entries were read")
+
+	for {
+		entry, err := reader.Read()
+		if err == event.ErrAllRead || err == event.ErrDeadlineExceeded {
+			return
+		} else if err != nil {
+			logger.Error(err)
+			return
+		}
+
+		if err := writer.Write(ctx, entry); err == event.ErrDeadlineExceeded {
+			return
+		} else if err != nil {
+			logger.Error(err)
+			return
+		}
+	}
+}
+
+// writePlainLogs writes log lines as plain text. Lines are flushed as they arrive when following logs.
+// Argo reports errors of log requests on the first read, so it's made before response status is sent.
+func writePlainLogs(w http.ResponseWriter, r *http.Request, reader event.LogReader, follow bool, logger *zap.SugaredLogger) {
+	flusher, _ := w.(http.Flusher)
+
+	entry, err := reader.Read()
+	if err != nil && err != event.ErrAllRead {
+		logger.Infof("error reading logs: %v", err)
+		writeError(w, r, err, "error reading logs")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	for ; ; entry, err = reader.Read() {
+		if err == event.ErrAllRead || err == event.ErrDeadlineExceeded {
+			return
+		} else if err != nil {
+			logger.Error(err)
+			return
+		}
+
+		if _, err := fmt.Fprintln(w, entry.Content); err != nil {
+			logger.Infof("error writing response: %v", err)
+			return
+		}
+
+		if follow && flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
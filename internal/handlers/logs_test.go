@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+	"go.uber.org/zap"
+)
+
+// Test_parseLogOptions tests parsing of log options.
+func Test_parseLogOptions(t *testing.T) {
+	t.Parallel()
+
+	values, _ := url.ParseQuery("container=wait&follow=true&tail=100&sinceTime=2021-11-01T00:00:00Z")
+	opts, err := parseLogOptions(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if opts.Container != "wait" || !opts.Follow || opts.TailLines == nil || *opts.TailLines != 100 ||
+		opts.SinceTime == nil || !opts.SinceTime.Equal(time.Date(2021, 11, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected options: %+v", opts)
+	}
+
+	opts, err = parseLogOptions(url.Values{})
+	if err != nil || opts.Container != defaultContainer || opts.Follow || opts.TailLines != nil || opts.SinceTime != nil {
+		t.Errorf("unexpected default options: %+v, %v", opts, err)
+	}
+
+	for _, query := range []string{"follow=maybe", "tail=-1", "tail=ten", "sinceTime=yesterday"} {
+		values, _ := url.ParseQuery(query)
+		if _, err := parseLogOptions(values); err == nil {
+			t.Errorf("query %q was accepted", query)
+		}
+	}
+}
+
+// testLogReader returns log lines until they end and then err or ErrAllRead if err is nil.
+type testLogReader struct {
+	lines []string
+	err   error
+}
+
+func (t *testLogReader) Read() (event.LogEntry, error) {
+	if len(t.lines) == 0 {
+		if t.err != nil {
+			return event.LogEntry{}, t.err
+		}
+
+		return event.LogEntry{}, event.ErrAllRead
+	}
+
+	line := t.lines[0]
+	t.lines = t.lines[1:]
+	return event.LogEntry{PodName: "pod", Content: line}, nil
+}
+
+func (t *testLogReader) Close() error {
+	return nil
+}
+
+// Test_writePlainLogs tests writing of logs as plain text.
+func Test_writePlainLogs(t *testing.T) {
+	t.Parallel()
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	writePlainLogs(w, r, &testLogReader{lines: []string{"injecting chaos", "chaos injected"}}, true, zap.NewNop().Sugar())
+
+	if got, want := w.Body.String(), "injecting chaos\nchaos injected\n"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+
+	if !w.Flushed {
+		t.Error("followed logs weren't flushed")
+	}
+}
+
+// Test_writePlainLogs_error tests that error returned on the first read is sent with its status.
+func Test_writePlainLogs_error(t *testing.T) {
+	t.Parallel()
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	writePlainLogs(w, r, &testLogReader{err: event.ErrInvalidRequest}, false, zap.NewNop().Sugar())
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	if ct := w.Header().Get("Content-Type"); ct == "text/plain; charset=utf-8" {
+		t.Errorf("error was sent as plain text logs")
+	}
+}
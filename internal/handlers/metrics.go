@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "chaos_workflows",
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of HTTP requests by route pattern. Streaming requests are measured until the stream ends.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+	activeSessions = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "chaos_workflows",
+		Name:      "active_sessions",
+		Help:      "Number of open watch and log streams.",
+	})
+	sessionEvents = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "chaos_workflows",
+		Name:      "session_events",
+		Help:      "Number of workflow events sent to a client during a watch session.",
+		Buckets:   prometheus.ExponentialBuckets(1, 4, 8),
+	})
+)
+
+// Metrics is a middleware recording duration and status of requests by route pattern, so paths with different
+// workflow names share the same metrics.
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		requestDuration.WithLabelValues(routePattern(r), r.Method, strconv.Itoa(responseStatus(r, ww))).Observe(time.Since(start).Seconds())
+	})
+}
+
+// routePattern returns route pattern of the request, e.g. "/api/v1/workflows/{namespace}/{name}".
+// Pattern is known only after the request was routed.
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+		return rctx.RoutePattern()
+	}
+
+	return "unmatched"
+}
+
+// responseStatus returns status code written to ww.
+func responseStatus(r *http.Request, ww middleware.WrapResponseWriter) int {
+	status := ww.Status()
+	if status == 0 && strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		// Hijacked connections don't report status to the response writer.
+		return http.StatusSwitchingProtocols
+	} else if status == 0 {
+		return http.StatusOK
+	}
+
+	return status
+}
@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// openAPI is an OpenAPI 3 document describing all API routes.
+//
+//go:embed openapi.json
+var openAPI []byte
+
+// OpenAPI serves OpenAPI 3 document describing all API routes.
+func OpenAPI(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	_, _ = w.Write(openAPI)
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/iskorotkov/chaos-workflows/pkg/argo"
+	"github.com/iskorotkov/chaos-workflows/pkg/eventsse"
+	"github.com/iskorotkov/chaos-workflows/pkg/eventws"
+	"github.com/iskorotkov/chaos-workflows/pkg/history"
+	"go.uber.org/zap"
+)
+
+// TestOpenAPI tests that every route is described in OpenAPI document.
+func TestOpenAPI(t *testing.T) {
+	t.Parallel()
+
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(openAPI, &doc); err != nil {
+		t.Fatalf("invalid OpenAPI document: %v", err)
+	}
+
+	logger := zap.NewNop().Sugar()
+	routers := map[string]http.Handler{
+		"/workflows": WorkflowsRouter(argo.Client{}, nil, eventws.WebsocketFactory{}, eventsse.SSEFactory{}, nil, nil, nil, Timeouts{}, logger),
+		"/templates": TemplatesRouter(argo.Client{}, 0, logger),
+		"/history":   HistoryRouter(history.Store{}, nil, 0, logger),
+	}
+
+	for prefix, router := range routers {
+		walk := func(method string, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+			path := strings.TrimSuffix(prefix+route, "/")
+
+			if _, ok := doc.Paths[path][strings.ToLower(method)]; !ok {
+				t.Errorf("route %s %s isn't described", method, path)
+			}
+
+			return nil
+		}
+
+		if err := chi.Walk(router.(chi.Routes), walk); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
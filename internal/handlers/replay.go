@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+	"github.com/iskorotkov/chaos-workflows/pkg/eventrec"
+	"go.uber.org/zap"
+)
+
+// recordingHeader is a response header with ID of the recording of a watched stream.
+const recordingHeader = "X-Recording-Id"
+
+// Recordings creates and opens recordings of workflow event streams.
+type Recordings interface {
+	Create(namespace, name string) (string, io.WriteCloser, error)
+	Open(id string) (io.ReadCloser, error)
+}
+
+// watchOptions are query parameters of watch requests.
+type watchOptions struct {
+	// replay is an ID of recording to send instead of live events.
+	replay string
+	// speed divides intervals between replayed events.
+	speed float64
+	// record is set when live events must be recorded.
+	record bool
+}
+
+// parseWatchOptions returns watch options from URL query.
+func parseWatchOptions(values url.Values) (watchOptions, error) {
+	opts := watchOptions{replay: values.Get("replay"), speed: 1}
+
+	if speed := values.Get("speed"); speed != "" {
+		s, err := strconv.ParseFloat(speed, 64)
+		if err != nil || s < 0 {
+			return watchOptions{}, fmt.Errorf("speed must be a non-negative number")
+		}
+
+		opts.speed = s
+	}
+
+	if record := values.Get("record"); record != "" {
+		r, err := strconv.ParseBool(record)
+		if err != nil {
+			return watchOptions{}, fmt.Errorf("record must be a boolean")
+		}
+
+		opts.record = r
+	}
+
+	if opts.record && opts.replay != "" {
+		return watchOptions{}, fmt.Errorf("replayed stream can't be recorded")
+	}
+
+	return opts, nil
+}
+
+// recordingWriterFactory creates writers recording all written events.
+type recordingWriterFactory struct {
+	WriterFactory
+	recordings Recordings
+	namespace  string
+	name       string
+	logger     *zap.SugaredLogger
+}
+
+func (f recordingWriterFactory) New(w http.ResponseWriter, r *http.Request) (event.Writer, error) {
+	id, file, err := f.recordings.Create(f.namespace, f.name)
+	if err != nil {
+		f.logger.Error(err)
+		return nil, err
+	}
+
+	// Header must be set before the response is started by writer.
+	w.Header().Set(recordingHeader, id)
+
+	writer, err := f.WriterFactory.New(w, r)
+	if err != nil {
+		closeWithLogger(file, f.logger)
+		return nil, err
+	}
+
+	f.logger.Infow("recording events", "id", id)
+	return eventrec.NewWriter(writer, file, f.logger.Named("recorder")), nil
+}
+
+// withRecording returns writer factory recording events if it was requested.
+func withRecording(wf WriterFactory, recordings Recordings, opts watchOptions, namespace, name string, logger *zap.SugaredLogger) WriterFactory {
+	if !opts.record {
+		return wf
+	}
+
+	return recordingWriterFactory{
+		WriterFactory: wf,
+		recordings:    recordings,
+		namespace:     namespace,
+		name:          name,
+		logger:        logger,
+	}
+}
+
+// replayEvents sends events of a recording instead of live events. Recorded events must belong to namespace
+// and workflow with name, and any namespace or workflow matches if it's empty.
+func replayEvents(w http.ResponseWriter, r *http.Request, recordings Recordings, opts watchOptions, namespace, name string, wf WriterFactory, sessions *Sessions, timeout time.Duration, logger *zap.SugaredLogger) {
+	logger.Infow("replay recording", "id", opts.replay, "speed", opts.speed)
+
+	file, err := recordings.Open(opts.replay)
+	if err == eventrec.ErrInvalidID {
+		writeError(w, r, event.ErrInvalidRequest, err.Error())
+		return
+	} else if err == eventrec.ErrNotFound {
+		writeError(w, r, event.ErrNotFound, err.Error())
+		return
+	} else if err != nil {
+		logger.Error(err)
+		writeError(w, r, err, "error opening recording")
+		return
+	}
+
+	ctx, cancel := requestContext(r, timeout)
+	defer cancel()
+
+	ctx, done := sessions.Track(ctx)
+	defer done()
+
+	reader := &scopedReader{
+		Reader:    eventrec.NewReader(ctx, file, opts.speed, logger.Named("replay")),
+		namespace: namespace,
+		name:      name,
+	}
+	defer closeWithLogger(reader, logger)
+
+	// The first event is read before the stream starts, so recording of another workflow is rejected with an error response.
+	if err := reader.peek(); err == errOutOfScope {
+		logger.Infow("recording doesn't match request", "namespace", namespace, "name", name)
+		writeError(w, r, event.ErrInvalidRequest, "recording was made for another namespace or workflow")
+		return
+	} else if err != nil {
+		logger.Infof("error reading recording: %v", err)
+		writeError(w, r, err, "error reading recording")
+		return
+	}
+
+	writeEvents(ctx, cancel, w, r, reader, wf, sessions, logger)
+}
+
+// errOutOfScope is returned by scopedReader when event doesn't belong to the watched namespace and workflow.
+var errOutOfScope = fmt.Errorf("event is out of watched scope: %w", event.ErrInvalidEvent)
+
+// scopedReader passes events which belong to namespace and workflow with name, and fails on other events.
+// Any namespace or workflow matches if it's empty.
+type scopedReader struct {
+	event.Reader
+	namespace string
+	name      string
+
+	// peeked is an event read in advance by peek.
+	peeked    *event.Workflow
+	peekedErr error
+}
+
+// peek reads the first event in advance and returns an error if it can't be passed to client.
+func (r *scopedReader) peek() error {
+	ev, err := r.Read()
+	r.peeked, r.peekedErr = &ev, err
+
+	if err == event.ErrAllRead {
+		return nil
+	}
+
+	return err
+}
+
+func (r *scopedReader) Read() (event.Workflow, error) {
+	if r.peeked != nil {
+		ev, err := *r.peeked, r.peekedErr
+		r.peeked, r.peekedErr = nil, nil
+		return ev, err
+	}
+
+	ev, err := r.Reader.Read()
+	if err != nil && err != event.ErrAllRead {
+		return ev, err
+	}
+
+	if r.namespace != "" && ev.Namespace != r.namespace || r.name != "" && ev.Name != r.name {
+		return event.Workflow{}, errOutOfScope
+	}
+
+	return ev, err
+}
+
+// parseWatchRequest returns watch options of a request. It writes an error response and returns false if options are invalid.
+func parseWatchRequest(w http.ResponseWriter, r *http.Request, recordings Recordings, logger *zap.SugaredLogger) (watchOptions, bool) {
+	opts, err := parseWatchOptions(r.URL.Query())
+	if err != nil {
+		logger.Infof("invalid watch options: %v", err)
+		writeError(w, r, event.ErrInvalidRequest, err.Error())
+		return watchOptions{}, false
+	}
+
+	if recordings == nil && (opts.record || opts.replay != "") {
+		logger.Info("recordings are disabled")
+		writeError(w, r, event.ErrInvalidRequest, "recordings are disabled")
+		return watchOptions{}, false
+	}
+
+	return opts, true
+}
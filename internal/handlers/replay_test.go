@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+)
+
+func Test_parseWatchOptions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		query   string
+		want    watchOptions
+		wantErr bool
+	}{
+		{name: "live", query: "", want: watchOptions{speed: 1}},
+		{name: "record", query: "record=true", want: watchOptions{speed: 1, record: true}},
+		{name: "replay", query: "replay=chaos_a_1&speed=2.5", want: watchOptions{replay: "chaos_a_1", speed: 2.5}},
+		{name: "replay without delays", query: "replay=chaos_a_1&speed=0", want: watchOptions{replay: "chaos_a_1"}},
+		{name: "negative speed", query: "replay=chaos_a_1&speed=-1", wantErr: true},
+		{name: "invalid record", query: "record=yes", wantErr: true},
+		{name: "record replay", query: "replay=chaos_a_1&record=true", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			values, _ := url.ParseQuery(tt.query)
+			got, err := parseWatchOptions(values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseWatchOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("parseWatchOptions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Test_scopedReader tests that replayed events must belong to the watched namespace and workflow.
+func Test_scopedReader(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		namespace     string
+		workflow      string
+		events        []event.Workflow
+		wantPeekErr   error
+		wantNames     []string
+		wantLastError error
+	}{
+		{
+			name:          "single workflow",
+			namespace:     "chaos",
+			workflow:      "a",
+			events:        []event.Workflow{{Namespace: "chaos", Name: "a"}, {Namespace: "chaos", Name: "a"}},
+			wantNames:     []string{"a", "a"},
+			wantLastError: event.ErrAllRead,
+		},
+		{
+			name:        "another workflow",
+			namespace:   "chaos",
+			workflow:    "a",
+			events:      []event.Workflow{{Namespace: "chaos", Name: "b"}},
+			wantPeekErr: errOutOfScope,
+		},
+		{
+			name:        "another namespace",
+			namespace:   "chaos",
+			events:      []event.Workflow{{Namespace: "litmus", Name: "a"}},
+			wantPeekErr: errOutOfScope,
+		},
+		{
+			name:          "another namespace later in stream",
+			namespace:     "chaos",
+			events:        []event.Workflow{{Namespace: "chaos", Name: "a"}, {Namespace: "litmus", Name: "b"}, {Namespace: "chaos", Name: "c"}},
+			wantNames:     []string{"a"},
+			wantLastError: errOutOfScope,
+		},
+		{
+			name:          "all namespaces",
+			events:        []event.Workflow{{Namespace: "chaos", Name: "a"}, {Namespace: "litmus", Name: "b"}},
+			wantNames:     []string{"a", "b"},
+			wantLastError: event.ErrAllRead,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			reader := &scopedReader{
+				Reader:    &event.TestReader{Events: tt.events},
+				namespace: tt.namespace,
+				name:      tt.workflow,
+			}
+
+			if err := reader.peek(); err != tt.wantPeekErr {
+				t.Fatalf("peek() error = %v, want %v", err, tt.wantPeekErr)
+			}
+
+			if tt.wantPeekErr != nil {
+				return
+			}
+
+			var names []string
+			for {
+				ev, err := reader.Read()
+				if err == nil || err == event.ErrAllRead {
+					names = append(names, ev.Name)
+				}
+
+				if err != nil {
+					if err != tt.wantLastError {
+						t.Errorf("Read() error = %v, want %v", err, tt.wantLastError)
+					}
+
+					break
+				}
+			}
+
+			if !reflect.DeepEqual(names, tt.wantNames) {
+				t.Errorf("Read() names = %v, want %v", names, tt.wantNames)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+	"go.uber.org/zap"
+)
+
+// shutdownNotifyTimeout limits time spent on telling a client about shutdown.
+const shutdownNotifyTimeout = time.Second
+
+// shutdownReason is sent to clients of streams ended by server shutdown.
+const shutdownReason = "server restarting"
+
+// Sessions tracks active streaming sessions, so they can be ended and waited for on shutdown.
+// Methods of nil *Sessions are no-ops.
+type Sessions struct {
+	m        sync.Mutex
+	active   int
+	draining chan struct{}
+	// idle is closed when the last session ends during drain.
+	idle chan struct{}
+}
+
+func NewSessions() *Sessions {
+	return &Sessions{
+		draining: make(chan struct{}),
+		idle:     make(chan struct{}),
+	}
+}
+
+// Track returns context of a new session which is cancelled when sessions are drained. Returned function must be called
+// when the session ends.
+func (s *Sessions) Track(ctx context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	if s == nil {
+		return ctx, cancel
+	}
+
+	s.m.Lock()
+	s.active++
+	s.m.Unlock()
+
+	activeSessions.Inc()
+
+	go func() {
+		select {
+		case <-s.draining:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	var once sync.Once
+	return ctx, func() {
+		once.Do(func() {
+			cancel()
+			activeSessions.Dec()
+
+			s.m.Lock()
+			defer s.m.Unlock()
+
+			s.active--
+			if s.Draining() {
+				s.checkIdle()
+			}
+		})
+	}
+}
+
+// Active returns number of active sessions.
+func (s *Sessions) Active() int {
+	if s == nil {
+		return 0
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	return s.active
+}
+
+// Draining returns whether sessions are being drained.
+func (s *Sessions) Draining() bool {
+	if s == nil {
+		return false
+	}
+
+	select {
+	case <-s.draining:
+		return true
+	default:
+		return false
+	}
+}
+
+// Drain cancels contexts of all sessions, including ones started later, and waits for sessions to end.
+// It returns event.ErrDeadlineExceeded if ctx is done before all sessions ended.
+func (s *Sessions) Drain(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+
+	s.m.Lock()
+	if !s.Draining() {
+		close(s.draining)
+		s.checkIdle()
+	}
+	s.m.Unlock()
+
+	select {
+	case <-s.idle:
+		return nil
+	case <-ctx.Done():
+		return event.ErrDeadlineExceeded
+	}
+}
+
+// checkIdle closes idle channel if there are no active sessions. It must be called with mutex locked.
+func (s *Sessions) checkIdle() {
+	select {
+	case <-s.idle:
+	default:
+		if s.active == 0 {
+			close(s.idle)
+		}
+	}
+}
+
+// cancelOnDisconnect calls cancel when client of writer disconnects before ctx is done, if writer supports it.
+func cancelOnDisconnect(ctx context.Context, writer interface{}, cancel context.CancelFunc) {
+	notifier, ok := writer.(event.DisconnectNotifier)
+	if !ok {
+		return
+	}
+
+	go func() {
+		select {
+		case <-notifier.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+}
+
+// notifyShutdown tells client that the stream was ended by server shutdown if writer supports it.
+func notifyShutdown(writer interface{}, logger *zap.SugaredLogger) {
+	notifier, ok := writer.(event.ShutdownNotifier)
+	if !ok {
+		return
+	}
+
+	// Session context is already cancelled at this point.
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownNotifyTimeout)
+	defer cancel()
+
+	if err := notifier.Shutdown(ctx, shutdownReason); err != nil {
+		logger.Warnf("couldn't notify client about shutdown: %v", err)
+	}
+}
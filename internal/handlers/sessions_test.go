@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+)
+
+func TestSessions(t *testing.T) {
+	t.Parallel()
+
+	sessions := NewSessions()
+
+	ctx, done := sessions.Track(context.Background())
+	if sessions.Active() != 1 || sessions.Draining() {
+		t.Fatalf("Active() = %d, Draining() = %v after session started", sessions.Active(), sessions.Draining())
+	}
+
+	drained := make(chan error, 1)
+	go func() {
+		drained <- sessions.Drain(context.Background())
+	}()
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("session context wasn't cancelled by drain")
+	}
+
+	// Sessions started during drain are cancelled at once.
+	late, lateDone := sessions.Track(context.Background())
+	select {
+	case <-late.Done():
+	case <-time.After(time.Second):
+		t.Fatal("context of session started during drain wasn't cancelled")
+	}
+
+	done()
+	done()
+	lateDone()
+
+	if err := <-drained; err != nil {
+		t.Errorf("Drain() error = %v", err)
+	}
+
+	if sessions.Active() != 0 {
+		t.Errorf("Active() = %d after all sessions ended", sessions.Active())
+	}
+
+	// Sessions ended after drain don't break it.
+	_, done = sessions.Track(context.Background())
+	done()
+}
+
+func TestSessions_Drain_timeout(t *testing.T) {
+	t.Parallel()
+
+	sessions := NewSessions()
+
+	_, done := sessions.Track(context.Background())
+	defer done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := sessions.Drain(ctx); err != event.ErrDeadlineExceeded {
+		t.Errorf("Drain() error = %v, want %v", err, event.ErrDeadlineExceeded)
+	}
+}
+
+func TestSessions_nil(t *testing.T) {
+	t.Parallel()
+
+	var sessions *Sessions
+
+	ctx, done := sessions.Track(context.Background())
+	done()
+
+	if ctx.Err() == nil || sessions.Active() != 0 || sessions.Draining() || sessions.Drain(context.Background()) != nil {
+		t.Error("nil sessions must be no-ops")
+	}
+}
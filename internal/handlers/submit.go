@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/iskorotkov/chaos-workflows/pkg/argo"
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// maxSubmitBodySize limits size of submit request body.
+const maxSubmitBodySize = 1024 * 1024
+
+// submitRequest is a body of submit request.
+type submitRequest struct {
+	Template string `json:"template"`
+	// Kind is either "WorkflowTemplate" (default) or "ClusterWorkflowTemplate".
+	Kind         string            `json:"kind"`
+	Parameters   map[string]string `json:"parameters"`
+	Labels       map[string]string `json:"labels"`
+	GenerateName string            `json:"generateName"`
+}
+
+// parseSubmitRequest returns validated submit options from request body.
+func parseSubmitRequest(body io.Reader) (argo.SubmitOptions, error) {
+	var req submitRequest
+	if err := json.NewDecoder(io.LimitReader(body, maxSubmitBodySize)).Decode(&req); err != nil {
+		return argo.SubmitOptions{}, fmt.Errorf("invalid request body: %w", err)
+	}
+
+	if req.Template == "" {
+		return argo.SubmitOptions{}, fmt.Errorf("template must not be empty")
+	}
+
+	kind := argo.TemplateKind(req.Kind)
+	if kind != "" && kind != argo.WorkflowTemplateKind && kind != argo.ClusterWorkflowTemplateKind {
+		return argo.SubmitOptions{}, fmt.Errorf("kind must be %s or %s", argo.WorkflowTemplateKind, argo.ClusterWorkflowTemplateKind)
+	}
+
+	for name := range req.Parameters {
+		if name == "" || strings.Contains(name, "=") {
+			return argo.SubmitOptions{}, fmt.Errorf("invalid parameter name %q", name)
+		}
+	}
+
+	for k, v := range req.Labels {
+		if errs := append(validation.IsQualifiedName(k), validation.IsValidLabelValue(v)...); len(errs) != 0 {
+			return argo.SubmitOptions{}, fmt.Errorf("invalid label %s=%s: %s", k, v, strings.Join(errs, "; "))
+		}
+	}
+
+	if req.GenerateName != "" {
+		if errs := validation.IsDNS1123Subdomain(strings.TrimSuffix(req.GenerateName, "-")); len(errs) != 0 {
+			return argo.SubmitOptions{}, fmt.Errorf("invalid generateName %q: %s", req.GenerateName, strings.Join(errs, "; "))
+		}
+	}
+
+	return argo.SubmitOptions{
+		Kind:         kind,
+		Template:     req.Template,
+		Parameters:   req.Parameters,
+		Labels:       req.Labels,
+		GenerateName: req.GenerateName,
+	}, nil
+}
+
+func submitWorkflow(w http.ResponseWriter, r *http.Request, client argo.Client, timeout time.Duration, log *zap.SugaredLogger) {
+	namespace := chi.URLParam(r, "namespace")
+	if namespace == "" {
+		log.Infof("namespace is empty")
+		writeError(w, r, event.ErrInvalidRequest, "namespace is empty")
+		return
+	}
+
+	opts, err := parseSubmitRequest(r.Body)
+	if err != nil {
+		log.Infof("invalid submit request: %v", err)
+		writeError(w, r, event.ErrInvalidRequest, err.Error())
+		return
+	}
+
+	ctx, cancel := argoContext(r, timeout)
+	defer cancel()
+
+	dto, err := client.Submit(ctx, namespace, opts)
+	if err != nil {
+		log.Infof("error submitting workflow from template %s in namespace %s: %v", opts.Template, namespace, err)
+		writeError(w, r, err, "error submitting workflow")
+		return
+	}
+
+	workflow, ok := convertWorkflow(ctx, dto)
+	if !ok {
+		log.Infof("error converting raw workflow to custom type")
+		writeError(w, r, event.ErrInvalidEvent, "error converting raw workflow to custom type")
+		return
+	}
+
+	b, err := json.Marshal(workflow)
+	if err != nil {
+		log.Infof("error marshaling workflows: %v", err)
+		writeError(w, r, err, "error marshaling workflows")
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	if _, err := w.Write(b); err != nil {
+		log.Infof("error writing response: %v", err)
+		return
+	}
+}
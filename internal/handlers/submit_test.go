@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/iskorotkov/chaos-workflows/pkg/argo"
+)
+
+// Test_parseSubmitRequest tests validation of submit requests.
+func Test_parseSubmitRequest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		body    string
+		want    argo.SubmitOptions
+		wantErr bool
+	}{
+		{
+			name: "full request",
+			body: `{"template": "pod-chaos", "kind": "ClusterWorkflowTemplate", "parameters": {"duration": "60s"}, "labels": {"team": "payments"}, "generateName": "pod-chaos-"}`,
+			want: argo.SubmitOptions{
+				Kind:         argo.ClusterWorkflowTemplateKind,
+				Template:     "pod-chaos",
+				Parameters:   map[string]string{"duration": "60s"},
+				Labels:       map[string]string{"team": "payments"},
+				GenerateName: "pod-chaos-",
+			},
+		},
+		{name: "template only", body: `{"template": "pod-chaos"}`, want: argo.SubmitOptions{Template: "pod-chaos"}},
+		{name: "no template", body: `{"kind": "WorkflowTemplate"}`, wantErr: true},
+		{name: "unknown kind", body: `{"template": "pod-chaos", "kind": "CronWorkflow"}`, wantErr: true},
+		{name: "invalid parameter", body: `{"template": "pod-chaos", "parameters": {"a=b": "c"}}`, wantErr: true},
+		{name: "invalid label", body: `{"template": "pod-chaos", "labels": {"team": "pay ments"}}`, wantErr: true},
+		{name: "invalid generateName", body: `{"template": "pod-chaos", "generateName": "Pod_Chaos"}`, wantErr: true},
+		{name: "invalid json", body: `template`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseSubmitRequest(strings.NewReader(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSubmitRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSubmitRequest() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/iskorotkov/chaos-workflows/pkg/argo"
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+	"go.uber.org/zap"
+)
+
+// TemplatesRouter returns configured router for workflow templates. Requests are limited by timeout unless it's 0.
+func TemplatesRouter(argoClient argo.Client, timeout time.Duration, log *zap.SugaredLogger) http.Handler {
+	r := chi.NewRouter()
+
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		listTemplates(w, r, argoClient, timeout, log.Named("list"))
+	})
+
+	return r
+}
+
+// boolParam returns value of boolean query parameter or def if it's not set.
+func boolParam(r *http.Request, name string, def bool) (bool, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+
+	return strconv.ParseBool(value)
+}
+
+func listTemplates(w http.ResponseWriter, r *http.Request, client argo.Client, timeout time.Duration, log *zap.SugaredLogger) {
+	cluster, err := boolParam(r, "cluster", true)
+	if err != nil {
+		log.Infof("invalid cluster parameter: %v", err)
+		writeError(w, r, event.ErrInvalidRequest, "cluster must be a boolean")
+		return
+	}
+
+	all, err := boolParam(r, "all", false)
+	if err != nil {
+		log.Infof("invalid all parameter: %v", err)
+		writeError(w, r, event.ErrInvalidRequest, "all must be a boolean")
+		return
+	}
+
+	ctx, cancel := argoContext(r, timeout)
+	defer cancel()
+
+	dtos, err := client.Templates(ctx, r.URL.Query().Get("namespace"))
+	if err != nil {
+		log.Infof("error listing workflow templates: %v", err)
+		writeError(w, r, err, "error listing workflow templates")
+		return
+	}
+
+	templates := make([]event.Template, 0)
+	for _, dto := range dtos {
+		templates = append(templates, event.FromWorkflowTemplate(dto))
+	}
+
+	if cluster {
+		clusterDTOs, err := client.ClusterTemplates(ctx)
+		// Users allowed to list templates only in their namespaces still get them unless they asked for cluster ones.
+		if errors.Is(err, event.ErrForbidden) && r.URL.Query().Get("cluster") == "" {
+			log.Infof("skipping cluster workflow templates: %v", err)
+			err, clusterDTOs = nil, nil
+		}
+
+		if err != nil {
+			log.Infof("error listing cluster workflow templates: %v", err)
+			writeError(w, r, err, "error listing cluster workflow templates")
+			return
+		}
+
+		for _, dto := range clusterDTOs {
+			templates = append(templates, event.FromClusterWorkflowTemplate(dto))
+		}
+	}
+
+	chaosTemplates := make([]event.Template, 0)
+	for _, t := range templates {
+		if all || t.IsChaos() {
+			chaosTemplates = append(chaosTemplates, t)
+		}
+	}
+
+	sort.SliceStable(chaosTemplates, func(i, j int) bool {
+		a, b := chaosTemplates[i], chaosTemplates[j]
+		return a.Namespace < b.Namespace || a.Namespace == b.Namespace && a.Name < b.Name
+	})
+
+	b, err := json.Marshal(chaosTemplates)
+	if err != nil {
+		log.Infof("error marshaling templates: %v", err)
+		writeError(w, r, err, "error marshaling templates")
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+
+	if _, err := w.Write(b); err != nil {
+		log.Infof("error writing response: %v", err)
+		writeError(w, r, err, "error writing response")
+		return
+	}
+}
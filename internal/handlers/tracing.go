@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	"github.com/go-chi/chi/middleware"
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// serviceName is reported in attributes of server spans.
+const serviceName = "chaos-workflows"
+
+var tracer = otel.Tracer("github.com/iskorotkov/chaos-workflows/internal/handlers")
+
+// Tracing is a middleware starting a server span for every request. Trace context is read from W3C traceparent header,
+// so spans of the service are added to traces started by clients.
+func Tracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, "HTTP "+r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(semconv.HTTPServerAttributesFromHTTPRequest(serviceName, "", r)...))
+		defer span.End()
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		// Route context is shared with the request passed to the next handler.
+		route := routePattern(r)
+		span.SetName(r.Method + " " + route)
+		span.SetAttributes(semconv.HTTPRouteKey.String(route))
+
+		status := responseStatus(r, ww)
+		span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(status)...)
+		// Client errors aren't errors of the server.
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
+
+// convertWorkflow converts workflow to custom type, recording conversion as a span.
+func convertWorkflow(ctx context.Context, dto v1alpha1.Workflow) (event.Workflow, bool) {
+	_, span := tracer.Start(ctx, "convert workflow", trace.WithAttributes(
+		attribute.String("workflow.namespace", dto.Namespace),
+		attribute.String("workflow.name", dto.Name)))
+	defer span.End()
+
+	return event.FromWorkflow(dto)
+}
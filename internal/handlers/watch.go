@@ -6,7 +6,9 @@ import (
 	"github.com/iskorotkov/chaos-workflows/pkg/event"
 	"go.uber.org/zap"
 	"io"
+	"k8s.io/apimachinery/pkg/labels"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -16,76 +18,188 @@ type ReaderFactory interface {
 	Close() error
 }
 
+// SelectorReaderFactory creates readers of events of all workflows matching label selector.
+type SelectorReaderFactory interface {
+	Watch(ctx context.Context, namespace, selector string) (event.Reader, error)
+}
+
 // WriterFactory creates workflow events writers.
 type WriterFactory interface {
 	New(w http.ResponseWriter, r *http.Request) (event.Writer, error)
 	Close() error
 }
 
+// writerSelector creates SSE writers for clients accepting event streams and websocket writers otherwise.
+type writerSelector struct {
+	websocket logWriterFactory
+	sse       logWriterFactory
+}
+
+func (s writerSelector) New(w http.ResponseWriter, r *http.Request) (event.Writer, error) {
+	if wantsSSE(r) {
+		return s.sse.New(w, r)
+	}
+
+	return s.websocket.New(w, r)
+}
+
+func (s writerSelector) Close() error {
+	if err := s.websocket.Close(); err != nil {
+		return err
+	}
+
+	return s.sse.Close()
+}
+
+// wantsSSE returns whether client asked for server-sent events instead of websocket connection.
+func wantsSSE(r *http.Request) bool {
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return false
+	}
+
+	for _, accept := range r.Header.Values("Accept") {
+		if strings.Contains(accept, "text/event-stream") {
+			return true
+		}
+	}
+
+	return false
+}
+
 // watchWS handles requests to watch workflow events.
-func watchWS(w http.ResponseWriter, r *http.Request, rf ReaderFactory, wf WriterFactory, logger *zap.SugaredLogger) {
+func watchWS(w http.ResponseWriter, r *http.Request, rf ReaderFactory, wf WriterFactory, recorder Recorder, recordings Recordings, sessions *Sessions, timeout time.Duration, logger *zap.SugaredLogger) {
 	logger.Debug("parse request")
 	namespace, name := chi.URLParam(r, "namespace"), chi.URLParam(r, "name")
 	if namespace == "" || name == "" {
 		logger.Infow("namespace and name must not be empty", "namespace", namespace, "name", name)
-		http.Error(w, "namespace and name must not be empty", http.StatusNotFound)
+		writeError(w, r, event.ErrNotFound, "namespace and name must not be empty")
 		return
 	}
 	logger.Infow("get request params from url", "namespace", namespace, "name", name)
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	opts, ok := parseWatchRequest(w, r, recordings, logger)
+	if !ok {
+		return
+	}
+
+	if opts.replay != "" {
+		replayEvents(w, r, recordings, opts, namespace, name, wf, sessions, timeout, logger)
+		return
+	}
+
+	ctx, cancel := argoContext(r, timeout)
 	defer cancel()
 
+	ctx, done := sessions.Track(ctx)
+	defer done()
+
 	logger.Debug("prepare reader")
 	reader, err := rf.New(ctx, namespace, name)
 	if err != nil {
 		logger.Error(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err, "error watching workflow")
+		return
+	}
+	defer closeWithLogger(reader, logger)
+
+	writeEvents(ctx, cancel, w, r, recordingReader{Reader: reader, recorder: recorder}, withRecording(wf, recordings, opts, namespace, name, logger), sessions, logger)
+}
+
+// watchSelectorWS handles requests to watch events of all workflows in a namespace matching label selector.
+func watchSelectorWS(w http.ResponseWriter, r *http.Request, rf SelectorReaderFactory, wf WriterFactory, recorder Recorder, recordings Recordings, sessions *Sessions, timeout time.Duration, logger *zap.SugaredLogger) {
+	logger.Debug("parse request")
+	namespace, selector := r.URL.Query().Get("namespace"), r.URL.Query().Get("selector")
+	if _, err := labels.Parse(selector); err != nil {
+		logger.Infow("invalid label selector", "selector", selector, "error", err)
+		writeError(w, r, event.ErrInvalidRequest, "invalid label selector")
+		return
+	}
+	logger.Infow("get request params from url", "namespace", namespace, "selector", selector)
+
+	opts, ok := parseWatchRequest(w, r, recordings, logger)
+	if !ok {
+		return
+	}
+
+	if opts.replay != "" {
+		replayEvents(w, r, recordings, opts, namespace, "", wf, sessions, timeout, logger)
+		return
+	}
+
+	ctx, cancel := argoContext(r, timeout)
+	defer cancel()
+
+	ctx, done := sessions.Track(ctx)
+	defer done()
+
+	logger.Debug("prepare reader")
+	reader, err := rf.Watch(ctx, namespace, selector)
+	if err != nil {
+		logger.Error(err)
+		writeError(w, r, err, "error watching workflows")
 		return
 	}
 	defer closeWithLogger(reader, logger)
 
+	writeEvents(ctx, cancel, w, r, recordingReader{Reader: reader, recorder: recorder}, withRecording(wf, recordings, opts, namespace, "", logger), sessions, logger)
+}
+
+// writeEvents creates writer and passes all events from reader to it. Cancel is called if the client disconnects,
+// so it must cancel context of the reader. Client is notified if the stream was ended by draining sessions.
+func writeEvents(ctx context.Context, cancel context.CancelFunc, w http.ResponseWriter, r *http.Request, reader event.Reader, wf WriterFactory, sessions *Sessions, logger *zap.SugaredLogger) {
 	logger.Debug("prepare writer")
 	writer, err := wf.New(w, r)
 	if err != nil {
 		logger.Error(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err, "error opening stream")
 		return
 	}
 	defer closeWithLogger(writer, logger)
 
-	transmitEvents(ctx, reader, writer, logger)
+	cancelOnDisconnect(ctx, writer, cancel)
+	sessionEvents.Observe(float64(transmitEvents(ctx, reader, writer, logger)))
+
+	if sessions.Draining() {
+		logger.Info("stream was ended by shutdown")
+		notifyShutdown(writer, logger)
+	}
+
 	logger.Info("all workflow events were processed")
 }
 
-// transmitEvents reads events from reader and passes them to writer.
-func transmitEvents(ctx context.Context, reader event.Reader, writer event.Writer, logger *zap.SugaredLogger) {
+// transmitEvents reads events from reader and passes them to writer. It returns number of sent events.
+func transmitEvents(ctx context.Context, reader event.Reader, writer event.Writer, logger *zap.SugaredLogger) int {
 	defer logger.Info("all workflow events were read")
 
+	sent := 0
 	for {
 		select {
 		case <-ctx.Done():
 			logger.Debug("context was cancelled while transmitting workflow events")
-			return
+			return sent
 		default:
 			ev, err := reader.Read()
 			if err == event.ErrAllRead {
 				// Send last message and close.
-				if err := writer.Write(ctx, ev); err != nil && err != event.ErrDeadlineExceeded {
+				if err := writer.Write(ctx, ev); err == nil {
+					sent++
+				} else if err != event.ErrDeadlineExceeded {
 					logger.Error(err)
 				}
-				return
+				return sent
 			} else if err != nil {
 				logger.Error(err)
-				return
+				return sent
 			}
 
 			if err := writer.Write(ctx, ev); err == event.ErrDeadlineExceeded {
-				return
+				return sent
 			} else if err != nil {
 				logger.Error(err)
-				return
+				return sent
 			}
+
+			sent++
 		}
 	}
 }
@@ -12,6 +12,7 @@ import (
 	"reflect"
 	"testing"
 	"testing/quick"
+	"time"
 )
 
 // TestReaderFactory mocks ReaderFactory.
@@ -90,7 +91,7 @@ func Test_watchWS(t *testing.T) {
 		// Setup router.
 		router := chi.NewRouter()
 		router.Get("/{namespace}/{name}", func(writer http.ResponseWriter, request *http.Request) {
-			watchWS(writer, request, &readerFactory, &writerFactory, zap.NewNop().Sugar())
+			watchWS(writer, request, &readerFactory, &writerFactory, nopRecorder{}, nil, nil, time.Minute, zap.NewNop().Sugar())
 		})
 
 		// Setup test server.
@@ -126,7 +127,13 @@ func Test_watchWS(t *testing.T) {
 
 		// If reader/writer creation failed.
 		if readerFactory.ErrNew != nil || writerFactory.ErrNew != nil {
-			if len(eventsSent) == 0 && resp.StatusCode == http.StatusInternalServerError {
+			// Status code depends on kind of the first error.
+			errNew := readerFactory.ErrNew
+			if errNew == nil {
+				errNew = writerFactory.ErrNew
+			}
+
+			if len(eventsSent) == 0 && resp.StatusCode == kindOf(errNew).status {
 				t.Logf("reader/writer creation failed")
 				return true
 			} else {
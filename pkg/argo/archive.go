@@ -0,0 +1,340 @@
+package argo
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apiclient/workflow"
+	"github.com/argoproj/argo-workflows/v3/pkg/apiclient/workflowarchive"
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// archiveService returns archived workflow service client and a context to use for a call.
+func (w Client) archiveService(ctx context.Context) (context.Context, workflowarchive.ArchivedWorkflowServiceClient, error) {
+	ctx, client, err := w.connect(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	service, err := client.NewArchivedWorkflowServiceClient()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ctx, service, nil
+}
+
+// ListArchived returns a page of archived workflows and a token to get the next one. The token is empty for the last page.
+// Workflows are sorted by start time in descending order and don't include nodes.
+func (w Client) ListArchived(ctx context.Context, opts ListOptions) ([]v1alpha1.Workflow, string, error) {
+	ctx, client, err := w.archiveService(ctx)
+	if err != nil {
+		return nil, "", w.apiError(err)
+	}
+
+	var fields []string
+	if opts.Namespace != "" {
+		fields = append(fields, fmt.Sprintf("metadata.namespace=%s", opts.Namespace))
+	}
+
+	if !opts.StartedAfter.IsZero() {
+		fields = append(fields, fmt.Sprintf("spec.startedAt>%s", opts.StartedAfter.UTC().Format(time.RFC3339)))
+	}
+
+	var list *v1alpha1.WorkflowList
+	err = call(ctx, archiveServiceName+"ListArchivedWorkflows", func(ctx context.Context) (err error) {
+		list, err = client.ListArchivedWorkflows(ctx, &workflowarchive.ListArchivedWorkflowsRequest{
+			ListOptions: &v1.ListOptions{
+				FieldSelector: strings.Join(fields, ","),
+				LabelSelector: opts.selector(),
+				Limit:         opts.Limit,
+				Continue:      opts.Continue,
+			},
+		})
+		return err
+	})
+	if err != nil {
+		return nil, "", w.apiError(err)
+	}
+
+	return list.Items, list.Continue, nil
+}
+
+// GetArchived returns the latest archived workflow with the name in namespace.
+func (w Client) GetArchived(ctx context.Context, namespace string, name string) (v1alpha1.Workflow, error) {
+	ctx, client, err := w.archiveService(ctx)
+	if err != nil {
+		return v1alpha1.Workflow{}, w.apiError(err)
+	}
+
+	var list *v1alpha1.WorkflowList
+	err = call(ctx, archiveServiceName+"ListArchivedWorkflows", func(ctx context.Context) (err error) {
+		list, err = client.ListArchivedWorkflows(ctx, &workflowarchive.ListArchivedWorkflowsRequest{
+			ListOptions: &v1.ListOptions{
+				FieldSelector: fmt.Sprintf("metadata.namespace=%s,metadata.name=%s", namespace, name),
+				Limit:         1,
+			},
+		})
+		return err
+	})
+	if err != nil {
+		return v1alpha1.Workflow{}, w.apiError(err)
+	}
+
+	if len(list.Items) == 0 {
+		return v1alpha1.Workflow{}, fmt.Errorf("archived workflow %s in namespace %s %w", name, namespace, ErrNotFound)
+	}
+
+	// Listed workflows don't include nodes, so the full workflow is requested separately.
+	return w.getArchivedByUID(ctx, list.Items[0].UID)
+}
+
+// deleteArchived deletes the latest archived workflow with the name in namespace and returns it.
+func (w Client) deleteArchived(ctx context.Context, namespace string, name string) (v1alpha1.Workflow, error) {
+	wf, err := w.GetArchived(ctx, namespace, name)
+	if err != nil {
+		return v1alpha1.Workflow{}, fmt.Errorf("error deleting archived workflow %s in namespace %s: %w", name, namespace, err)
+	}
+
+	ctx, client, err := w.archiveService(ctx)
+	if err != nil {
+		return v1alpha1.Workflow{}, fmt.Errorf("error deleting archived workflow %s in namespace %s: %w", name, namespace, w.apiError(err))
+	}
+
+	err = call(ctx, archiveServiceName+"DeleteArchivedWorkflow", func(ctx context.Context) error {
+		_, err := client.DeleteArchivedWorkflow(ctx, &workflowarchive.DeleteArchivedWorkflowRequest{Uid: string(wf.UID)})
+		return err
+	})
+	if err != nil {
+		return v1alpha1.Workflow{}, fmt.Errorf("error deleting archived workflow %s in namespace %s: %w", name, namespace, w.apiError(err))
+	}
+
+	return wf, nil
+}
+
+// getArchivedByUID returns archived workflow with nodes.
+func (w Client) getArchivedByUID(ctx context.Context, uid types.UID) (v1alpha1.Workflow, error) {
+	ctx, client, err := w.archiveService(ctx)
+	if err != nil {
+		return v1alpha1.Workflow{}, w.apiError(err)
+	}
+
+	var wf *v1alpha1.Workflow
+	err = call(ctx, archiveServiceName+"GetArchivedWorkflow", func(ctx context.Context) (err error) {
+		wf, err = client.GetArchivedWorkflow(ctx, &workflowarchive.GetArchivedWorkflowRequest{
+			Uid: string(uid),
+		})
+		return err
+	})
+	if err != nil {
+		return v1alpha1.Workflow{}, w.apiError(err)
+	}
+
+	return *wf, nil
+}
+
+// mergedToken is a continue token of a page of merged live and archived workflows.
+type mergedToken struct {
+	// Archive is set when all live workflows were returned.
+	Archive bool   `json:"archive,omitempty"`
+	Token   string `json:"token,omitempty"`
+}
+
+func (t mergedToken) encode() string {
+	b, _ := json.Marshal(t)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeMergedToken returns token encoded with encode. Empty string is decoded as the first page token.
+func decodeMergedToken(s string) (mergedToken, error) {
+	var t mergedToken
+	if s == "" {
+		return t, nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return mergedToken{}, fmt.Errorf("invalid continue token %q: %w", s, event.ErrInvalidRequest)
+	}
+
+	if err := json.Unmarshal(b, &t); err != nil {
+		return mergedToken{}, fmt.Errorf("invalid continue token %q: %w", s, event.ErrInvalidRequest)
+	}
+
+	return t, nil
+}
+
+// listMerged returns a page of live workflows followed by archived ones.
+// Archived workflows which are still live are skipped, so a page may contain fewer workflows than requested.
+// Archived workflows include nodes only if ListOptions.ArchivedNodes is set.
+func (w Client) listMerged(ctx context.Context, opts ListOptions) ([]v1alpha1.Workflow, string, error) {
+	token, err := decodeMergedToken(opts.Continue)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var (
+		workflows []v1alpha1.Workflow
+		live      map[types.UID]bool
+	)
+
+	if !token.Archive {
+		liveOpts := opts
+		liveOpts.Continue = token.Token
+
+		items, next, err := w.listLive(ctx, liveOpts)
+		if err != nil {
+			return nil, "", err
+		}
+
+		if next != "" {
+			return items, mergedToken{Token: next}.encode(), nil
+		}
+
+		if opts.Limit != 0 && int64(len(items)) >= opts.Limit {
+			return items, mergedToken{Archive: true}.encode(), nil
+		}
+
+		workflows = items
+
+		// All live workflows were listed in a single call, so there is no need to list them again.
+		if token.Token == "" {
+			live = uids(items)
+		}
+	}
+
+	archiveOpts := opts
+	archiveOpts.Continue = ""
+	if token.Archive {
+		archiveOpts.Continue = token.Token
+	}
+
+	if opts.Limit != 0 {
+		archiveOpts.Limit = opts.Limit - int64(len(workflows))
+	}
+
+	archived, next, err := w.ListArchived(ctx, archiveOpts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if live == nil {
+		live, err = w.liveUIDs(ctx, opts)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	var notLive []v1alpha1.Workflow
+	for _, wf := range archived {
+		if !live[wf.UID] {
+			notLive = append(notLive, wf)
+		}
+	}
+
+	if opts.ArchivedNodes {
+		if notLive, err = w.withArchivedNodes(ctx, notLive); err != nil {
+			return nil, "", err
+		}
+	}
+
+	workflows = append(workflows, notLive...)
+
+	if next != "" {
+		return workflows, mergedToken{Archive: true, Token: next}.encode(), nil
+	}
+
+	return workflows, "", nil
+}
+
+// archivedNodesConcurrency limits a number of archived workflows requested at once to get their nodes.
+const archivedNodesConcurrency = 8
+
+// withArchivedNodes returns archived workflows with nodes in the same order. Archive list doesn't include nodes,
+// so each workflow is requested separately, at most archivedNodesConcurrency at once.
+func (w Client) withArchivedNodes(ctx context.Context, archived []v1alpha1.Workflow) ([]v1alpha1.Workflow, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		m        sync.Mutex
+		firstErr error
+		sem      = make(chan struct{}, archivedNodesConcurrency)
+		result   = make([]v1alpha1.Workflow, len(archived))
+	)
+
+	for i, wf := range archived {
+		sem <- struct{}{}
+		wg.Add(1)
+
+		go func(i int, uid types.UID) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			wf, err := w.getArchivedByUID(ctx, uid)
+			if err != nil {
+				m.Lock()
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				m.Unlock()
+				return
+			}
+
+			result[i] = wf
+		}(i, wf.UID)
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	return result, nil
+}
+
+// liveUIDs returns UIDs of all live workflows matching options. Limit and continue token are ignored.
+func (w Client) liveUIDs(ctx context.Context, opts ListOptions) (map[types.UID]bool, error) {
+	ctx, client, err := w.workflowService(ctx)
+	if err != nil {
+		return nil, w.apiError(err)
+	}
+
+	var list *v1alpha1.WorkflowList
+	err = call(ctx, workflowServiceName+"ListWorkflows", func(ctx context.Context) (err error) {
+		list, err = client.ListWorkflows(ctx, &workflow.WorkflowListRequest{
+			Namespace: opts.Namespace,
+			ListOptions: &v1.ListOptions{
+				LabelSelector: opts.selector(),
+			},
+			Fields: "items.metadata.uid",
+		})
+		return err
+	})
+	if err != nil {
+		return nil, w.apiError(err)
+	}
+
+	return uids(list.Items), nil
+}
+
+// uids returns a set of UIDs of workflows.
+func uids(workflows []v1alpha1.Workflow) map[types.UID]bool {
+	set := make(map[types.UID]bool, len(workflows))
+	for _, wf := range workflows {
+		set[wf.UID] = true
+	}
+
+	return set
+}
@@ -0,0 +1,134 @@
+package argo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	"github.com/iskorotkov/chaos-workflows/pkg/argo/argotest"
+	"go.uber.org/zap"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Test_mergedToken tests encoding and decoding of continue tokens of merged pages.
+func Test_mergedToken(t *testing.T) {
+	t.Parallel()
+
+	f := func(archive bool, token string) bool {
+		want := mergedToken{Archive: archive, Token: token}
+		got, err := decodeMergedToken(want.encode())
+		return err == nil && got == want
+	}
+
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+
+	if got, err := decodeMergedToken(""); err != nil || got != (mergedToken{}) {
+		t.Errorf("empty token decoded as %+v, %v", got, err)
+	}
+
+	for _, token := range []string{"not base64!", "bm90IGpzb24"} {
+		if _, err := decodeMergedToken(token); err == nil {
+			t.Errorf("invalid token %q was accepted", token)
+		}
+	}
+}
+
+// TestClient_Delete tests that garbage-collected workflows are deleted from the archive.
+func TestClient_Delete(t *testing.T) {
+	t.Parallel()
+
+	live := v1alpha1.Workflow{ObjectMeta: v1.ObjectMeta{Name: "live", Namespace: "chaos"}}
+	archived := v1alpha1.Workflow{
+		ObjectMeta: v1.ObjectMeta{Name: "old", Namespace: "chaos", UID: "old-uid"},
+		Status:     v1alpha1.WorkflowStatus{Phase: v1alpha1.WorkflowSucceeded},
+	}
+
+	fake, err := argotest.NewServer(live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(fake.Close)
+
+	fake.Archive(archived)
+
+	client, err := NewClient(Options{URL: fake.URL, Archive: true}, zap.NewNop().Sugar())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	wf, err := client.Delete(context.Background(), "chaos", "old")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if wf.UID != archived.UID || fake.Archived(archived.UID) {
+		t.Errorf("Delete() = %s, archived workflow is kept = %v", wf.UID, fake.Archived(archived.UID))
+	}
+
+	if _, err := client.Delete(context.Background(), "chaos", "live"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Delete(context.Background(), "chaos", "old"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleting deleted archived workflow returned %v, want %v", err, ErrNotFound)
+	}
+}
+
+// TestClient_List_archivedNodes tests that nodes of archived workflows are requested concurrently without changing order.
+func TestClient_List_archivedNodes(t *testing.T) {
+	t.Parallel()
+
+	fake, err := argotest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(fake.Close)
+
+	start := time.Date(2021, 11, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 3*archivedNodesConcurrency; i++ {
+		name := fmt.Sprintf("wf-%02d", i)
+		fake.Archive(v1alpha1.Workflow{
+			ObjectMeta: v1.ObjectMeta{Name: name, Namespace: "chaos", UID: types.UID(name)},
+			Status: v1alpha1.WorkflowStatus{
+				Phase:     v1alpha1.WorkflowSucceeded,
+				StartedAt: v1.NewTime(start.Add(time.Duration(i) * time.Minute)),
+				Nodes:     v1alpha1.Nodes{name: {ID: name, Type: v1alpha1.NodeTypePod}},
+			},
+		})
+	}
+
+	client, err := NewClient(Options{URL: fake.URL, Archive: true}, zap.NewNop().Sugar())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	workflows, _, err := client.List(context.Background(), ListOptions{Namespace: "chaos", ArchivedNodes: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(workflows) != 3*archivedNodesConcurrency {
+		t.Fatalf("List() returned %d workflows, want %d", len(workflows), 3*archivedNodesConcurrency)
+	}
+
+	for i, wf := range workflows {
+		// Archived workflows are sorted by start time in descending order.
+		want := fmt.Sprintf("wf-%02d", len(workflows)-1-i)
+		if wf.Name != want || len(wf.Status.Nodes) != 1 {
+			t.Errorf("workflow %d = %s with %d nodes, want %s with 1 node", i, wf.Name, len(wf.Status.Nodes), want)
+		}
+	}
+}
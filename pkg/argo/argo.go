@@ -3,8 +3,8 @@ package argo
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"io"
 	"strings"
 	"time"
 
@@ -14,146 +14,507 @@ import (
 	"github.com/iskorotkov/chaos-workflows/pkg/event"
 	"go.uber.org/zap"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
+// ErrNotFound is returned when a workflow doesn't exist.
+var ErrNotFound = event.ErrNotFound
+
+// Options configures connection to Argo.
+type Options struct {
+	// URL is Argo server address in host:port format.
+	// Kubernetes API is used directly when URL is empty.
+	URL                string
+	HTTP1              bool
+	Secure             bool
+	InsecureSkipVerify bool
+	// CAFile is a path to PEM bundle used to verify Argo server certificate.
+	CAFile string
+	// Token is a bearer token sent to Argo server.
+	Token string
+	// TokenFile is a path to a file containing bearer token. The file is re-read when it changes.
+	TokenFile string
+	// ForwardToken enables sending tokens of users making requests instead of the service token.
+	// Calls without a forwarded token are rejected unless they are marked with WithServiceToken. It requires URL.
+	ForwardToken bool
+	// Kubeconfig is a path to kubeconfig used when URL is empty. In-cluster config is used if it isn't set.
+	Kubeconfig string
+	// Retry configures reconnection of broken watch streams.
+	Retry RetryOptions
+	// Archive enables reading of archived workflows. It requires Argo server with workflow archive.
+	Archive bool
+}
+
 // Client creates Argo events readers and lists workflows.
 type Client struct {
 	client apiclient.Client
+	// base is a context returned by apiclient. In Kubernetes mode it holds clients for Kubernetes API.
+	base   context.Context
+	opts   Options
+	token  tokenSource
 	logger *zap.SugaredLogger
 }
 
-func NewClient(url string, logger *zap.SugaredLogger) (Client, error) {
-	logger.Info("opening argo gRPC connection")
+func NewClient(opts Options, logger *zap.SugaredLogger) (Client, error) {
+	token, err := newTokenSource(opts)
+	if err != nil {
+		logger.Error(err)
+		return Client{}, event.ErrConnectionFailed
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
-	defer cancel()
+	client := Client{
+		base:   context.Background(),
+		opts:   opts,
+		token:  token,
+		logger: logger,
+	}
 
-	_, apiClient, err := apiclient.NewClientFromOpts(apiclient.Opts{
-		ArgoServerOpts: apiclient.ArgoServerOpts{
-			URL:                url,
-			Path:               "",
-			Secure:             true,
-			InsecureSkipVerify: true,
-			HTTP1:              false,
-		},
-		InstanceID: "",
-		AuthSupplier: func() string {
-			return ""
-		},
-		ClientConfigSupplier: nil,
-		Offline:              false,
-		Context:              ctx,
-	})
-	if err != nil {
-		logger.Errorw(err.Error(), "url", url)
+	if opts.Archive && opts.URL == "" {
+		logger.Error("workflow archive is available only through Argo server")
+		return Client{}, event.ErrConnectionFailed
+	}
+
+	if opts.ForwardToken && opts.URL == "" {
+		logger.Error("tokens can be forwarded only to Argo server")
 		return Client{}, event.ErrConnectionFailed
 	}
 
+	switch {
+	case opts.URL == "":
+		logger.Info("opening kubernetes connection")
+
+		ctx, apiClient, err := apiclient.NewClientFromOpts(apiclient.Opts{
+			ClientConfigSupplier: func() clientcmd.ClientConfig {
+				return kubeConfig(opts.Kubeconfig)
+			},
+			Context: context.Background(),
+		})
+		if err != nil {
+			logger.Errorw(err.Error(), "kubeconfig", opts.Kubeconfig)
+			return Client{}, event.ErrConnectionFailed
+		}
+
+		client.client, client.base = apiClient, ctx
+	case opts.HTTP1:
+		logger.Info("opening argo HTTP1 connection")
+
+		conn, err := newHTTP1Client(opts, client.authorization)
+		if err != nil {
+			logger.Errorw(err.Error(), "url", opts.URL)
+			return Client{}, event.ErrConnectionFailed
+		}
+
+		client.client = conn
+	default:
+		logger.Info("opening argo gRPC connection")
+
+		conn, err := dialGRPC(opts)
+		if err != nil {
+			logger.Errorw(err.Error(), "url", opts.URL)
+			return Client{}, event.ErrConnectionFailed
+		}
+
+		client.client = conn
+	}
+
 	logger.Debug("argo watcher created successfully")
-	return Client{client: apiClient, logger: logger}, nil
+	return client, nil
+}
+
+// newTokenSource returns token source configured in opts.
+func newTokenSource(opts Options) (tokenSource, error) {
+	switch {
+	case opts.Token != "" && opts.TokenFile != "":
+		return nil, fmt.Errorf("token and token file must not be set at the same time")
+	case opts.TokenFile != "":
+		return newFileToken(opts.TokenFile), nil
+	default:
+		return staticToken(opts.Token), nil
+	}
+}
+
+// kubeConfig returns Kubernetes client config loaded from path or from in-cluster environment.
+func kubeConfig(path string) clientcmd.ClientConfig {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = path
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{})
+}
+
+// connect returns API client and a context to use for a call.
+func (w Client) connect(ctx context.Context) (context.Context, apiclient.Client, error) {
+	if w.opts.URL == "" {
+		return valuesContext{Context: ctx, values: w.base}, w.client, nil
+	}
+
+	// HTTP1 client sets token of each request itself.
+	if w.opts.HTTP1 {
+		return ctx, w.client, nil
+	}
+
+	token, err := w.authorization(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if token != "" {
+		ctx = withAuthorization(ctx, token)
+	}
+
+	return ctx, w.client, nil
+}
+
+// authorization returns token to send to Argo server. When tokens are forwarded, calls without a forwarded token are
+// forbidden unless they are made by the service itself, so anonymous requests don't get rights of the service token.
+func (w Client) authorization(ctx context.Context) (string, error) {
+	if !w.opts.ForwardToken {
+		return w.token.Token()
+	}
+
+	if token := ForwardedToken(ctx); token != "" {
+		return token, nil
+	}
+
+	if serviceCall(ctx) {
+		return w.token.Token()
+	}
+
+	return "", fmt.Errorf("no token to forward to argo server: %w", event.ErrForbidden)
+}
+
+// workflowService returns workflow service client and a context to use for a call.
+func (w Client) workflowService(ctx context.Context) (context.Context, workflow.WorkflowServiceClient, error) {
+	ctx, client, err := w.connect(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ctx, client.NewWorkflowServiceClient(), nil
+}
+
+// valuesContext uses deadline and cancellation of embedded context and values of both contexts.
+type valuesContext struct {
+	context.Context
+	values context.Context
+}
+
+func (c valuesContext) Value(key interface{}) interface{} {
+	if v := c.Context.Value(key); v != nil {
+		return v
+	}
+
+	return c.values.Value(key)
+}
+
+// phaseLabel is a label Argo sets to the current phase of a workflow.
+const phaseLabel = "workflows.argoproj.io/phase"
+
+// ListOptions filters and paginates workflows returned by List.
+type ListOptions struct {
+	// Namespace to list workflows in. All namespaces are used if empty.
+	Namespace     string
+	LabelSelector string
+	// Phases lists allowed workflow phases in any case, e.g. "running" or "Failed".
+	Phases []string
+	// Limit is a max number of workflows to return. All workflows are returned if zero.
+	Limit int64
+	// Continue is a token returned by the previous call to get the next page.
+	Continue string
+	// StartedAfter limits archived workflows to ones started after it unless it's zero.
+	// Live workflows aren't limited, as Kubernetes can't select them by start time.
+	StartedAfter time.Time
+	// ArchivedNodes requests archived workflows with nodes, e.g. to filter them by steps.
+	// Archive list doesn't include nodes, so each archived workflow is requested separately, a few at once.
+	// It costs an Argo call per archived workflow, so it should be combined with Limit.
+	ArchivedNodes bool
+}
+
+// selector returns label selector matching both label selector and phases.
+func (o ListOptions) selector() string {
+	var requirements []string
+	if o.LabelSelector != "" {
+		requirements = append(requirements, o.LabelSelector)
+	}
+
+	if len(o.Phases) != 0 {
+		var phases []string
+		for _, phase := range o.Phases {
+			if phase == "" {
+				continue
+			}
+
+			phase = strings.ToLower(phase)
+			phases = append(phases, strings.ToUpper(phase[:1])+phase[1:])
+		}
+
+		requirements = append(requirements, fmt.Sprintf("%s in (%s)", phaseLabel, strings.Join(phases, ",")))
+	}
+
+	return strings.Join(requirements, ",")
 }
 
-func (w Client) List(ctx context.Context) ([]v1alpha1.Workflow, error) {
-	service, err := w.client.NewWorkflowServiceClient().ListWorkflows(ctx, &workflow.WorkflowListRequest{})
+// List returns a page of workflows and a token to get the next one. The token is empty for the last page.
+// Archived workflows are returned after live ones if archive is enabled.
+func (w Client) List(ctx context.Context, opts ListOptions) ([]v1alpha1.Workflow, string, error) {
+	if w.opts.Archive {
+		return w.listMerged(ctx, opts)
+	}
+
+	return w.listLive(ctx, opts)
+}
+
+// listLive returns a page of live workflows and a token to get the next one.
+func (w Client) listLive(ctx context.Context, opts ListOptions) ([]v1alpha1.Workflow, string, error) {
+	ctx, client, err := w.workflowService(ctx)
 	if err != nil {
-		w.logger.Error(err.Error())
-		return nil, event.ErrConnectionFailed
+		return nil, "", w.apiError(err)
 	}
 
-	return service.Items, nil
+	var list *v1alpha1.WorkflowList
+	err = call(ctx, workflowServiceName+"ListWorkflows", func(ctx context.Context) (err error) {
+		list, err = client.ListWorkflows(ctx, &workflow.WorkflowListRequest{
+			Namespace: opts.Namespace,
+			ListOptions: &v1.ListOptions{
+				LabelSelector: opts.selector(),
+				Limit:         opts.Limit,
+				Continue:      opts.Continue,
+			},
+		})
+		return err
+	})
+	if err != nil {
+		return nil, "", w.apiError(err)
+	}
+
+	return list.Items, list.Continue, nil
 }
 
+// Get returns a workflow. The latest archived workflow with the name is returned if archive is enabled
+// and the workflow was garbage-collected.
 func (w Client) Get(ctx context.Context, namespace string, name string) (v1alpha1.Workflow, error) {
-	service, err := w.client.NewWorkflowServiceClient().ListWorkflows(ctx, &workflow.WorkflowListRequest{
-		Namespace: namespace,
-		ListOptions: &v1.ListOptions{
-			FieldSelector: fmt.Sprintf("metadata.name=%s", name),
-		},
+	wf, err := w.getLive(ctx, namespace, name)
+	if errors.Is(err, ErrNotFound) && w.opts.Archive {
+		return w.GetArchived(ctx, namespace, name)
+	}
+
+	return wf, err
+}
+
+// getLive returns a workflow which wasn't garbage-collected.
+func (w Client) getLive(ctx context.Context, namespace string, name string) (v1alpha1.Workflow, error) {
+	ctx, client, err := w.workflowService(ctx)
+	if err != nil {
+		return v1alpha1.Workflow{}, w.apiError(err)
+	}
+
+	var list *v1alpha1.WorkflowList
+	err = call(ctx, workflowServiceName+"ListWorkflows", func(ctx context.Context) (err error) {
+		list, err = client.ListWorkflows(ctx, &workflow.WorkflowListRequest{
+			Namespace: namespace,
+			ListOptions: &v1.ListOptions{
+				FieldSelector: fmt.Sprintf("metadata.name=%s", name),
+			},
+		})
+		return err
 	})
 	if err != nil {
-		w.logger.Error(err.Error())
-		return v1alpha1.Workflow{}, event.ErrConnectionFailed
+		return v1alpha1.Workflow{}, w.apiError(err)
 	}
 
-	if len(service.Items) == 0 {
-		return v1alpha1.Workflow{}, fmt.Errorf("workflow %s in namespace %s not found", name, namespace)
+	if len(list.Items) == 0 {
+		return v1alpha1.Workflow{}, fmt.Errorf("workflow %s in namespace %s %w", name, namespace, ErrNotFound)
 	}
 
-	return service.Items[0], nil
+	return list.Items[0], nil
 }
 
 func (w Client) New(ctx context.Context, namespace string, name string) (event.Reader, error) {
-	service, err := w.client.NewWorkflowServiceClient().WatchWorkflows(ctx, &workflow.WatchWorkflowsRequest{
-		Namespace: namespace,
-		ListOptions: &v1.ListOptions{
-			FieldSelector: fmt.Sprintf("metadata.name=%s", name),
+	selector := fmt.Sprintf("metadata.name=%s", name)
+	stream := &eventStream{
+		single: true,
+		ctx:    ctx,
+		open: func(resourceVersion string) (workflow.WorkflowService_WatchWorkflowsClient, error) {
+			return w.watch(ctx, namespace, v1.ListOptions{
+				FieldSelector:   selector,
+				ResourceVersion: resourceVersion,
+			})
 		},
-	})
+		retry:  w.opts.Retry,
+		logger: w.logger.Named(fmt.Sprintf("%s-%s", namespace, name)),
+	}
+
+	service, err := stream.open("")
+	if err != nil {
+		return nil, w.apiError(err, "selector", selector)
+	}
+
+	stream.service = service
+	return stream, nil
+}
+
+// Watch returns reader of events of all workflows in namespace matching label selector.
+// All namespaces are watched if namespace is empty. Unlike New, the reader doesn't stop when a workflow finishes.
+func (w Client) Watch(ctx context.Context, namespace string, selector string) (event.Reader, error) {
+	stream := &eventStream{
+		ctx: ctx,
+		open: func(resourceVersion string) (workflow.WorkflowService_WatchWorkflowsClient, error) {
+			return w.watch(ctx, namespace, v1.ListOptions{
+				LabelSelector:   selector,
+				ResourceVersion: resourceVersion,
+			})
+		},
+		retry:  w.opts.Retry,
+		logger: w.logger.Named(fmt.Sprintf("%s-watch", namespace)),
+	}
+
+	service, err := stream.open("")
+	if err != nil {
+		return nil, w.apiError(err, "namespace", namespace, "selector", selector)
+	}
+
+	stream.service = service
+	return stream, nil
+}
+
+// watch opens a stream of workflow events.
+func (w Client) watch(ctx context.Context, namespace string, opts v1.ListOptions) (workflow.WorkflowService_WatchWorkflowsClient, error) {
+	ctx, client, err := w.workflowService(ctx)
 	if err != nil {
-		w.logger.Errorw(err.Error(), "selector", fmt.Sprintf("metadata.name=%s", name))
-		return eventStream{}, event.ErrConnectionFailed
+		return nil, err
 	}
 
-	return eventStream{
-		ctx:     ctx,
-		service: service,
-		logger:  w.logger.Named(fmt.Sprintf("%s-%s", namespace, name)),
-	}, nil
+	var stream workflow.WorkflowService_WatchWorkflowsClient
+	err = call(ctx, workflowServiceName+"WatchWorkflows", func(ctx context.Context) (err error) {
+		stream, err = client.WatchWorkflows(ctx, &workflow.WatchWorkflowsRequest{
+			Namespace:   namespace,
+			ListOptions: &opts,
+		})
+		return err
+	})
+
+	return stream, err
 }
 
 func (w Client) Close() error {
+	switch conn := w.client.(type) {
+	case grpcClient:
+		return conn.conn.Close()
+	case http1Client:
+		conn.client.CloseIdleConnections()
+	}
+
 	return nil
 }
 
-func (w Client) Stop(ctx context.Context, namespace string, name string) (v1alpha1.Workflow, error) {
-	wf, err := w.client.NewWorkflowServiceClient().StopWorkflow(ctx, &workflow.WorkflowStopRequest{
-		Namespace: namespace,
-		Name:      name,
-		Message:   "Cancelled via GUI",
-	})
-	if err != nil {
-		return v1alpha1.Workflow{}, fmt.Errorf("error stopping workflow %s in namespace %s: %v", name, namespace, err)
+// defaultStopMessage is a message set on workflows stopped without a reason.
+const defaultStopMessage = "Cancelled via GUI"
+
+// Stop stops a workflow and runs its exit handlers. Message is saved in the workflow as a reason for stopping.
+func (w Client) Stop(ctx context.Context, namespace string, name string, message string) (v1alpha1.Workflow, error) {
+	if message == "" {
+		message = defaultStopMessage
 	}
 
-	return *wf, nil
+	return w.act(ctx, workflowServiceName+"StopWorkflow", "stopping", namespace, name, func(ctx context.Context, client workflow.WorkflowServiceClient) (*v1alpha1.Workflow, error) {
+		return client.StopWorkflow(ctx, &workflow.WorkflowStopRequest{
+			Namespace: namespace,
+			Name:      name,
+			Message:   message,
+		})
+	})
+}
+
+// Terminate immediately stops a workflow without running its exit handlers.
+func (w Client) Terminate(ctx context.Context, namespace string, name string) (v1alpha1.Workflow, error) {
+	return w.act(ctx, workflowServiceName+"TerminateWorkflow", "terminating", namespace, name, func(ctx context.Context, client workflow.WorkflowServiceClient) (*v1alpha1.Workflow, error) {
+		return client.TerminateWorkflow(ctx, &workflow.WorkflowTerminateRequest{
+			Namespace: namespace,
+			Name:      name,
+		})
+	})
 }
 
-// eventStream reads stream of workflow events from Argo server.
-type eventStream struct {
-	ctx     context.Context
-	service workflow.WorkflowService_WatchWorkflowsClient
-	logger  *zap.SugaredLogger
+// Suspend pauses a running workflow. Running steps are finished, but no new steps are started.
+func (w Client) Suspend(ctx context.Context, namespace string, name string) (v1alpha1.Workflow, error) {
+	return w.act(ctx, workflowServiceName+"SuspendWorkflow", "suspending", namespace, name, func(ctx context.Context, client workflow.WorkflowServiceClient) (*v1alpha1.Workflow, error) {
+		return client.SuspendWorkflow(ctx, &workflow.WorkflowSuspendRequest{
+			Namespace: namespace,
+			Name:      name,
+		})
+	})
+}
+
+// Resume continues a suspended workflow.
+func (w Client) Resume(ctx context.Context, namespace string, name string) (v1alpha1.Workflow, error) {
+	return w.act(ctx, workflowServiceName+"ResumeWorkflow", "resuming", namespace, name, func(ctx context.Context, client workflow.WorkflowServiceClient) (*v1alpha1.Workflow, error) {
+		return client.ResumeWorkflow(ctx, &workflow.WorkflowResumeRequest{
+			Namespace: namespace,
+			Name:      name,
+		})
+	})
 }
 
-func (e eventStream) Read() (event.Workflow, error) {
-	msg, err := e.service.Recv()
-	if err == io.EOF {
-		return event.Workflow{}, event.ErrAllRead
-	} else if e.ctx.Err() != nil {
-		return event.Workflow{}, event.ErrDeadlineExceeded
-	} else if err != nil {
-		e.logger.Error(err)
-		return event.Workflow{}, event.ErrConnectionFailed
+// Retry reruns failed steps of a finished workflow.
+func (w Client) Retry(ctx context.Context, namespace string, name string) (v1alpha1.Workflow, error) {
+	return w.act(ctx, workflowServiceName+"RetryWorkflow", "retrying", namespace, name, func(ctx context.Context, client workflow.WorkflowServiceClient) (*v1alpha1.Workflow, error) {
+		return client.RetryWorkflow(ctx, &workflow.WorkflowRetryRequest{
+			Namespace: namespace,
+			Name:      name,
+		})
+	})
+}
+
+// Resubmit creates a new workflow with the same spec and parameters and returns it.
+func (w Client) Resubmit(ctx context.Context, namespace string, name string) (v1alpha1.Workflow, error) {
+	return w.act(ctx, workflowServiceName+"ResubmitWorkflow", "resubmitting", namespace, name, func(ctx context.Context, client workflow.WorkflowServiceClient) (*v1alpha1.Workflow, error) {
+		return client.ResubmitWorkflow(ctx, &workflow.WorkflowResubmitRequest{
+			Namespace: namespace,
+			Name:      name,
+		})
+	})
+}
+
+// Delete deletes a workflow and returns its last state. The latest archived workflow with the name is deleted
+// from the archive if archive is enabled and the workflow was garbage-collected.
+func (w Client) Delete(ctx context.Context, namespace string, name string) (v1alpha1.Workflow, error) {
+	wf, err := w.getLive(ctx, namespace, name)
+	if errors.Is(err, ErrNotFound) && w.opts.Archive {
+		return w.deleteArchived(ctx, namespace, name)
 	}
 
-	ev, ok := event.FromWorkflowEvent(msg)
-	if !ok {
-		e.logger.Error("couldn't convert to custom event")
-		return event.Workflow{}, event.ErrInvalidEvent
+	if err != nil {
+		return v1alpha1.Workflow{}, fmt.Errorf("error deleting workflow %s in namespace %s: %w", name, namespace, err)
 	}
 
-	if strings.ToLower(ev.Status) != "running" && strings.ToLower(ev.Status) != "pending" {
-		return ev, event.ErrAllRead
+	_, err = w.act(ctx, workflowServiceName+"DeleteWorkflow", "deleting", namespace, name, func(ctx context.Context, client workflow.WorkflowServiceClient) (*v1alpha1.Workflow, error) {
+		_, err := client.DeleteWorkflow(ctx, &workflow.WorkflowDeleteRequest{
+			Namespace: namespace,
+			Name:      name,
+		})
+		return &wf, err
+	})
+	if err != nil {
+		return v1alpha1.Workflow{}, err
 	}
 
-	return ev, nil
+	return wf, nil
 }
 
-func (e eventStream) Close() error {
-	if err := e.service.CloseSend(); err != nil {
-		e.logger.Error(err)
-		return nil
+// act calls Argo method to perform an action on a workflow and returns the updated workflow.
+func (w Client) act(ctx context.Context, method string, verb string, namespace string, name string,
+	f func(ctx context.Context, client workflow.WorkflowServiceClient) (*v1alpha1.Workflow, error)) (v1alpha1.Workflow, error) {
+	ctx, client, err := w.workflowService(ctx)
+	if err != nil {
+		return v1alpha1.Workflow{}, fmt.Errorf("error %s workflow %s in namespace %s: %w", verb, name, namespace, w.apiError(err))
 	}
 
-	return nil
+	var wf *v1alpha1.Workflow
+	err = call(ctx, method, func(ctx context.Context) (err error) {
+		wf, err = f(ctx, client)
+		return err
+	})
+	if err != nil {
+		return v1alpha1.Workflow{}, fmt.Errorf("error %s workflow %s in namespace %s: %w", verb, name, namespace, w.apiError(err))
+	}
+
+	return *wf, nil
 }
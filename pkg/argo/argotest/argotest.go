@@ -0,0 +1,651 @@
+// Package argotest provides in-process fake of Argo server for tests.
+package argotest
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apiclient/info"
+	"github.com/argoproj/argo-workflows/v3/pkg/apiclient/workflow"
+	"github.com/argoproj/argo-workflows/v3/pkg/apiclient/workflowarchive"
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// phaseLabel is a label Argo sets to the current phase of a workflow.
+const phaseLabel = "workflows.argoproj.io/phase"
+
+// Version is an Argo version reported by the fake server.
+const Version = "v0.0.0-argotest"
+
+// watchBufferSize is a number of events kept for a slow watch stream before new ones are dropped.
+const watchBufferSize = 64
+
+// Server is a fake Argo server implementing workflow and archived workflow services over plain gRPC.
+// Workflows are kept in memory, and every change is sent to matching watch streams.
+type Server struct {
+	workflow.UnimplementedWorkflowServiceServer
+
+	// URL is an address of the server to use in argo.Options.
+	URL string
+
+	server   *grpc.Server
+	listener net.Listener
+
+	m               sync.Mutex
+	workflows       map[string]*v1alpha1.Workflow
+	archived        map[types.UID]*v1alpha1.Workflow
+	logs            map[string]map[string][]string
+	watchers        map[*watcher]struct{}
+	resourceVersion int
+	done            chan struct{}
+}
+
+// watcher is an open watch stream.
+type watcher struct {
+	namespace string
+	fields    fields.Selector
+	labels    labels.Selector
+	events    chan *workflow.WorkflowWatchEvent
+}
+
+// matches returns whether workflow is watched by the stream.
+func (w *watcher) matches(wf *v1alpha1.Workflow) bool {
+	return matches(wf, w.namespace, w.fields, w.labels)
+}
+
+// Transition is a scripted change of workflow phase.
+type Transition struct {
+	// Delay is a time to wait after the previous transition.
+	Delay time.Duration
+	Phase v1alpha1.WorkflowPhase
+	// Nodes replace workflow nodes if set.
+	Nodes v1alpha1.Nodes
+	// Message is set as workflow message.
+	Message string
+}
+
+// NewServer starts fake server on a random local port with the given workflows.
+func NewServer(workflows ...v1alpha1.Workflow) (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("error listening for fake argo server: %w", err)
+	}
+
+	s := &Server{
+		URL:       listener.Addr().String(),
+		server:    grpc.NewServer(),
+		listener:  listener,
+		workflows: make(map[string]*v1alpha1.Workflow),
+		archived:  make(map[types.UID]*v1alpha1.Workflow),
+		logs:      make(map[string]map[string][]string),
+		watchers:  make(map[*watcher]struct{}),
+		done:      make(chan struct{}),
+	}
+
+	for _, wf := range workflows {
+		s.Add(wf)
+	}
+
+	workflow.RegisterWorkflowServiceServer(s.server, s)
+	info.RegisterInfoServiceServer(s.server, &infoServer{})
+	workflowarchive.RegisterArchivedWorkflowServiceServer(s.server, &archiveServer{server: s})
+
+	go func() {
+		_ = s.server.Serve(listener)
+	}()
+
+	return s, nil
+}
+
+// infoServer implements info service of the fake server.
+type infoServer struct {
+	info.UnimplementedInfoServiceServer
+}
+
+func (*infoServer) GetVersion(context.Context, *info.GetVersionRequest) (*v1alpha1.Version, error) {
+	return &v1alpha1.Version{Version: Version}, nil
+}
+
+// Close stops the server and closes all streams.
+func (s *Server) Close() {
+	close(s.done)
+	s.server.Stop()
+}
+
+// key returns a key of workflow in namespace.
+func key(namespace, name string) string {
+	return fmt.Sprintf("%s/%s", namespace, name)
+}
+
+// Add adds or replaces a workflow.
+func (s *Server) Add(wf v1alpha1.Workflow) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	wf = *wf.DeepCopy()
+	if wf.Labels == nil {
+		wf.Labels = make(map[string]string)
+	}
+
+	if wf.UID == "" {
+		wf.UID = types.UID("uid-" + key(wf.Namespace, wf.Name))
+	}
+
+	wf.Labels[phaseLabel] = string(wf.Status.Phase)
+
+	eventType := "ADDED"
+	if _, ok := s.workflows[key(wf.Namespace, wf.Name)]; ok {
+		eventType = "MODIFIED"
+	}
+
+	s.workflows[key(wf.Namespace, wf.Name)] = &wf
+	s.notify(eventType, &wf)
+}
+
+// Update changes a workflow with update function and returns the updated workflow.
+func (s *Server) Update(namespace, name string, update func(wf *v1alpha1.Workflow)) (v1alpha1.Workflow, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	wf, ok := s.workflows[key(namespace, name)]
+	if !ok {
+		return v1alpha1.Workflow{}, status.Errorf(codes.NotFound, "workflow %s in namespace %s not found", name, namespace)
+	}
+
+	update(wf)
+	wf.Labels[phaseLabel] = string(wf.Status.Phase)
+
+	s.notify("MODIFIED", wf)
+	return *wf.DeepCopy(), nil
+}
+
+// Play applies transitions to a workflow in background. Transitions of deleted workflows are skipped.
+func (s *Server) Play(namespace, name string, transitions ...Transition) {
+	go func() {
+		for _, t := range transitions {
+			select {
+			case <-s.done:
+				return
+			case <-time.After(t.Delay):
+			}
+
+			_, _ = s.Update(namespace, name, func(wf *v1alpha1.Workflow) {
+				setPhase(wf, t.Phase, t.Message)
+				if t.Nodes != nil {
+					wf.Status.Nodes = t.Nodes
+				}
+			})
+		}
+	}()
+}
+
+// setPhase changes workflow phase and updates start and finish times.
+func setPhase(wf *v1alpha1.Workflow, phase v1alpha1.WorkflowPhase, message string) {
+	wf.Status.Phase = phase
+	if message != "" {
+		wf.Status.Message = message
+	}
+
+	if wf.Status.StartedAt.IsZero() && phase != v1alpha1.WorkflowPending {
+		wf.Status.StartedAt = v1.Now()
+	}
+
+	if wf.Status.Fulfilled() && wf.Status.FinishedAt.IsZero() {
+		wf.Status.FinishedAt = v1.Now()
+	}
+}
+
+// notify sends workflow event to all matching watchers. It must be called with mutex locked.
+func (s *Server) notify(eventType string, wf *v1alpha1.Workflow) {
+	s.resourceVersion++
+	wf.ResourceVersion = strconv.Itoa(s.resourceVersion)
+
+	for w := range s.watchers {
+		if !w.matches(wf) {
+			continue
+		}
+
+		select {
+		case w.events <- &workflow.WorkflowWatchEvent{Type: eventType, Object: wf.DeepCopy()}:
+		default:
+			// Slow watchers miss events, as real watch streams do when they are broken.
+		}
+	}
+}
+
+// matches returns whether workflow is in namespace and matches field and label selectors.
+func matches(wf *v1alpha1.Workflow, namespace string, fs fields.Selector, ls labels.Selector) bool {
+	if namespace != "" && wf.Namespace != namespace {
+		return false
+	}
+
+	return fs.Matches(fields.Set{"metadata.name": wf.Name, "metadata.namespace": wf.Namespace}) &&
+		ls.Matches(labels.Set(wf.Labels))
+}
+
+// selectors parses list options selectors.
+func selectors(opts *v1.ListOptions) (fields.Selector, labels.Selector, error) {
+	if opts == nil {
+		return fields.Everything(), labels.Everything(), nil
+	}
+
+	fs, err := fields.ParseSelector(opts.FieldSelector)
+	if err != nil {
+		return nil, nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	ls, err := labels.Parse(opts.LabelSelector)
+	if err != nil {
+		return nil, nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return fs, ls, nil
+}
+
+// list returns matching workflows sorted by namespace and name. It must be called with mutex locked.
+func (s *Server) list(namespace string, fs fields.Selector, ls labels.Selector) []v1alpha1.Workflow {
+	var items []v1alpha1.Workflow
+	for _, wf := range s.workflows {
+		if matches(wf, namespace, fs, ls) {
+			items = append(items, *wf.DeepCopy())
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return key(items[i].Namespace, items[i].Name) < key(items[j].Namespace, items[j].Name)
+	})
+
+	return items
+}
+
+func (s *Server) ListWorkflows(_ context.Context, req *workflow.WorkflowListRequest) (*v1alpha1.WorkflowList, error) {
+	fs, ls, err := selectors(req.ListOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	s.m.Lock()
+	items := s.list(req.Namespace, fs, ls)
+	s.m.Unlock()
+
+	// Continue token is an offset of the next page.
+	list := &v1alpha1.WorkflowList{Items: items}
+	if opts := req.ListOptions; opts != nil && opts.Limit > 0 {
+		offset := 0
+		if opts.Continue != "" {
+			if offset, err = strconv.Atoi(opts.Continue); err != nil || offset < 0 {
+				return nil, status.Errorf(codes.InvalidArgument, "invalid continue token %q", opts.Continue)
+			}
+		}
+
+		if offset > len(items) {
+			offset = len(items)
+		}
+
+		end := offset + int(opts.Limit)
+		if end < len(items) {
+			list.Continue = strconv.Itoa(end)
+		} else {
+			end = len(items)
+		}
+
+		list.Items = items[offset:end]
+	}
+
+	return list, nil
+}
+
+func (s *Server) GetWorkflow(_ context.Context, req *workflow.WorkflowGetRequest) (*v1alpha1.Workflow, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	wf, ok := s.workflows[key(req.Namespace, req.Name)]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "workflow %s in namespace %s not found", req.Name, req.Namespace)
+	}
+
+	return wf.DeepCopy(), nil
+}
+
+// WatchWorkflows sends current states of matching workflows unless resource version is set, and then all their changes.
+func (s *Server) WatchWorkflows(req *workflow.WatchWorkflowsRequest, stream workflow.WorkflowService_WatchWorkflowsServer) error {
+	fs, ls, err := selectors(req.ListOptions)
+	if err != nil {
+		return err
+	}
+
+	s.m.Lock()
+
+	var initial []v1alpha1.Workflow
+	if req.ListOptions == nil || req.ListOptions.ResourceVersion == "" {
+		initial = s.list(req.Namespace, fs, ls)
+	}
+
+	w := &watcher{
+		namespace: req.Namespace,
+		fields:    fs,
+		labels:    ls,
+		events:    make(chan *workflow.WorkflowWatchEvent, len(initial)+watchBufferSize),
+	}
+
+	for i := range initial {
+		w.events <- &workflow.WorkflowWatchEvent{Type: "ADDED", Object: &initial[i]}
+	}
+
+	s.watchers[w] = struct{}{}
+	s.m.Unlock()
+
+	defer func() {
+		s.m.Lock()
+		delete(s.watchers, w)
+		s.m.Unlock()
+	}()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-s.done:
+			return nil
+		case ev := <-w.events:
+			if err := stream.Send(ev); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *Server) DeleteWorkflow(_ context.Context, req *workflow.WorkflowDeleteRequest) (*workflow.WorkflowDeleteResponse, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	wf, ok := s.workflows[key(req.Namespace, req.Name)]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "workflow %s in namespace %s not found", req.Name, req.Namespace)
+	}
+
+	delete(s.workflows, key(req.Namespace, req.Name))
+	s.notify("DELETED", wf)
+	return &workflow.WorkflowDeleteResponse{}, nil
+}
+
+func (s *Server) StopWorkflow(_ context.Context, req *workflow.WorkflowStopRequest) (*v1alpha1.Workflow, error) {
+	return s.finish(req.Namespace, req.Name, req.Message)
+}
+
+func (s *Server) TerminateWorkflow(_ context.Context, req *workflow.WorkflowTerminateRequest) (*v1alpha1.Workflow, error) {
+	return s.finish(req.Namespace, req.Name, "Stopped with strategy 'Terminate'")
+}
+
+// finish fails a running workflow.
+func (s *Server) finish(namespace, name, message string) (*v1alpha1.Workflow, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	wf, ok := s.workflows[key(namespace, name)]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "workflow %s in namespace %s not found", name, namespace)
+	}
+
+	if wf.Status.Fulfilled() {
+		return nil, status.Errorf(codes.FailedPrecondition, "workflow %s in namespace %s is already finished", name, namespace)
+	}
+
+	setPhase(wf, v1alpha1.WorkflowFailed, message)
+	wf.Labels[phaseLabel] = string(wf.Status.Phase)
+
+	s.notify("MODIFIED", wf)
+	return wf.DeepCopy(), nil
+}
+
+func (s *Server) SuspendWorkflow(_ context.Context, req *workflow.WorkflowSuspendRequest) (*v1alpha1.Workflow, error) {
+	return s.suspend(req.Namespace, req.Name, true)
+}
+
+func (s *Server) ResumeWorkflow(_ context.Context, req *workflow.WorkflowResumeRequest) (*v1alpha1.Workflow, error) {
+	return s.suspend(req.Namespace, req.Name, false)
+}
+
+func (s *Server) RetryWorkflow(_ context.Context, req *workflow.WorkflowRetryRequest) (*v1alpha1.Workflow, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	wf, ok := s.workflows[key(req.Namespace, req.Name)]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "workflow %s in namespace %s not found", req.Name, req.Namespace)
+	}
+
+	if wf.Status.Phase != v1alpha1.WorkflowFailed && wf.Status.Phase != v1alpha1.WorkflowError {
+		return nil, status.Errorf(codes.FailedPrecondition, "workflow %s in namespace %s must be Failed/Error to retry", req.Name, req.Namespace)
+	}
+
+	wf.Status.Phase, wf.Status.Message, wf.Status.FinishedAt = v1alpha1.WorkflowRunning, "", v1.Time{}
+	wf.Labels[phaseLabel] = string(wf.Status.Phase)
+
+	s.notify("MODIFIED", wf)
+	return wf.DeepCopy(), nil
+}
+
+// ResubmitWorkflow creates a pending copy of a workflow named after it, as Argo does with generated name.
+func (s *Server) ResubmitWorkflow(_ context.Context, req *workflow.WorkflowResubmitRequest) (*v1alpha1.Workflow, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	wf, ok := s.workflows[key(req.Namespace, req.Name)]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "workflow %s in namespace %s not found", req.Name, req.Namespace)
+	}
+
+	name := fmt.Sprintf("%s-%d", wf.Name, s.resourceVersion+1)
+	resubmitted := &v1alpha1.Workflow{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      name,
+			Namespace: wf.Namespace,
+			UID:       types.UID("uid-" + key(wf.Namespace, name)),
+			Labels: map[string]string{
+				"workflows.argoproj.io/resubmitted-from-workflow": wf.Name,
+				phaseLabel: string(v1alpha1.WorkflowPending),
+			},
+		},
+		Spec:   *wf.Spec.DeepCopy(),
+		Status: v1alpha1.WorkflowStatus{Phase: v1alpha1.WorkflowPending},
+	}
+
+	s.workflows[key(resubmitted.Namespace, resubmitted.Name)] = resubmitted
+	s.notify("ADDED", resubmitted)
+	return resubmitted.DeepCopy(), nil
+}
+
+// suspend sets suspend flag of a workflow.
+func (s *Server) suspend(namespace, name string, suspend bool) (*v1alpha1.Workflow, error) {
+	wf, err := s.Update(namespace, name, func(wf *v1alpha1.Workflow) {
+		wf.Spec.Suspend = &suspend
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &wf, nil
+}
+
+// SetLogs sets log lines of a container of a pod in namespace. Logs of other containers of the pod aren't available.
+func (s *Server) SetLogs(namespace, podName, container string, lines ...string) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	pod := key(namespace, podName)
+	if s.logs[pod] == nil {
+		s.logs[pod] = make(map[string][]string)
+	}
+
+	s.logs[pod][container] = lines
+}
+
+// WorkflowLogs sends all log lines of a container set with SetLogs. Following isn't supported.
+func (s *Server) WorkflowLogs(req *workflow.WorkflowLogRequest, stream workflow.WorkflowService_WorkflowLogsServer) error {
+	s.m.Lock()
+	containers, ok := s.logs[key(req.Namespace, req.PodName)]
+	var container string
+	if req.LogOptions != nil {
+		container = req.LogOptions.Container
+	}
+	lines, found := containers[container]
+	s.m.Unlock()
+
+	if !ok {
+		return status.Errorf(codes.NotFound, "pod %s in namespace %s not found", req.PodName, req.Namespace)
+	}
+
+	if !found {
+		return status.Errorf(codes.InvalidArgument, "container %s is not valid for pod %s", container, req.PodName)
+	}
+
+	for _, line := range lines {
+		if err := stream.Send(&workflow.LogEntry{PodName: req.PodName, Content: line}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Archive adds a workflow to the archive. Archived workflows aren't live unless they are added with Add too.
+func (s *Server) Archive(wf v1alpha1.Workflow) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	wf = *wf.DeepCopy()
+	if wf.UID == "" {
+		wf.UID = types.UID("archived-uid-" + key(wf.Namespace, wf.Name))
+	}
+
+	s.archived[wf.UID] = &wf
+}
+
+// Archived returns whether workflow with UID is in the archive.
+func (s *Server) Archived(uid types.UID) bool {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	_, ok := s.archived[uid]
+	return ok
+}
+
+// archiveServer implements archived workflow service of the fake server.
+type archiveServer struct {
+	workflowarchive.UnimplementedArchivedWorkflowServiceServer
+	server *Server
+}
+
+// ListArchivedWorkflows returns archived workflows sorted by start time in descending order without nodes.
+// Like Argo, it supports only namespace, name and start time field selectors.
+func (a *archiveServer) ListArchivedWorkflows(_ context.Context, req *workflowarchive.ListArchivedWorkflowsRequest) (*v1alpha1.WorkflowList, error) {
+	opts := req.ListOptions
+	if opts == nil {
+		opts = &v1.ListOptions{}
+	}
+
+	ls, err := labels.Parse(opts.LabelSelector)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	var namespace, name string
+	var startedAfter time.Time
+	for _, selector := range strings.Split(opts.FieldSelector, ",") {
+		switch {
+		case selector == "":
+		case strings.HasPrefix(selector, "metadata.namespace="):
+			namespace = strings.TrimPrefix(selector, "metadata.namespace=")
+		case strings.HasPrefix(selector, "metadata.name="):
+			name = strings.TrimPrefix(selector, "metadata.name=")
+		case strings.HasPrefix(selector, "spec.startedAt>"):
+			if startedAfter, err = time.Parse(time.RFC3339, strings.TrimPrefix(selector, "spec.startedAt>")); err != nil {
+				return nil, status.Error(codes.InvalidArgument, err.Error())
+			}
+		default:
+			return nil, status.Errorf(codes.InvalidArgument, "unsupported field selector %q", selector)
+		}
+	}
+
+	a.server.m.Lock()
+	var items []v1alpha1.Workflow
+	for _, wf := range a.server.archived {
+		if namespace != "" && wf.Namespace != namespace || name != "" && wf.Name != name ||
+			!startedAfter.IsZero() && !wf.Status.StartedAt.After(startedAfter) || !ls.Matches(labels.Set(wf.Labels)) {
+			continue
+		}
+
+		item := *wf.DeepCopy()
+		item.Status.Nodes = nil
+		items = append(items, item)
+	}
+	a.server.m.Unlock()
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Status.StartedAt.After(items[j].Status.StartedAt.Time)
+	})
+
+	// Continue token is an offset of the next page.
+	list := &v1alpha1.WorkflowList{Items: items}
+	if opts.Limit > 0 {
+		offset := 0
+		if opts.Continue != "" {
+			if offset, err = strconv.Atoi(opts.Continue); err != nil || offset < 0 {
+				return nil, status.Errorf(codes.InvalidArgument, "invalid continue token %q", opts.Continue)
+			}
+		}
+
+		if offset > len(items) {
+			offset = len(items)
+		}
+
+		end := offset + int(opts.Limit)
+		if end < len(items) {
+			list.Continue = strconv.Itoa(end)
+		} else {
+			end = len(items)
+		}
+
+		list.Items = items[offset:end]
+	}
+
+	return list, nil
+}
+
+func (a *archiveServer) GetArchivedWorkflow(_ context.Context, req *workflowarchive.GetArchivedWorkflowRequest) (*v1alpha1.Workflow, error) {
+	a.server.m.Lock()
+	defer a.server.m.Unlock()
+
+	wf, ok := a.server.archived[types.UID(req.Uid)]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "archived workflow %s not found", req.Uid)
+	}
+
+	return wf.DeepCopy(), nil
+}
+
+func (a *archiveServer) DeleteArchivedWorkflow(_ context.Context, req *workflowarchive.DeleteArchivedWorkflowRequest) (*workflowarchive.ArchivedWorkflowDeletedResponse, error) {
+	a.server.m.Lock()
+	defer a.server.m.Unlock()
+
+	if _, ok := a.server.archived[types.UID(req.Uid)]; !ok {
+		return nil, status.Errorf(codes.NotFound, "archived workflow %s not found", req.Uid)
+	}
+
+	delete(a.server.archived, types.UID(req.Uid))
+	return &workflowarchive.ArchivedWorkflowDeletedResponse{}, nil
+}
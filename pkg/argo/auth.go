@@ -0,0 +1,127 @@
+package argo
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+type forwardedTokenKey struct{}
+
+// WithForwardedToken returns a copy of ctx carrying token of the user who made the request.
+// The token is used instead of the service token when Options.ForwardToken is set.
+func WithForwardedToken(ctx context.Context, token string) context.Context {
+	if token == "" {
+		return ctx
+	}
+
+	return context.WithValue(ctx, forwardedTokenKey{}, token)
+}
+
+// ForwardedToken returns user token stored in ctx.
+func ForwardedToken(ctx context.Context) string {
+	token, _ := ctx.Value(forwardedTokenKey{}).(string)
+	return token
+}
+
+type serviceTokenKey struct{}
+
+// WithServiceToken returns a copy of ctx marking calls as made by the service itself, e.g. by history sweeper.
+// Such calls use the service token even when Options.ForwardToken is set.
+func WithServiceToken(ctx context.Context) context.Context {
+	return context.WithValue(ctx, serviceTokenKey{}, true)
+}
+
+// serviceCall returns whether ctx is marked with WithServiceToken.
+func serviceCall(ctx context.Context) bool {
+	service, _ := ctx.Value(serviceTokenKey{}).(bool)
+	return service
+}
+
+// ForwardsToken returns whether client sends tokens of users making requests instead of the service token.
+func (w Client) ForwardsToken() bool {
+	return w.opts.ForwardToken
+}
+
+// ServiceAuthorized returns whether client can call Argo on its own behalf, e.g. to collect metrics.
+// It's false when tokens are forwarded to Argo server and no service token is configured, as Argo then
+// accepts only tokens of users.
+func (w Client) ServiceAuthorized() bool {
+	return !w.opts.ForwardToken || w.opts.Token != "" || w.opts.TokenFile != ""
+}
+
+// withAuthorization returns a copy of ctx with gRPC authorization metadata set to token.
+func withAuthorization(ctx context.Context, token string) context.Context {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+
+	md.Set("authorization", token)
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// bearer adds Bearer scheme to a raw token.
+func bearer(token string) string {
+	token = strings.TrimSpace(token)
+	if token == "" || strings.Contains(token, " ") {
+		return token
+	}
+
+	return fmt.Sprintf("Bearer %s", token)
+}
+
+// tokenSource supplies token for Argo server.
+type tokenSource interface {
+	Token() (string, error)
+}
+
+// staticToken is a token that never changes.
+type staticToken string
+
+func (t staticToken) Token() (string, error) {
+	return bearer(string(t)), nil
+}
+
+// fileToken reads token from file and re-reads it when the file changes.
+// Service account tokens mounted by Kubernetes are rotated this way.
+type fileToken struct {
+	path string
+
+	m       sync.Mutex
+	modTime time.Time
+	token   string
+}
+
+func newFileToken(path string) *fileToken {
+	return &fileToken{path: path}
+}
+
+func (t *fileToken) Token() (string, error) {
+	info, err := os.Stat(t.path)
+	if err != nil {
+		return "", fmt.Errorf("couldn't stat token file %s: %w", t.path, err)
+	}
+
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	if t.token != "" && info.ModTime().Equal(t.modTime) {
+		return t.token, nil
+	}
+
+	b, err := os.ReadFile(t.path)
+	if err != nil {
+		return "", fmt.Errorf("couldn't read token file %s: %w", t.path, err)
+	}
+
+	t.token, t.modTime = bearer(string(b)), info.ModTime()
+	return t.token, nil
+}
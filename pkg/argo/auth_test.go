@@ -0,0 +1,69 @@
+package argo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+)
+
+func TestClient_ServiceAuthorized(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		opts Options
+		want bool
+	}{
+		{name: "kubernetes", opts: Options{}, want: true},
+		{name: "service token", opts: Options{URL: "argo:2746", Token: "Bearer token"}, want: true},
+		{name: "no token without forwarding", opts: Options{URL: "argo:2746"}, want: true},
+		{name: "forwarding with service token", opts: Options{URL: "argo:2746", ForwardToken: true, Token: "Bearer token"}, want: true},
+		{name: "forwarding with token file", opts: Options{URL: "argo:2746", ForwardToken: true, TokenFile: "/var/run/token"}, want: true},
+		{name: "forwarding without service token", opts: Options{URL: "argo:2746", ForwardToken: true}, want: false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := (Client{opts: tt.opts}).ServiceAuthorized(); got != tt.want {
+				t.Errorf("ServiceAuthorized() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestClient_authorization tests that with forwarding enabled the service token is sent only for service calls.
+func TestClient_authorization(t *testing.T) {
+	t.Parallel()
+
+	user := WithForwardedToken(context.Background(), "Bearer user")
+	service := WithServiceToken(context.Background())
+
+	tests := []struct {
+		name    string
+		forward bool
+		ctx     context.Context
+		want    string
+		wantErr error
+	}{
+		{name: "service token without forwarding", ctx: user, want: "Bearer service"},
+		{name: "forwarded token", forward: true, ctx: user, want: "Bearer user"},
+		{name: "service call", forward: true, ctx: service, want: "Bearer service"},
+		{name: "anonymous call", forward: true, ctx: context.Background(), wantErr: event.ErrForbidden},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := Client{opts: Options{URL: "argo:2746", ForwardToken: tt.forward}, token: staticToken("service")}
+			got, err := client.authorization(tt.ctx)
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("authorization() = %q, %v, want %q, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
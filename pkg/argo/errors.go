@@ -0,0 +1,68 @@
+package argo
+
+import (
+	"errors"
+	"io"
+	"net"
+
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// apiError logs error returned by Argo server or Kubernetes API and returns error of the same kind from event package.
+func (w Client) apiError(err error, keysAndValues ...interface{}) error {
+	w.logger.Errorw(err.Error(), keysAndValues...)
+	return errorKind(err)
+}
+
+// errorKind returns error from event package matching Kubernetes status reason or gRPC status code of err.
+// Failures of transport and overloaded servers are treated as connection errors, and errors of unknown kind are internal.
+func errorKind(err error) error {
+	switch {
+	case errors.Is(err, event.ErrForbidden):
+		// Calls without a forwarded token are rejected before reaching Argo.
+		return event.ErrForbidden
+	case apierrors.IsNotFound(err):
+		return event.ErrNotFound
+	case apierrors.IsForbidden(err), apierrors.IsUnauthorized(err):
+		return event.ErrForbidden
+	case apierrors.IsConflict(err), apierrors.IsAlreadyExists(err):
+		return event.ErrConflict
+	case apierrors.IsBadRequest(err), apierrors.IsInvalid(err):
+		return event.ErrInvalidRequest
+	case apierrors.IsServiceUnavailable(err), apierrors.IsTooManyRequests(err), apierrors.IsServerTimeout(err):
+		return event.ErrConnectionFailed
+	}
+
+	switch status.Code(err) {
+	case codes.NotFound:
+		return event.ErrNotFound
+	case codes.PermissionDenied, codes.Unauthenticated:
+		return event.ErrForbidden
+	case codes.AlreadyExists, codes.FailedPrecondition, codes.Aborted:
+		return event.ErrConflict
+	case codes.InvalidArgument, codes.OutOfRange:
+		return event.ErrInvalidRequest
+	case codes.DeadlineExceeded, codes.Canceled:
+		return event.ErrDeadlineExceeded
+	case codes.Unavailable, codes.ResourceExhausted:
+		return event.ErrConnectionFailed
+	case codes.Unknown:
+		// Errors which aren't gRPC statuses have Unknown code too.
+		if transportError(err) {
+			return event.ErrConnectionFailed
+		}
+
+		return event.ErrInternal
+	default:
+		return event.ErrInternal
+	}
+}
+
+// transportError returns whether err is a failure of connection to Argo server or Kubernetes API.
+func transportError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
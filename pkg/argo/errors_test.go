@@ -0,0 +1,62 @@
+package argo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"syscall"
+	"testing"
+
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func Test_errorKind(t *testing.T) {
+	t.Parallel()
+
+	workflows := schema.GroupResource{Group: "argoproj.io", Resource: "workflows"}
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"grpc not found", status.Error(codes.NotFound, "not found"), event.ErrNotFound},
+		{"grpc permission denied", status.Error(codes.PermissionDenied, "denied"), event.ErrForbidden},
+		{"grpc unauthenticated", status.Error(codes.Unauthenticated, "no token"), event.ErrForbidden},
+		{"grpc already exists", status.Error(codes.AlreadyExists, "exists"), event.ErrConflict},
+		{"grpc failed precondition", status.Error(codes.FailedPrecondition, "finished"), event.ErrConflict},
+		{"grpc invalid argument", status.Error(codes.InvalidArgument, "invalid"), event.ErrInvalidRequest},
+		{"grpc deadline", status.Error(codes.DeadlineExceeded, "timeout"), event.ErrDeadlineExceeded},
+		{"grpc unavailable", status.Error(codes.Unavailable, "unavailable"), event.ErrConnectionFailed},
+		{"grpc resource exhausted", status.Error(codes.ResourceExhausted, "rate limited"), event.ErrConnectionFailed},
+		{"grpc unknown", status.Error(codes.Unknown, "panic"), event.ErrInternal},
+		{"grpc internal", status.Error(codes.Internal, "failed"), event.ErrInternal},
+		{"grpc unimplemented", status.Error(codes.Unimplemented, "unimplemented"), event.ErrInternal},
+		{"kubernetes not found", apierrors.NewNotFound(workflows, "a"), event.ErrNotFound},
+		{"kubernetes forbidden", apierrors.NewForbidden(workflows, "a", errors.New("rbac")), event.ErrForbidden},
+		{"kubernetes conflict", apierrors.NewConflict(workflows, "a", errors.New("modified")), event.ErrConflict},
+		{"kubernetes bad request", apierrors.NewBadRequest("invalid"), event.ErrInvalidRequest},
+		{"kubernetes internal", apierrors.NewInternalError(errors.New("failed")), event.ErrInternal},
+		{"kubernetes unavailable", apierrors.NewServiceUnavailable("unavailable"), event.ErrConnectionFailed},
+		{"connection refused", &url.Error{Op: "Get", URL: "https://argo:2746", Err: &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}}, event.ErrConnectionFailed},
+		{"unexpected eof", fmt.Errorf("reading response: %w", io.ErrUnexpectedEOF), event.ErrConnectionFailed},
+		{"unknown", errors.New("invalid character in response"), event.ErrInternal},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := errorKind(tt.err); got != tt.want {
+				t.Errorf("errorKind() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package argo
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apiclient"
+	"github.com/argoproj/argo-workflows/v3/pkg/apiclient/clusterworkflowtemplate"
+	"github.com/argoproj/argo-workflows/v3/pkg/apiclient/cronworkflow"
+	"github.com/argoproj/argo-workflows/v3/pkg/apiclient/info"
+	"github.com/argoproj/argo-workflows/v3/pkg/apiclient/workflow"
+	"github.com/argoproj/argo-workflows/v3/pkg/apiclient/workflowarchive"
+	"github.com/argoproj/argo-workflows/v3/pkg/apiclient/workflowtemplate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// grpcClient is apiclient.Client using gRPC connection to Argo server.
+// It is used instead of the one from apiclient to support custom CA bundles.
+type grpcClient struct {
+	conn *grpc.ClientConn
+}
+
+var _ apiclient.Client = grpcClient{}
+
+// dialGRPC opens gRPC connection to Argo server.
+func dialGRPC(opts Options) (grpcClient, error) {
+	creds := grpc.WithInsecure()
+	if opts.Secure {
+		tlsConfig := &tls.Config{InsecureSkipVerify: opts.InsecureSkipVerify}
+		if opts.CAFile != "" {
+			pool, err := loadCertPool(opts.CAFile)
+			if err != nil {
+				return grpcClient{}, err
+			}
+
+			tlsConfig.RootCAs = pool
+		}
+
+		creds = grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))
+	}
+
+	conn, err := grpc.Dial(opts.URL,
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(apiclient.MaxClientGRPCMessageSize)),
+		// Trace context is sent to Argo server in W3C traceparent metadata.
+		grpc.WithUnaryInterceptor(propagateUnary),
+		grpc.WithStreamInterceptor(propagateStream),
+		creds)
+	if err != nil {
+		return grpcClient{}, err
+	}
+
+	return grpcClient{conn: conn}, nil
+}
+
+// loadCertPool returns cert pool containing certificates from PEM file.
+func loadCertPool(path string) (*x509.CertPool, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read CA bundle %s: %w", path, err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("no certificates found in CA bundle %s", path)
+	}
+
+	return pool, nil
+}
+
+func (g grpcClient) NewArchivedWorkflowServiceClient() (workflowarchive.ArchivedWorkflowServiceClient, error) {
+	return workflowarchive.NewArchivedWorkflowServiceClient(g.conn), nil
+}
+
+func (g grpcClient) NewWorkflowServiceClient() workflow.WorkflowServiceClient {
+	return workflow.NewWorkflowServiceClient(g.conn)
+}
+
+func (g grpcClient) NewCronWorkflowServiceClient() (cronworkflow.CronWorkflowServiceClient, error) {
+	return cronworkflow.NewCronWorkflowServiceClient(g.conn), nil
+}
+
+func (g grpcClient) NewWorkflowTemplateServiceClient() (workflowtemplate.WorkflowTemplateServiceClient, error) {
+	return workflowtemplate.NewWorkflowTemplateServiceClient(g.conn), nil
+}
+
+func (g grpcClient) NewClusterWorkflowTemplateServiceClient() (clusterworkflowtemplate.ClusterWorkflowTemplateServiceClient, error) {
+	return clusterworkflowtemplate.NewClusterWorkflowTemplateServiceClient(g.conn), nil
+}
+
+func (g grpcClient) NewInfoServiceClient() (info.InfoServiceClient, error) {
+	return info.NewInfoServiceClient(g.conn), nil
+}
@@ -0,0 +1,490 @@
+package argo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apiclient"
+	"github.com/argoproj/argo-workflows/v3/pkg/apiclient/clusterworkflowtemplate"
+	"github.com/argoproj/argo-workflows/v3/pkg/apiclient/cronworkflow"
+	"github.com/argoproj/argo-workflows/v3/pkg/apiclient/info"
+	"github.com/argoproj/argo-workflows/v3/pkg/apiclient/workflow"
+	"github.com/argoproj/argo-workflows/v3/pkg/apiclient/workflowarchive"
+	"github.com/argoproj/argo-workflows/v3/pkg/apiclient/workflowtemplate"
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	"github.com/argoproj/argo-workflows/v3/util/flatten"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// http1Client is apiclient.Client sending requests to Argo server over HTTP1 with a single shared HTTP client.
+// It is used instead of the one from apiclient, which creates HTTP client for every request, ignores request context
+// and supports neither custom CA bundles nor changing tokens.
+// Only calls made by Client are implemented; other methods of service clients return Unimplemented error.
+type http1Client struct {
+	baseURL string
+	client  *http.Client
+}
+
+var _ apiclient.Client = http1Client{}
+
+// newHTTP1Client returns HTTP1 client sending token returned by authorization with every request.
+func newHTTP1Client(opts Options, authorization func(ctx context.Context) (string, error)) (http1Client, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: opts.InsecureSkipVerify}
+	if opts.CAFile != "" {
+		pool, err := loadCertPool(opts.CAFile)
+		if err != nil {
+			return http1Client{}, err
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	scheme := "http"
+	if opts.Secure {
+		scheme = "https"
+	}
+
+	return http1Client{
+		baseURL: fmt.Sprintf("%s://%s", scheme, opts.URL),
+		client:  &http.Client{Transport: authTransport{base: transport, authorization: authorization}},
+	}, nil
+}
+
+// authTransport sets Authorization header of each request to the token returned for request context and sends trace
+// context in W3C traceparent header. Tokens are requested for every request, so rotated token files and forwarded tokens are used.
+type authTransport struct {
+	base          http.RoundTripper
+	authorization func(ctx context.Context) (string, error)
+}
+
+func (t authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.authorization(req.Context())
+	if err != nil {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+
+		return nil, err
+	}
+
+	// Round trippers must not modify requests.
+	req = req.Clone(req.Context())
+	otel.GetTextMapPropagator().Inject(req.Context(), propagation.HeaderCarrier(req.Header))
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+
+	return t.base.RoundTrip(req)
+}
+
+// pathParam matches parameters in URL path templates, e.g. "{namespace}".
+var pathParam = regexp.MustCompile("{[^}]*}")
+
+// url returns URL of a call. Fields of in replace matching parameters in path template, and other fields of GET requests
+// are passed in query, as grpc-gateway of Argo server expects.
+func (h http1Client) url(method, path string, in interface{}) string {
+	query := url.Values{}
+	for key, value := range flatten.Flatten(in) {
+		if param := "{" + key + "}"; strings.Contains(path, param) {
+			path = strings.Replace(path, param, url.PathEscape(value), 1)
+		} else if method == http.MethodGet {
+			query.Set(key, value)
+		}
+	}
+
+	// Parameters which weren't set are removed.
+	path = pathParam.ReplaceAllString(path, "")
+	return fmt.Sprintf("%s%s?%s", h.baseURL, path, query.Encode())
+}
+
+// send sends request to Argo server and returns response if it succeeded.
+func (h http1Client) send(ctx context.Context, method, path string, in interface{}, accept string) (*http.Response, error) {
+	var body io.Reader
+	if method != http.MethodGet {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return nil, err
+		}
+
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, h.url(method, path, in), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, errorFromResponse(resp)
+	}
+
+	return resp, nil
+}
+
+// do sends request to Argo server and decodes JSON response into out.
+func (h http1Client) do(ctx context.Context, method, path string, in, out interface{}) error {
+	resp, err := h.send(ctx, method, path, in, "application/json")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// stream opens a stream of server-sent events.
+func (h http1Client) stream(ctx context.Context, path string, in interface{}) (*sseStream, error) {
+	resp, err := h.send(ctx, http.MethodGet, path, in, "text/event-stream")
+	if err != nil {
+		return nil, err
+	}
+
+	return &sseStream{ctx: ctx, body: resp.Body, reader: bufio.NewReader(resp.Body)}, nil
+}
+
+// errorFromResponse returns gRPC status error described in response body, as Argo server returns it.
+func errorFromResponse(resp *http.Response) error {
+	var body struct {
+		Code    codes.Code `json:"code"`
+		Message string     `json:"message"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Code == codes.OK {
+		return status.Errorf(codes.Unknown, "argo server returned %s", resp.Status)
+	}
+
+	return status.Error(body.Code, body.Message)
+}
+
+// sseStream reads server-sent events sent by Argo server. It implements grpc.ClientStream, so it can be used as a stream
+// of any gRPC streaming call.
+type sseStream struct {
+	ctx    context.Context
+	body   io.Closer
+	reader *bufio.Reader
+}
+
+var _ grpc.ClientStream = &sseStream{}
+
+// recv decodes data of the next event into v.
+func (s *sseStream) recv(v interface{}) error {
+	for {
+		line, err := s.reader.ReadBytes('\n')
+		if err != nil {
+			return err
+		}
+
+		// Lines other than data, e.g. empty lines separating events, are skipped.
+		line = bytes.TrimSpace(line)
+		if !bytes.HasPrefix(line, []byte("data:")) {
+			continue
+		}
+
+		data := bytes.TrimSpace(bytes.TrimPrefix(line, []byte("data:")))
+		if len(data) == 0 {
+			continue
+		}
+
+		// Messages of streaming calls are wrapped into result field.
+		result := struct {
+			Result interface{} `json:"result"`
+		}{Result: v}
+		return json.Unmarshal(data, &result)
+	}
+}
+
+func (s *sseStream) Header() (metadata.MD, error) {
+	return metadata.MD{}, nil
+}
+
+func (s *sseStream) Trailer() metadata.MD {
+	return metadata.MD{}
+}
+
+func (s *sseStream) CloseSend() error {
+	return s.body.Close()
+}
+
+func (s *sseStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *sseStream) SendMsg(interface{}) error {
+	return status.Error(codes.Unimplemented, "sending messages isn't supported over HTTP1")
+}
+
+func (s *sseStream) RecvMsg(m interface{}) error {
+	return s.recv(m)
+}
+
+// http1WatchStream is a stream of workflow events.
+type http1WatchStream struct {
+	*sseStream
+}
+
+func (s http1WatchStream) Recv() (*workflow.WorkflowWatchEvent, error) {
+	ev := &workflow.WorkflowWatchEvent{}
+	return ev, s.recv(ev)
+}
+
+// http1LogStream is a stream of log entries.
+type http1LogStream struct {
+	*sseStream
+}
+
+func (s http1LogStream) Recv() (*workflow.LogEntry, error) {
+	entry := &workflow.LogEntry{}
+	return entry, s.recv(entry)
+}
+
+func (h http1Client) NewWorkflowServiceClient() workflow.WorkflowServiceClient {
+	return http1WorkflowService{client: h}
+}
+
+func (h http1Client) NewArchivedWorkflowServiceClient() (workflowarchive.ArchivedWorkflowServiceClient, error) {
+	return http1ArchiveService{client: h}, nil
+}
+
+func (h http1Client) NewCronWorkflowServiceClient() (cronworkflow.CronWorkflowServiceClient, error) {
+	return nil, fmt.Errorf("cron workflows aren't supported over HTTP1")
+}
+
+func (h http1Client) NewWorkflowTemplateServiceClient() (workflowtemplate.WorkflowTemplateServiceClient, error) {
+	return http1TemplateService{client: h}, nil
+}
+
+func (h http1Client) NewClusterWorkflowTemplateServiceClient() (clusterworkflowtemplate.ClusterWorkflowTemplateServiceClient, error) {
+	return http1ClusterTemplateService{client: h}, nil
+}
+
+func (h http1Client) NewInfoServiceClient() (info.InfoServiceClient, error) {
+	return http1InfoService{client: h}, nil
+}
+
+// unimplemented returns error of a call not supported over HTTP1.
+func unimplemented(method string) error {
+	return status.Errorf(codes.Unimplemented, "%s isn't supported over HTTP1", method)
+}
+
+// http1WorkflowService is a workflow service client.
+type http1WorkflowService struct {
+	client http1Client
+}
+
+var _ workflow.WorkflowServiceClient = http1WorkflowService{}
+
+func (s http1WorkflowService) ListWorkflows(ctx context.Context, in *workflow.WorkflowListRequest, _ ...grpc.CallOption) (*v1alpha1.WorkflowList, error) {
+	out := &v1alpha1.WorkflowList{}
+	return out, s.client.do(ctx, http.MethodGet, "/api/v1/workflows/{namespace}", in, out)
+}
+
+func (s http1WorkflowService) WatchWorkflows(ctx context.Context, in *workflow.WatchWorkflowsRequest, _ ...grpc.CallOption) (workflow.WorkflowService_WatchWorkflowsClient, error) {
+	stream, err := s.client.stream(ctx, "/api/v1/workflow-events/{namespace}", in)
+	if err != nil {
+		return nil, err
+	}
+
+	return http1WatchStream{stream}, nil
+}
+
+func (s http1WorkflowService) WorkflowLogs(ctx context.Context, in *workflow.WorkflowLogRequest, _ ...grpc.CallOption) (workflow.WorkflowService_WorkflowLogsClient, error) {
+	stream, err := s.client.stream(ctx, "/api/v1/workflows/{namespace}/{name}/log", in)
+	if err != nil {
+		return nil, err
+	}
+
+	return http1LogStream{stream}, nil
+}
+
+func (s http1WorkflowService) SubmitWorkflow(ctx context.Context, in *workflow.WorkflowSubmitRequest, _ ...grpc.CallOption) (*v1alpha1.Workflow, error) {
+	out := &v1alpha1.Workflow{}
+	return out, s.client.do(ctx, http.MethodPost, "/api/v1/workflows/{namespace}/submit", in, out)
+}
+
+func (s http1WorkflowService) DeleteWorkflow(ctx context.Context, in *workflow.WorkflowDeleteRequest, _ ...grpc.CallOption) (*workflow.WorkflowDeleteResponse, error) {
+	out := &workflow.WorkflowDeleteResponse{}
+	return out, s.client.do(ctx, http.MethodDelete, "/api/v1/workflows/{namespace}/{name}", in, out)
+}
+
+func (s http1WorkflowService) StopWorkflow(ctx context.Context, in *workflow.WorkflowStopRequest, _ ...grpc.CallOption) (*v1alpha1.Workflow, error) {
+	out := &v1alpha1.Workflow{}
+	return out, s.client.do(ctx, http.MethodPut, "/api/v1/workflows/{namespace}/{name}/stop", in, out)
+}
+
+func (s http1WorkflowService) TerminateWorkflow(ctx context.Context, in *workflow.WorkflowTerminateRequest, _ ...grpc.CallOption) (*v1alpha1.Workflow, error) {
+	out := &v1alpha1.Workflow{}
+	return out, s.client.do(ctx, http.MethodPut, "/api/v1/workflows/{namespace}/{name}/terminate", in, out)
+}
+
+func (s http1WorkflowService) SuspendWorkflow(ctx context.Context, in *workflow.WorkflowSuspendRequest, _ ...grpc.CallOption) (*v1alpha1.Workflow, error) {
+	out := &v1alpha1.Workflow{}
+	return out, s.client.do(ctx, http.MethodPut, "/api/v1/workflows/{namespace}/{name}/suspend", in, out)
+}
+
+func (s http1WorkflowService) ResumeWorkflow(ctx context.Context, in *workflow.WorkflowResumeRequest, _ ...grpc.CallOption) (*v1alpha1.Workflow, error) {
+	out := &v1alpha1.Workflow{}
+	return out, s.client.do(ctx, http.MethodPut, "/api/v1/workflows/{namespace}/{name}/resume", in, out)
+}
+
+func (s http1WorkflowService) RetryWorkflow(ctx context.Context, in *workflow.WorkflowRetryRequest, _ ...grpc.CallOption) (*v1alpha1.Workflow, error) {
+	out := &v1alpha1.Workflow{}
+	return out, s.client.do(ctx, http.MethodPut, "/api/v1/workflows/{namespace}/{name}/retry", in, out)
+}
+
+func (s http1WorkflowService) ResubmitWorkflow(ctx context.Context, in *workflow.WorkflowResubmitRequest, _ ...grpc.CallOption) (*v1alpha1.Workflow, error) {
+	out := &v1alpha1.Workflow{}
+	return out, s.client.do(ctx, http.MethodPut, "/api/v1/workflows/{namespace}/{name}/resubmit", in, out)
+}
+
+func (s http1WorkflowService) CreateWorkflow(context.Context, *workflow.WorkflowCreateRequest, ...grpc.CallOption) (*v1alpha1.Workflow, error) {
+	return nil, unimplemented("CreateWorkflow")
+}
+
+func (s http1WorkflowService) GetWorkflow(context.Context, *workflow.WorkflowGetRequest, ...grpc.CallOption) (*v1alpha1.Workflow, error) {
+	return nil, unimplemented("GetWorkflow")
+}
+
+func (s http1WorkflowService) WatchEvents(context.Context, *workflow.WatchEventsRequest, ...grpc.CallOption) (workflow.WorkflowService_WatchEventsClient, error) {
+	return nil, unimplemented("WatchEvents")
+}
+
+func (s http1WorkflowService) SetWorkflow(context.Context, *workflow.WorkflowSetRequest, ...grpc.CallOption) (*v1alpha1.Workflow, error) {
+	return nil, unimplemented("SetWorkflow")
+}
+
+func (s http1WorkflowService) LintWorkflow(context.Context, *workflow.WorkflowLintRequest, ...grpc.CallOption) (*v1alpha1.Workflow, error) {
+	return nil, unimplemented("LintWorkflow")
+}
+
+func (s http1WorkflowService) PodLogs(context.Context, *workflow.WorkflowLogRequest, ...grpc.CallOption) (workflow.WorkflowService_PodLogsClient, error) {
+	return nil, unimplemented("PodLogs")
+}
+
+// http1ArchiveService is an archived workflow service client.
+type http1ArchiveService struct {
+	client http1Client
+}
+
+var _ workflowarchive.ArchivedWorkflowServiceClient = http1ArchiveService{}
+
+func (s http1ArchiveService) ListArchivedWorkflows(ctx context.Context, in *workflowarchive.ListArchivedWorkflowsRequest, _ ...grpc.CallOption) (*v1alpha1.WorkflowList, error) {
+	out := &v1alpha1.WorkflowList{}
+	return out, s.client.do(ctx, http.MethodGet, "/api/v1/archived-workflows", in, out)
+}
+
+func (s http1ArchiveService) GetArchivedWorkflow(ctx context.Context, in *workflowarchive.GetArchivedWorkflowRequest, _ ...grpc.CallOption) (*v1alpha1.Workflow, error) {
+	out := &v1alpha1.Workflow{}
+	return out, s.client.do(ctx, http.MethodGet, "/api/v1/archived-workflows/{uid}", in, out)
+}
+
+func (s http1ArchiveService) DeleteArchivedWorkflow(ctx context.Context, in *workflowarchive.DeleteArchivedWorkflowRequest, _ ...grpc.CallOption) (*workflowarchive.ArchivedWorkflowDeletedResponse, error) {
+	out := &workflowarchive.ArchivedWorkflowDeletedResponse{}
+	return out, s.client.do(ctx, http.MethodDelete, "/api/v1/archived-workflows/{uid}", in, out)
+}
+
+// http1TemplateService is a workflow template service client.
+type http1TemplateService struct {
+	client http1Client
+}
+
+var _ workflowtemplate.WorkflowTemplateServiceClient = http1TemplateService{}
+
+func (s http1TemplateService) ListWorkflowTemplates(ctx context.Context, in *workflowtemplate.WorkflowTemplateListRequest, _ ...grpc.CallOption) (*v1alpha1.WorkflowTemplateList, error) {
+	out := &v1alpha1.WorkflowTemplateList{}
+	return out, s.client.do(ctx, http.MethodGet, "/api/v1/workflow-templates/{namespace}", in, out)
+}
+
+func (s http1TemplateService) CreateWorkflowTemplate(context.Context, *workflowtemplate.WorkflowTemplateCreateRequest, ...grpc.CallOption) (*v1alpha1.WorkflowTemplate, error) {
+	return nil, unimplemented("CreateWorkflowTemplate")
+}
+
+func (s http1TemplateService) GetWorkflowTemplate(context.Context, *workflowtemplate.WorkflowTemplateGetRequest, ...grpc.CallOption) (*v1alpha1.WorkflowTemplate, error) {
+	return nil, unimplemented("GetWorkflowTemplate")
+}
+
+func (s http1TemplateService) UpdateWorkflowTemplate(context.Context, *workflowtemplate.WorkflowTemplateUpdateRequest, ...grpc.CallOption) (*v1alpha1.WorkflowTemplate, error) {
+	return nil, unimplemented("UpdateWorkflowTemplate")
+}
+
+func (s http1TemplateService) DeleteWorkflowTemplate(context.Context, *workflowtemplate.WorkflowTemplateDeleteRequest, ...grpc.CallOption) (*workflowtemplate.WorkflowTemplateDeleteResponse, error) {
+	return nil, unimplemented("DeleteWorkflowTemplate")
+}
+
+func (s http1TemplateService) LintWorkflowTemplate(context.Context, *workflowtemplate.WorkflowTemplateLintRequest, ...grpc.CallOption) (*v1alpha1.WorkflowTemplate, error) {
+	return nil, unimplemented("LintWorkflowTemplate")
+}
+
+// http1ClusterTemplateService is a cluster workflow template service client.
+type http1ClusterTemplateService struct {
+	client http1Client
+}
+
+var _ clusterworkflowtemplate.ClusterWorkflowTemplateServiceClient = http1ClusterTemplateService{}
+
+func (s http1ClusterTemplateService) ListClusterWorkflowTemplates(ctx context.Context, in *clusterworkflowtemplate.ClusterWorkflowTemplateListRequest, _ ...grpc.CallOption) (*v1alpha1.ClusterWorkflowTemplateList, error) {
+	out := &v1alpha1.ClusterWorkflowTemplateList{}
+	return out, s.client.do(ctx, http.MethodGet, "/api/v1/cluster-workflow-templates", in, out)
+}
+
+func (s http1ClusterTemplateService) CreateClusterWorkflowTemplate(context.Context, *clusterworkflowtemplate.ClusterWorkflowTemplateCreateRequest, ...grpc.CallOption) (*v1alpha1.ClusterWorkflowTemplate, error) {
+	return nil, unimplemented("CreateClusterWorkflowTemplate")
+}
+
+func (s http1ClusterTemplateService) GetClusterWorkflowTemplate(context.Context, *clusterworkflowtemplate.ClusterWorkflowTemplateGetRequest, ...grpc.CallOption) (*v1alpha1.ClusterWorkflowTemplate, error) {
+	return nil, unimplemented("GetClusterWorkflowTemplate")
+}
+
+func (s http1ClusterTemplateService) UpdateClusterWorkflowTemplate(context.Context, *clusterworkflowtemplate.ClusterWorkflowTemplateUpdateRequest, ...grpc.CallOption) (*v1alpha1.ClusterWorkflowTemplate, error) {
+	return nil, unimplemented("UpdateClusterWorkflowTemplate")
+}
+
+func (s http1ClusterTemplateService) DeleteClusterWorkflowTemplate(context.Context, *clusterworkflowtemplate.ClusterWorkflowTemplateDeleteRequest, ...grpc.CallOption) (*clusterworkflowtemplate.ClusterWorkflowTemplateDeleteResponse, error) {
+	return nil, unimplemented("DeleteClusterWorkflowTemplate")
+}
+
+func (s http1ClusterTemplateService) LintClusterWorkflowTemplate(context.Context, *clusterworkflowtemplate.ClusterWorkflowTemplateLintRequest, ...grpc.CallOption) (*v1alpha1.ClusterWorkflowTemplate, error) {
+	return nil, unimplemented("LintClusterWorkflowTemplate")
+}
+
+// http1InfoService is an info service client.
+type http1InfoService struct {
+	client http1Client
+}
+
+var _ info.InfoServiceClient = http1InfoService{}
+
+func (s http1InfoService) GetVersion(ctx context.Context, in *info.GetVersionRequest, _ ...grpc.CallOption) (*v1alpha1.Version, error) {
+	out := &v1alpha1.Version{}
+	return out, s.client.do(ctx, http.MethodGet, "/api/v1/version", in, out)
+}
+
+func (s http1InfoService) GetInfo(context.Context, *info.GetInfoRequest, ...grpc.CallOption) (*info.InfoResponse, error) {
+	return nil, unimplemented("GetInfo")
+}
+
+func (s http1InfoService) GetUserInfo(context.Context, *info.GetUserInfoRequest, ...grpc.CallOption) (*info.GetUserInfoResponse, error) {
+	return nil, unimplemented("GetUserInfo")
+}
@@ -0,0 +1,167 @@
+package argo
+
+import (
+	"context"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apiclient/clusterworkflowtemplate"
+	"github.com/argoproj/argo-workflows/v3/pkg/apiclient/info"
+	"github.com/argoproj/argo-workflows/v3/pkg/apiclient/workflow"
+	"github.com/argoproj/argo-workflows/v3/pkg/apiclient/workflowtemplate"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestClient_http1 tests that HTTP1 client verifies Argo server with CA bundle, sends the current token of token file
+// and records call metrics.
+func TestClient_http1(t *testing.T) {
+	t.Parallel()
+
+	var (
+		m      sync.Mutex
+		tokens []string
+	)
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.Lock()
+		tokens = append(tokens, r.Header.Get("Authorization"))
+		m.Unlock()
+
+		switch r.URL.Path {
+		case "/api/v1/version":
+			_, _ = fmt.Fprint(w, `{"version": "v3.2.4"}`)
+		case "/api/v1/workflows/chaos":
+			if r.URL.Query().Get("listOptions.labelSelector") != "team=payments" {
+				t.Errorf("unexpected query %s", r.URL.RawQuery)
+			}
+
+			_, _ = fmt.Fprint(w, `{"items": [{"metadata": {"name": "a", "namespace": "chaos"}}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = fmt.Fprint(w, `{"code": 5, "message": "not found"}`)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.crt")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, ca, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tokenFile := filepath.Join(dir, "token")
+	if err := os.WriteFile(tokenFile, []byte("first"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := NewClient(Options{
+		URL:       strings.TrimPrefix(server.URL, "https://"),
+		HTTP1:     true,
+		Secure:    true,
+		CAFile:    caFile,
+		TokenFile: tokenFile,
+	}, zap.NewNop().Sugar())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	if version, err := client.Version(context.Background()); err != nil || version != "v3.2.4" {
+		t.Fatalf("Version() = %q, %v", version, err)
+	}
+
+	if err := os.WriteFile(tokenFile, []byte("second"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Token is re-read when modification time of the file changes.
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(tokenFile, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	workflows, _, err := client.List(context.Background(), ListOptions{Namespace: "chaos", LabelSelector: "team=payments"})
+	if err != nil || len(workflows) != 1 || workflows[0].Name != "a" {
+		t.Fatalf("List() = %+v, %v", workflows, err)
+	}
+
+	// Calls made over HTTP1 are measured like gRPC ones.
+	notFound := callErrors.WithLabelValues(archiveServiceName+"ListArchivedWorkflows", "NotFound")
+	before := testutil.ToFloat64(notFound)
+
+	if _, err := client.GetArchived(context.Background(), "chaos", "a"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetArchived() error = %v, want %v", err, ErrNotFound)
+	}
+
+	if after := testutil.ToFloat64(notFound); after != before+1 {
+		t.Errorf("call errors = %v, want %v", after, before+1)
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	if len(tokens) < 2 || tokens[0] != "Bearer first" || tokens[1] != "Bearer second" {
+		t.Errorf("sent tokens %q, want Bearer first and then Bearer second", tokens)
+	}
+}
+
+// Test_http1Client_unimplemented tests that calls not used by Client return Unimplemented error instead of panicking.
+func Test_http1Client_unimplemented(t *testing.T) {
+	t.Parallel()
+
+	client, err := newHTTP1Client(Options{URL: "localhost"}, func(context.Context) (string, error) {
+		return "", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	templates, _ := client.NewWorkflowTemplateServiceClient()
+	clusterTemplates, _ := client.NewClusterWorkflowTemplateServiceClient()
+	infoClient, _ := client.NewInfoServiceClient()
+
+	ctx := context.Background()
+	calls := map[string]func() error{
+		"CreateWorkflow": func() error {
+			_, err := client.NewWorkflowServiceClient().CreateWorkflow(ctx, &workflow.WorkflowCreateRequest{})
+			return err
+		},
+		"PodLogs": func() error {
+			_, err := client.NewWorkflowServiceClient().PodLogs(ctx, &workflow.WorkflowLogRequest{})
+			return err
+		},
+		"GetWorkflowTemplate": func() error {
+			_, err := templates.GetWorkflowTemplate(ctx, &workflowtemplate.WorkflowTemplateGetRequest{})
+			return err
+		},
+		"DeleteClusterWorkflowTemplate": func() error {
+			_, err := clusterTemplates.DeleteClusterWorkflowTemplate(ctx, &clusterworkflowtemplate.ClusterWorkflowTemplateDeleteRequest{})
+			return err
+		},
+		"GetUserInfo": func() error {
+			_, err := infoClient.GetUserInfo(ctx, &info.GetUserInfoRequest{})
+			return err
+		},
+	}
+
+	for method, call := range calls {
+		if err := call(); status.Code(err) != codes.Unimplemented {
+			t.Errorf("%s() error = %v, want Unimplemented", method, err)
+		}
+	}
+}
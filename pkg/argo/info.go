@@ -0,0 +1,41 @@
+package argo
+
+import (
+	"context"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apiclient/info"
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+)
+
+// Version returns version of Argo server. Kubernetes API doesn't report Argo version, so when URL isn't set, a single
+// workflow is listed to check the connection and an empty version is returned.
+func (w Client) Version(ctx context.Context) (string, error) {
+	if w.opts.URL == "" {
+		if _, _, err := w.listLive(ctx, ListOptions{Limit: 1}); err != nil {
+			return "", err
+		}
+
+		return "", nil
+	}
+
+	ctx, client, err := w.connect(ctx)
+	if err != nil {
+		return "", w.apiError(err)
+	}
+
+	service, err := client.NewInfoServiceClient()
+	if err != nil {
+		return "", w.apiError(err)
+	}
+
+	var version *v1alpha1.Version
+	err = call(ctx, infoServiceName+"GetVersion", func(ctx context.Context) (err error) {
+		version, err = service.GetVersion(ctx, &info.GetVersionRequest{})
+		return err
+	})
+	if err != nil {
+		return "", w.apiError(err)
+	}
+
+	return version.Version, nil
+}
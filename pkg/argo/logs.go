@@ -0,0 +1,98 @@
+package argo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apiclient/workflow"
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// LogOptions configures logs returned by Logs.
+type LogOptions struct {
+	// Container is a name of pod container. Argo uses "main" container if empty.
+	Container string
+	// Follow enables streaming of new log lines until the container stops.
+	Follow bool
+	// TailLines is a number of last lines to return. All lines are returned if nil.
+	TailLines *int64
+	// SinceTime limits logs to ones written after the time. All lines are returned if nil.
+	SinceTime *time.Time
+}
+
+// Logs returns reader of logs of a workflow pod.
+func (w Client) Logs(ctx context.Context, namespace, name, podName string, opts LogOptions) (event.LogReader, error) {
+	ctx, client, err := w.workflowService(ctx)
+	if err != nil {
+		return nil, w.apiError(err)
+	}
+
+	logOptions := &corev1.PodLogOptions{
+		Container: opts.Container,
+		Follow:    opts.Follow,
+		TailLines: opts.TailLines,
+	}
+	if opts.SinceTime != nil {
+		logOptions.SinceTime = &v1.Time{Time: *opts.SinceTime}
+	}
+
+	var service workflow.WorkflowService_WorkflowLogsClient
+	err = call(ctx, workflowServiceName+"WorkflowLogs", func(ctx context.Context) (err error) {
+		service, err = client.WorkflowLogs(ctx, &workflow.WorkflowLogRequest{
+			Namespace:  namespace,
+			Name:       name,
+			PodName:    podName,
+			LogOptions: logOptions,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, w.apiError(err, "namespace", namespace, "name", name, "pod", podName)
+	}
+
+	return &logStream{
+		ctx:     ctx,
+		service: service,
+		logger:  w.logger.Named(fmt.Sprintf("%s-%s-logs", namespace, podName)),
+	}, nil
+}
+
+// logStream reads stream of log entries from Argo server.
+type logStream struct {
+	ctx     context.Context
+	service workflow.WorkflowService_WorkflowLogsClient
+	logger  *zap.SugaredLogger
+}
+
+func (l *logStream) Read() (event.LogEntry, error) {
+	msg, err := l.service.Recv()
+	if errors.Is(err, io.EOF) {
+		return event.LogEntry{}, event.ErrAllRead
+	} else if l.ctx.Err() != nil {
+		return event.LogEntry{}, event.ErrDeadlineExceeded
+	} else if err != nil {
+		// Errors of log requests, e.g. invalid container, are returned on the first read.
+		l.logger.Error(err)
+		return event.LogEntry{}, errorKind(err)
+	}
+
+	return event.LogEntry{
+		PodName: msg.PodName,
+		Content: msg.Content,
+	}, nil
+}
+
+func (l *logStream) Close() error {
+	if err := l.service.CloseSend(); err != nil {
+		l.logger.Error(err)
+		return nil
+	}
+
+	return nil
+}
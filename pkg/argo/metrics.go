@@ -0,0 +1,128 @@
+package argo
+
+import (
+	"context"
+	"time"
+
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	callDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "chaos_workflows",
+		Subsystem: "argo",
+		Name:      "call_duration_seconds",
+		Help:      "Duration of Argo API calls. Only opening is measured for streaming calls.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+	callErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "chaos_workflows",
+		Subsystem: "argo",
+		Name:      "call_errors_total",
+		Help:      "Number of failed Argo API calls by gRPC status code.",
+	}, []string{"method", "code"})
+	streamReconnects = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "chaos_workflows",
+		Subsystem: "argo",
+		Name:      "stream_reconnects_total",
+		Help:      "Number of attempts to reopen broken watch streams.",
+	})
+)
+
+// Argo services used in names of called methods.
+const (
+	workflowServiceName        = "workflow.WorkflowService/"
+	archiveServiceName         = "workflowarchive.ArchivedWorkflowService/"
+	templateServiceName        = "workflowtemplate.WorkflowTemplateService/"
+	clusterTemplateServiceName = "clusterworkflowtemplate.ClusterWorkflowTemplateService/"
+	infoServiceName            = "info.InfoService/"
+)
+
+// call makes Argo call in a client span and records its duration and error. Method is a gRPC method name,
+// e.g. "workflow.WorkflowService/ListWorkflows". Calls are recorded for gRPC, HTTP1 and Kubernetes connections alike,
+// as all of them return gRPC status errors.
+func call(ctx context.Context, method string, f func(ctx context.Context) error) error {
+	ctx, span := startSpan(ctx, method)
+	start := time.Now()
+	err := f(ctx)
+	endSpan(span, err)
+
+	callDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		callErrors.WithLabelValues(method, status.Code(err).String()).Inc()
+	}
+
+	return err
+}
+
+// workflowsCollector exports number of running workflows by chaos type and severity of their steps.
+type workflowsCollector struct {
+	client  Client
+	timeout time.Duration
+	running *prometheus.Desc
+	logger  *zap.SugaredLogger
+}
+
+// NewWorkflowsCollector returns collector listing running workflows on every scrape. Workflow having steps with different
+// chaos types or severities is counted once for every distinct pair. Listing is cancelled after timeout.
+// Client must be authorized to list workflows with the service token, see Client.ServiceAuthorized.
+func NewWorkflowsCollector(client Client, timeout time.Duration, logger *zap.SugaredLogger) prometheus.Collector {
+	return workflowsCollector{
+		client:  client,
+		timeout: timeout,
+		running: prometheus.NewDesc("chaos_workflows_running",
+			"Number of running workflows with chaos steps of type and severity.",
+			[]string{"type", "severity"}, nil),
+		logger: logger,
+	}
+}
+
+func (c workflowsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.running
+}
+
+func (c workflowsCollector) Collect(ch chan<- prometheus.Metric) {
+	// Scrapes aren't made on behalf of users, so the service token is used.
+	ctx, cancel := context.WithTimeout(WithServiceToken(context.Background()), c.timeout)
+	defer cancel()
+
+	type kind struct {
+		chaosType, severity string
+	}
+
+	counts := make(map[kind]int)
+	opts := ListOptions{Phases: []string{"running"}}
+	for {
+		workflows, next, err := c.client.listLive(ctx, opts)
+		if err != nil {
+			// Gauge isn't exported instead of failing the whole scrape.
+			c.logger.Warnf("couldn't list running workflows: %v", err)
+			return
+		}
+
+		for _, wf := range workflows {
+			seen := make(map[kind]bool)
+			for _, step := range event.ChaosSteps(wf) {
+				k := kind{chaosType: step.Type, severity: step.Severity}
+				if !seen[k] {
+					seen[k] = true
+					counts[k]++
+				}
+			}
+		}
+
+		if next == "" {
+			break
+		}
+
+		opts.Continue = next
+	}
+
+	for k, count := range counts {
+		ch <- prometheus.MustNewConstMetric(c.running, prometheus.GaugeValue, float64(count), k.chaosType, k.severity)
+	}
+}
@@ -0,0 +1,67 @@
+package argo
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	"github.com/iskorotkov/chaos-workflows/pkg/argo/argotest"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testChaosWorkflow(name string, phase v1alpha1.WorkflowPhase, steps ...[2]string) v1alpha1.Workflow {
+	wf := v1alpha1.Workflow{
+		ObjectMeta: v1.ObjectMeta{Name: name, Namespace: "chaos"},
+		Status:     v1alpha1.WorkflowStatus{Phase: phase},
+	}
+
+	for i, step := range steps {
+		wf.Spec.Templates = append(wf.Spec.Templates, v1alpha1.Template{
+			Name: name + "-" + string(rune('a'+i)),
+			Metadata: v1alpha1.Metadata{Annotations: map[string]string{
+				"chaosframework.com/type":     step[0],
+				"chaosframework.com/severity": step[1],
+			}},
+		})
+	}
+
+	return wf
+}
+
+// Test_workflowsCollector tests that running workflows are counted once per distinct chaos type and severity.
+func Test_workflowsCollector(t *testing.T) {
+	t.Parallel()
+
+	fake, err := argotest.NewServer(
+		testChaosWorkflow("a", v1alpha1.WorkflowRunning, [2]string{"pod-delete", "critical"}, [2]string{"pod-delete", "critical"}),
+		testChaosWorkflow("b", v1alpha1.WorkflowRunning, [2]string{"pod-delete", "critical"}, [2]string{"network-delay", "light"}),
+		testChaosWorkflow("c", v1alpha1.WorkflowSucceeded, [2]string{"pod-delete", "critical"}),
+		testChaosWorkflow("d", v1alpha1.WorkflowRunning))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(fake.Close)
+
+	client, err := NewClient(Options{URL: fake.URL}, zap.NewNop().Sugar())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	collector := NewWorkflowsCollector(client, 10*time.Second, zap.NewNop().Sugar())
+
+	want := `
+# HELP chaos_workflows_running Number of running workflows with chaos steps of type and severity.
+# TYPE chaos_workflows_running gauge
+chaos_workflows_running{severity="critical",type="pod-delete"} 2
+chaos_workflows_running{severity="light",type="network-delay"} 1
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,220 @@
+package argo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apiclient/workflow"
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RetryOptions configures reconnection of broken watch streams.
+type RetryOptions struct {
+	// Attempts is a number of consecutive reconnection attempts before giving up.
+	Attempts int
+	// Backoff is a delay before the first reconnection attempt. It doubles with every failed attempt.
+	Backoff time.Duration
+	// MaxBackoff limits delay between reconnection attempts.
+	MaxBackoff time.Duration
+}
+
+// delay returns backoff before the given attempt, starting from 1.
+func (r RetryOptions) delay(attempt int) time.Duration {
+	d := r.Backoff
+	for i := 1; i < attempt && (r.MaxBackoff == 0 || d < r.MaxBackoff); i++ {
+		d *= 2
+	}
+
+	if r.MaxBackoff != 0 && d > r.MaxBackoff {
+		d = r.MaxBackoff
+	}
+
+	return d
+}
+
+// eventStream reads stream of workflow events from Argo server.
+// Broken streams are reopened from the last seen resource version, and already delivered states are skipped.
+type eventStream struct {
+	// single is set when the stream watches one workflow and ends when it finishes.
+	single  bool
+	ctx     context.Context
+	open    func(resourceVersion string) (workflow.WorkflowService_WatchWorkflowsClient, error)
+	service workflow.WorkflowService_WatchWorkflowsClient
+	retry   RetryOptions
+	logger  *zap.SugaredLogger
+
+	attempts        int
+	received        bool
+	resourceVersion string
+	// last holds delivered states by workflow namespace and name.
+	last map[string]event.Workflow
+}
+
+func (e *eventStream) Read() (event.Workflow, error) {
+	for {
+		msg, err := e.service.Recv()
+		if e.ctx.Err() != nil {
+			return event.Workflow{}, event.ErrDeadlineExceeded
+		} else if err != nil {
+			if !retryable(err) {
+				e.logger.Error(err)
+				return event.Workflow{}, event.ErrConnectionFailed
+			}
+
+			e.logger.Warnw("watch stream was interrupted", "error", err)
+			if err := e.reconnect(); err != nil {
+				return event.Workflow{}, err
+			}
+
+			continue
+		}
+
+		e.attempts, e.received = 0, true
+
+		if msg.Object == nil {
+			continue
+		}
+
+		if rv := msg.Object.ResourceVersion; rv != "" && rv == e.resourceVersion {
+			continue
+		}
+
+		ev, ok := event.FromWorkflowEvent(msg)
+		if !ok && e.single {
+			e.logger.Error("couldn't convert to custom event")
+			return event.Workflow{}, event.ErrInvalidEvent
+		}
+
+		e.resourceVersion = msg.Object.ResourceVersion
+
+		// A single malformed workflow must not end a stream of many workflows.
+		if !ok {
+			e.logger.Errorw("skipping event: couldn't convert to custom event",
+				"namespace", msg.Object.Namespace, "name", msg.Object.Name, "type", msg.Type)
+			if msg.Type == "DELETED" {
+				delete(e.last, workflowKey(event.Workflow{Namespace: msg.Object.Namespace, Name: msg.Object.Name}))
+			}
+
+			continue
+		}
+
+		if e.delivered(ev) {
+			continue
+		}
+
+		e.remember(ev)
+
+		// Just submitted workflows have no phase yet, so they aren't finished.
+		if e.single && (ev.Type == "DELETED" || ev.Finished()) {
+			return ev, event.ErrAllRead
+		}
+
+		return ev, nil
+	}
+}
+
+// delivered returns whether the same workflow state was already returned.
+// Streams reopened without resource version start with the current state which may be already seen.
+func (e *eventStream) delivered(ev event.Workflow) bool {
+	last, ok := e.last[workflowKey(ev)]
+	if !ok || ev.Type == "DELETED" {
+		return false
+	}
+
+	last.Type, ev.Type = "", ""
+	return reflect.DeepEqual(last, ev)
+}
+
+// remember saves delivered workflow state.
+func (e *eventStream) remember(ev event.Workflow) {
+	if e.last == nil {
+		e.last = make(map[string]event.Workflow)
+	}
+
+	if ev.Type == "DELETED" {
+		delete(e.last, workflowKey(ev))
+	} else {
+		e.last[workflowKey(ev)] = ev
+	}
+}
+
+// workflowKey returns unique key of a workflow.
+func workflowKey(ev event.Workflow) string {
+	return fmt.Sprintf("%s/%s", ev.Namespace, ev.Name)
+}
+
+// reconnect reopens the stream with backoff until it succeeds or retry budget is exhausted.
+func (e *eventStream) reconnect() error {
+	if err := e.service.CloseSend(); err != nil {
+		e.logger.Warn(err)
+	}
+
+	// Stream that failed before receiving anything may be resumed from an expired resource version.
+	if !e.received {
+		e.resourceVersion = ""
+	}
+
+	for {
+		if e.attempts >= e.retry.Attempts {
+			e.logger.Errorw("gave up reconnecting to watch stream", "attempts", e.attempts)
+			return event.ErrConnectionFailed
+		}
+
+		e.attempts++
+		streamReconnects.Inc()
+
+		select {
+		case <-e.ctx.Done():
+			return event.ErrDeadlineExceeded
+		case <-time.After(e.retry.delay(e.attempts)):
+		}
+
+		e.logger.Infow("reopening watch stream", "attempt", e.attempts, "resource version", e.resourceVersion)
+
+		service, err := e.open(e.resourceVersion)
+		if err == nil {
+			e.service, e.received = service, false
+			return nil
+		}
+
+		if e.ctx.Err() != nil {
+			return event.ErrDeadlineExceeded
+		} else if !retryable(err) {
+			e.logger.Error(err)
+			return event.ErrConnectionFailed
+		}
+
+		e.logger.Warnw("couldn't reopen watch stream", "error", err)
+	}
+}
+
+func (e *eventStream) Close() error {
+	if err := e.service.CloseSend(); err != nil {
+		e.logger.Error(err)
+		return nil
+	}
+
+	return nil
+}
+
+// retryable returns whether the call may succeed if repeated.
+func retryable(err error) bool {
+	if errors.Is(err, io.EOF) {
+		return true
+	}
+
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.NotFound, codes.AlreadyExists, codes.PermissionDenied,
+		codes.Unauthenticated, codes.FailedPrecondition, codes.Unimplemented, codes.Canceled:
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,281 @@
+package argo
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apiclient/workflow"
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// testWatchClient mocks workflow.WorkflowService_WatchWorkflowsClient.
+type testWatchClient struct {
+	grpc.ClientStream
+
+	Events []*workflow.WorkflowWatchEvent
+	Err    error
+}
+
+func (t *testWatchClient) Recv() (*workflow.WorkflowWatchEvent, error) {
+	if len(t.Events) == 0 {
+		return nil, t.Err
+	}
+
+	ev := t.Events[0]
+	t.Events = t.Events[1:]
+	return ev, nil
+}
+
+func (t *testWatchClient) CloseSend() error {
+	return nil
+}
+
+func testWatchEvent(resourceVersion string, phase v1alpha1.WorkflowPhase) *workflow.WorkflowWatchEvent {
+	return &workflow.WorkflowWatchEvent{
+		Type: "MODIFIED",
+		Object: &v1alpha1.Workflow{
+			ObjectMeta: v1.ObjectMeta{Name: resourceVersion, Namespace: "namespace", ResourceVersion: resourceVersion},
+			Status:     v1alpha1.WorkflowStatus{Phase: phase},
+		},
+	}
+}
+
+// testInvalidWatchEvent returns event of a workflow with cyclic DAG which can't be converted.
+func testInvalidWatchEvent(resourceVersion string) *workflow.WorkflowWatchEvent {
+	ev := testWatchEvent(resourceVersion, v1alpha1.WorkflowRunning)
+	ev.Object.Spec.Templates = []v1alpha1.Template{{
+		Name: "main",
+		DAG: &v1alpha1.DAGTemplate{Tasks: []v1alpha1.DAGTask{
+			{Name: "a", Dependencies: []string{"b"}},
+			{Name: "b", Dependencies: []string{"a"}},
+		}},
+	}}
+	ev.Object.Status.Nodes = v1alpha1.Nodes{
+		resourceVersion: {ID: resourceVersion, Type: v1alpha1.NodeTypeDAG, TemplateName: "main"},
+	}
+
+	return ev
+}
+
+// Test_eventStream_Read tests reconnection of broken watch streams.
+func Test_eventStream_Read(t *testing.T) {
+	t.Parallel()
+
+	unavailable := status.Error(codes.Unavailable, "argo server restarted")
+
+	tests := []struct {
+		name            string
+		continuous      bool
+		first           *testWatchClient
+		reopened        []*testWatchClient
+		errOpen         error
+		attempts        int
+		wantNames       []string
+		wantErr         error
+		wantResumedFrom []string
+	}{
+		{
+			name: "resumes from last resource version and skips duplicates",
+			first: &testWatchClient{
+				Events: []*workflow.WorkflowWatchEvent{
+					testWatchEvent("1", v1alpha1.WorkflowRunning),
+					testWatchEvent("2", v1alpha1.WorkflowRunning),
+				},
+				Err: unavailable,
+			},
+			reopened: []*testWatchClient{{
+				Events: []*workflow.WorkflowWatchEvent{
+					testWatchEvent("2", v1alpha1.WorkflowRunning),
+					testWatchEvent("3", v1alpha1.WorkflowSucceeded),
+				},
+			}},
+			attempts:        3,
+			wantNames:       []string{"1", "2", "3"},
+			wantErr:         event.ErrAllRead,
+			wantResumedFrom: []string{"2"},
+		},
+		{
+			name: "doesn't end single workflow stream before workflow gets a phase",
+			first: &testWatchClient{
+				Events: []*workflow.WorkflowWatchEvent{
+					testWatchEvent("1", ""),
+					testWatchEvent("2", v1alpha1.WorkflowRunning),
+					testWatchEvent("3", v1alpha1.WorkflowSucceeded),
+				},
+			},
+			wantNames: []string{"1", "2", "3"},
+			wantErr:   event.ErrAllRead,
+		},
+		{
+			name: "reconnects after stream is closed by server",
+			first: &testWatchClient{
+				Events: []*workflow.WorkflowWatchEvent{testWatchEvent("1", v1alpha1.WorkflowRunning)},
+				Err:    io.EOF,
+			},
+			reopened: []*testWatchClient{{
+				Events: []*workflow.WorkflowWatchEvent{testWatchEvent("4", v1alpha1.WorkflowFailed)},
+			}},
+			attempts:        1,
+			wantNames:       []string{"1", "4"},
+			wantErr:         event.ErrAllRead,
+			wantResumedFrom: []string{"1"},
+		},
+		{
+			name: "gives up when retry budget is exhausted",
+			first: &testWatchClient{
+				Events: []*workflow.WorkflowWatchEvent{testWatchEvent("1", v1alpha1.WorkflowRunning)},
+				Err:    unavailable,
+			},
+			errOpen:         unavailable,
+			attempts:        3,
+			wantNames:       []string{"1"},
+			wantErr:         event.ErrConnectionFailed,
+			wantResumedFrom: []string{"1", "1", "1"},
+		},
+		{
+			name:       "doesn't stop watching many workflows when one finishes",
+			continuous: true,
+			first: &testWatchClient{
+				Events: []*workflow.WorkflowWatchEvent{
+					testWatchEvent("1", v1alpha1.WorkflowSucceeded),
+					testWatchEvent("2", v1alpha1.WorkflowRunning),
+				},
+				Err: unavailable,
+			},
+			reopened: []*testWatchClient{{
+				Events: []*workflow.WorkflowWatchEvent{testWatchEvent("3", v1alpha1.WorkflowFailed)},
+				Err:    status.Error(codes.Unauthenticated, "token expired"),
+			}},
+			attempts:        1,
+			wantNames:       []string{"1", "2", "3"},
+			wantErr:         event.ErrConnectionFailed,
+			wantResumedFrom: []string{"2"},
+		},
+		{
+			name:       "skips invalid events when watching many workflows",
+			continuous: true,
+			first: &testWatchClient{
+				Events: []*workflow.WorkflowWatchEvent{
+					testWatchEvent("1", v1alpha1.WorkflowRunning),
+					testInvalidWatchEvent("2"),
+					testWatchEvent("3", v1alpha1.WorkflowRunning),
+				},
+				Err: status.Error(codes.PermissionDenied, "forbidden"),
+			},
+			wantNames: []string{"1", "3"},
+			wantErr:   event.ErrConnectionFailed,
+		},
+		{
+			name: "fails on invalid event when watching single workflow",
+			first: &testWatchClient{
+				Events: []*workflow.WorkflowWatchEvent{
+					testWatchEvent("1", v1alpha1.WorkflowRunning),
+					testInvalidWatchEvent("2"),
+				},
+			},
+			wantNames: []string{"1"},
+			wantErr:   event.ErrInvalidEvent,
+		},
+		{
+			name: "doesn't reconnect on permanent errors",
+			first: &testWatchClient{
+				Err: status.Error(codes.PermissionDenied, "forbidden"),
+			},
+			attempts: 3,
+			wantErr:  event.ErrConnectionFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var resumedFrom []string
+			stream := &eventStream{
+				single: !tt.continuous,
+				ctx:    context.Background(),
+				open: func(resourceVersion string) (workflow.WorkflowService_WatchWorkflowsClient, error) {
+					resumedFrom = append(resumedFrom, resourceVersion)
+					if tt.errOpen != nil {
+						return nil, tt.errOpen
+					}
+
+					next := tt.reopened[0]
+					tt.reopened = tt.reopened[1:]
+					return next, nil
+				},
+				service: tt.first,
+				retry:   RetryOptions{Attempts: tt.attempts},
+				logger:  zap.NewNop().Sugar(),
+			}
+
+			var names []string
+			for {
+				ev, err := stream.Read()
+				if err == nil || err == event.ErrAllRead {
+					names = append(names, ev.Name)
+				}
+
+				if err != nil {
+					if err != tt.wantErr {
+						t.Errorf("Read() error = %v, want %v", err, tt.wantErr)
+					}
+
+					break
+				}
+			}
+
+			if !reflect.DeepEqual(names, tt.wantNames) {
+				t.Errorf("Read() names = %v, want %v", names, tt.wantNames)
+			}
+
+			if !reflect.DeepEqual(resumedFrom, tt.wantResumedFrom) {
+				t.Errorf("resumed from = %v, want %v", resumedFrom, tt.wantResumedFrom)
+			}
+		})
+	}
+}
+
+// Test_eventStream_last tests that states of deleted workflows aren't kept, including ones that couldn't be converted.
+func Test_eventStream_last(t *testing.T) {
+	t.Parallel()
+
+	deleted := func(ev *workflow.WorkflowWatchEvent) *workflow.WorkflowWatchEvent {
+		ev.Type = "DELETED"
+		ev.Object.ResourceVersion += "-deleted"
+		return ev
+	}
+
+	stream := &eventStream{
+		ctx: context.Background(),
+		service: &testWatchClient{
+			Events: []*workflow.WorkflowWatchEvent{
+				testWatchEvent("1", v1alpha1.WorkflowRunning),
+				testWatchEvent("2", v1alpha1.WorkflowRunning),
+				deleted(testWatchEvent("1", v1alpha1.WorkflowSucceeded)),
+				deleted(testInvalidWatchEvent("2")),
+			},
+			Err: status.Error(codes.PermissionDenied, "forbidden"),
+		},
+		logger: zap.NewNop().Sugar(),
+	}
+
+	for {
+		if _, err := stream.Read(); err != nil {
+			break
+		}
+	}
+
+	if len(stream.last) != 0 {
+		t.Errorf("states of deleted workflows are kept: %v", stream.last)
+	}
+}
@@ -0,0 +1,124 @@
+package argo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apiclient/clusterworkflowtemplate"
+	"github.com/argoproj/argo-workflows/v3/pkg/apiclient/workflow"
+	"github.com/argoproj/argo-workflows/v3/pkg/apiclient/workflowtemplate"
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TemplateKind is a kind of template to submit workflows from.
+type TemplateKind string
+
+const (
+	WorkflowTemplateKind        TemplateKind = "WorkflowTemplate"
+	ClusterWorkflowTemplateKind TemplateKind = "ClusterWorkflowTemplate"
+)
+
+// SubmitOptions configures workflow submitted from a template.
+type SubmitOptions struct {
+	// Kind is a kind of template. WorkflowTemplateKind is used if empty.
+	Kind     TemplateKind
+	Template string
+	// Parameters override values of template parameters.
+	Parameters map[string]string
+	Labels     map[string]string
+	// GenerateName is a prefix of workflow name. Template name is used if empty.
+	GenerateName string
+}
+
+// Templates returns workflow templates in namespace. All namespaces are used if namespace is empty.
+func (w Client) Templates(ctx context.Context, namespace string) ([]v1alpha1.WorkflowTemplate, error) {
+	ctx, client, err := w.connect(ctx)
+	if err != nil {
+		return nil, w.apiError(err)
+	}
+
+	templateClient, err := client.NewWorkflowTemplateServiceClient()
+	if err != nil {
+		return nil, w.apiError(err)
+	}
+
+	var list *v1alpha1.WorkflowTemplateList
+	err = call(ctx, templateServiceName+"ListWorkflowTemplates", func(ctx context.Context) (err error) {
+		list, err = templateClient.ListWorkflowTemplates(ctx, &workflowtemplate.WorkflowTemplateListRequest{
+			Namespace:   namespace,
+			ListOptions: &v1.ListOptions{},
+		})
+		return err
+	})
+	if err != nil {
+		return nil, w.apiError(err)
+	}
+
+	return list.Items, nil
+}
+
+// ClusterTemplates returns cluster workflow templates.
+func (w Client) ClusterTemplates(ctx context.Context) ([]v1alpha1.ClusterWorkflowTemplate, error) {
+	ctx, client, err := w.connect(ctx)
+	if err != nil {
+		return nil, w.apiError(err)
+	}
+
+	templateClient, err := client.NewClusterWorkflowTemplateServiceClient()
+	if err != nil {
+		return nil, w.apiError(err)
+	}
+
+	var list *v1alpha1.ClusterWorkflowTemplateList
+	err = call(ctx, clusterTemplateServiceName+"ListClusterWorkflowTemplates", func(ctx context.Context) (err error) {
+		list, err = templateClient.ListClusterWorkflowTemplates(ctx, &clusterworkflowtemplate.ClusterWorkflowTemplateListRequest{
+			ListOptions: &v1.ListOptions{},
+		})
+		return err
+	})
+	if err != nil {
+		return nil, w.apiError(err)
+	}
+
+	return list.Items, nil
+}
+
+// Submit creates a new workflow from a template in namespace.
+func (w Client) Submit(ctx context.Context, namespace string, opts SubmitOptions) (v1alpha1.Workflow, error) {
+	kind := opts.Kind
+	if kind == "" {
+		kind = WorkflowTemplateKind
+	}
+
+	if kind != WorkflowTemplateKind && kind != ClusterWorkflowTemplateKind {
+		return v1alpha1.Workflow{}, fmt.Errorf("unsupported template kind %q: %w", kind, event.ErrInvalidRequest)
+	}
+
+	return w.act(ctx, workflowServiceName+"SubmitWorkflow", "submitting", namespace, opts.Template, func(ctx context.Context, client workflow.WorkflowServiceClient) (*v1alpha1.Workflow, error) {
+		return client.SubmitWorkflow(ctx, &workflow.WorkflowSubmitRequest{
+			Namespace:    namespace,
+			ResourceKind: string(kind),
+			ResourceName: opts.Template,
+			SubmitOptions: &v1alpha1.SubmitOpts{
+				GenerateName: opts.GenerateName,
+				Parameters:   joinPairs(opts.Parameters),
+				Labels:       strings.Join(joinPairs(opts.Labels), ","),
+			},
+		})
+	})
+}
+
+// joinPairs returns "key=value" strings sorted by key.
+func joinPairs(m map[string]string) []string {
+	var pairs []string
+	for k, v := range m {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", k, v))
+	}
+
+	sort.Strings(pairs)
+	return pairs
+}
@@ -0,0 +1,80 @@
+package argo
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+var tracer = otel.Tracer("github.com/iskorotkov/chaos-workflows/pkg/argo")
+
+// startSpan starts client span of Argo call. Method is a gRPC method name, e.g. "workflow.WorkflowService/ListWorkflows".
+func startSpan(ctx context.Context, method string) (context.Context, trace.Span) {
+	service, name := method, ""
+	if i := strings.LastIndex(method, "/"); i != -1 {
+		service, name = method[:i], method[i+1:]
+	}
+
+	return tracer.Start(ctx, method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.RPCServiceKey.String(service), semconv.RPCMethodKey.String(name)))
+}
+
+// endSpan ends span of Argo call, marking it as failed if err isn't nil.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// propagateUnary sends trace context of unary calls to Argo server in W3C traceparent metadata.
+func propagateUnary(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return invoker(injectMetadata(ctx), method, req, reply, cc, opts...)
+}
+
+// propagateStream sends trace context of streaming calls to Argo server in W3C traceparent metadata.
+func propagateStream(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return streamer(injectMetadata(ctx), desc, cc, method, opts...)
+}
+
+// injectMetadata returns context with trace context of ctx added to outgoing gRPC metadata.
+func injectMetadata(ctx context.Context) context.Context {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	otel.GetTextMapPropagator().Inject(ctx, metadataCarrier(md))
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// metadataCarrier adapts gRPC metadata to propagation.TextMapCarrier.
+type metadataCarrier metadata.MD
+
+func (m metadataCarrier) Get(key string) string {
+	values := metadata.MD(m).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+func (m metadataCarrier) Set(key, value string) {
+	metadata.MD(m).Set(key, value)
+}
+
+func (m metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
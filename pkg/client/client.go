@@ -0,0 +1,274 @@
+// Package client is a Go client of chaos workflows REST API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+)
+
+// Error is an error response returned by the server.
+type Error struct {
+	StatusCode int
+	// Code is a machine-readable kind of error, e.g. "NotFound".
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// RequestID is an ID of request to find it in server logs.
+	RequestID string `json:"requestId"`
+	// Retryable is set when the same request may succeed later.
+	Retryable bool `json:"retryable"`
+}
+
+func (e *Error) Error() string {
+	if e.RequestID == "" {
+		return fmt.Sprintf("%d %s: %s", e.StatusCode, e.Code, e.Message)
+	}
+
+	return fmt.Sprintf("%d %s: %s (request %s)", e.StatusCode, e.Code, e.Message, e.RequestID)
+}
+
+// errorCodes maps error codes to errors from event package.
+var errorCodes = map[string]error{
+	"InvalidRequest": event.ErrInvalidRequest,
+	"Forbidden":      event.ErrForbidden,
+	"NotFound":       event.ErrNotFound,
+	"Conflict":       event.ErrConflict,
+	"Internal":       event.ErrInternal,
+	"Unavailable":    event.ErrConnectionFailed,
+	"Timeout":        event.ErrDeadlineExceeded,
+}
+
+// Is reports whether e has the same kind as target, so errors.Is(err, event.ErrNotFound) can be used.
+func (e *Error) Is(target error) bool {
+	kind, ok := errorCodes[e.Code]
+	return ok && kind == target
+}
+
+// Options configures Client.
+type Options struct {
+	// HTTPClient is used to send requests. http.DefaultClient is used if it isn't set.
+	HTTPClient *http.Client
+	// Token is a value of Authorization header, e.g. "Bearer <token>".
+	Token string
+}
+
+// Client sends requests to chaos workflows server.
+type Client struct {
+	baseURL string
+	opts    Options
+}
+
+// New returns client of server with API available at baseURL, e.g. "http://localhost:8811/api/v1".
+func New(baseURL string, opts Options) Client {
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+
+	return Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		opts:    opts,
+	}
+}
+
+// ListOptions filters and paginates listed workflows.
+type ListOptions struct {
+	// Namespace limits workflows to a namespace. All namespaces are used if it's empty.
+	Namespace string
+	// Selector is a label selector, e.g. "team=payments".
+	Selector string
+	// Statuses are lowercase workflow statuses, e.g. "running".
+	Statuses []string
+	// ChaosType and Severity select workflows having a step with given annotations.
+	ChaosType string
+	Severity  string
+	// StartedAfter and StartedBefore are ignored if zero.
+	StartedAfter  time.Time
+	StartedBefore time.Time
+	// Sort is a sort order, e.g. "-startedAt". It can't be combined with Limit or Continue.
+	Sort string
+	// Limit is a max number of workflows to return.
+	Limit int64
+	// Continue is a token returned with the previous page.
+	Continue string
+}
+
+// query returns URL query of list request.
+func (o ListOptions) query() url.Values {
+	values := url.Values{}
+	for key, value := range map[string]string{
+		"namespace": o.Namespace,
+		"selector":  o.Selector,
+		"status":    strings.Join(o.Statuses, ","),
+		"type":      o.ChaosType,
+		"severity":  o.Severity,
+		"sort":      o.Sort,
+		"continue":  o.Continue,
+	} {
+		if value != "" {
+			values.Set(key, value)
+		}
+	}
+
+	if !o.StartedAfter.IsZero() {
+		values.Set("startedAfter", o.StartedAfter.Format(time.RFC3339))
+	}
+
+	if !o.StartedBefore.IsZero() {
+		values.Set("startedBefore", o.StartedBefore.Format(time.RFC3339))
+	}
+
+	if o.Limit != 0 {
+		values.Set("limit", strconv.FormatInt(o.Limit, 10))
+	}
+
+	return values
+}
+
+// List returns a page of workflows and a token to get the next page. The token is empty for the last page.
+func (c Client) List(ctx context.Context, opts ListOptions) ([]event.Workflow, string, error) {
+	var workflows []event.Workflow
+	header, err := c.do(ctx, http.MethodGet, "/workflows?"+opts.query().Encode(), nil, &workflows)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return workflows, header.Get("X-Continue-Token"), nil
+}
+
+// Get returns a workflow.
+func (c Client) Get(ctx context.Context, namespace, name string) (event.Workflow, error) {
+	var wf event.Workflow
+	if _, err := c.do(ctx, http.MethodGet, workflowPath(namespace, name), nil, &wf); err != nil {
+		return event.Workflow{}, err
+	}
+
+	return wf, nil
+}
+
+// Cancel stops a workflow and runs its exit handlers. Reason may be empty.
+func (c Client) Cancel(ctx context.Context, namespace, name, reason string) (event.Workflow, error) {
+	body, err := json.Marshal(struct {
+		Reason string `json:"reason,omitempty"`
+	}{reason})
+	if err != nil {
+		return event.Workflow{}, err
+	}
+
+	var wf event.Workflow
+	if _, err := c.do(ctx, http.MethodPost, workflowPath(namespace, name)+"/cancel", body, &wf); err != nil {
+		return event.Workflow{}, err
+	}
+
+	return wf, nil
+}
+
+// Watch returns reader of workflow events. Events of all workflows in namespace are read if name is empty,
+// and events of all workflows are read if both namespace and name are empty.
+// Reader of a single workflow returns event.ErrAllRead with the last event when the workflow finishes.
+func (c Client) Watch(ctx context.Context, namespace, name string) (event.Reader, error) {
+	path := "/workflows/watch"
+	if name != "" {
+		path = workflowPath(namespace, name) + "/watch"
+	} else if namespace != "" {
+		path += "?" + url.Values{"namespace": {namespace}}.Encode()
+	}
+
+	req, err := c.request(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sseReader{
+		ctx:    ctx,
+		body:   resp.Body,
+		lines:  newLineReader(resp.Body),
+		single: name != "",
+	}, nil
+}
+
+// workflowPath returns path of a workflow.
+func workflowPath(namespace, name string) string {
+	return fmt.Sprintf("/workflows/%s/%s", url.PathEscape(namespace), url.PathEscape(name))
+}
+
+// request returns request to API path with authorization header set.
+func (c Client) request(ctx context.Context, method, path string, body []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", event.ErrInvalidRequest, err)
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if c.opts.Token != "" {
+		req.Header.Set("Authorization", c.opts.Token)
+	}
+
+	return req, nil
+}
+
+// send sends request and returns response with successful status code. Error responses are returned as *Error.
+func (c Client) send(req *http.Request) (*http.Response, error) {
+	resp, err := c.opts.HTTPClient.Do(req)
+	if err != nil {
+		if req.Context().Err() != nil {
+			return nil, fmt.Errorf("%w: %v", event.ErrDeadlineExceeded, err)
+		}
+
+		return nil, fmt.Errorf("%w: %v", event.ErrConnectionFailed, err)
+	}
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+
+	apiErr := &Error{StatusCode: resp.StatusCode}
+	if err := json.NewDecoder(resp.Body).Decode(apiErr); err != nil || apiErr.Code == "" {
+		apiErr.Code, apiErr.Message = "Internal", resp.Status
+	}
+
+	return nil, apiErr
+}
+
+// do sends request and decodes JSON response into v.
+func (c Client) do(ctx context.Context, method, path string, body []byte, v interface{}) (http.Header, error) {
+	req, err := c.request(ctx, method, path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("%w: %v", event.ErrDeadlineExceeded, err)
+		}
+
+		return nil, fmt.Errorf("%w: %v", event.ErrInvalidEvent, err)
+	}
+
+	return resp.Header, nil
+}
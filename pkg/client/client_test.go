@@ -0,0 +1,179 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	"github.com/go-chi/chi"
+	"github.com/iskorotkov/chaos-workflows/internal/handlers"
+	"github.com/iskorotkov/chaos-workflows/pkg/argo"
+	"github.com/iskorotkov/chaos-workflows/pkg/argo/argotest"
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+	"github.com/iskorotkov/chaos-workflows/pkg/eventsse"
+	"github.com/iskorotkov/chaos-workflows/pkg/eventws"
+	"go.uber.org/zap"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// newTestClient returns fake Argo server and client of API server connected to it.
+func newTestClient(t *testing.T, workflows ...v1alpha1.Workflow) (*argotest.Server, Client) {
+	t.Helper()
+
+	fake, err := argotest.NewServer(workflows...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(fake.Close)
+
+	logger := zap.NewNop().Sugar()
+
+	argoClient, err := argo.NewClient(argo.Options{URL: fake.URL}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = argoClient.Close()
+	})
+
+	r := chi.NewRouter()
+	r.Mount("/api/v1/workflows", handlers.WorkflowsRouter(argoClient, argoClient, eventws.NewWebsocketFactory(time.Minute, 2*time.Minute, logger),
+		eventsse.NewSSEFactory(time.Minute, time.Second, logger), nil, nil, nil, handlers.Timeouts{}, logger))
+
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+
+	return fake, New(server.URL+"/api/v1/", Options{})
+}
+
+func testWorkflow(namespace, name string, phase v1alpha1.WorkflowPhase) v1alpha1.Workflow {
+	return v1alpha1.Workflow{
+		ObjectMeta: v1.ObjectMeta{Name: name, Namespace: namespace},
+		Status: v1alpha1.WorkflowStatus{
+			Phase:     phase,
+			StartedAt: v1.Now(),
+		},
+	}
+}
+
+func TestClient_List(t *testing.T) {
+	t.Parallel()
+
+	_, client := newTestClient(t,
+		testWorkflow("chaos", "a", v1alpha1.WorkflowRunning),
+		testWorkflow("chaos", "b", v1alpha1.WorkflowSucceeded),
+		testWorkflow("chaos", "c", v1alpha1.WorkflowFailed),
+		testWorkflow("other", "d", v1alpha1.WorkflowRunning))
+
+	workflows, token, err := client.List(context.Background(), ListOptions{
+		Namespace: "chaos",
+		Statuses:  []string{"running", "succeeded"},
+		Sort:      "-name",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if token != "" || len(workflows) != 2 || workflows[0].Name != "b" || workflows[1].Name != "a" {
+		t.Errorf("List() = %+v, %q", workflows, token)
+	}
+
+	workflows, token, err = client.List(context.Background(), ListOptions{Namespace: "chaos", Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if token == "" || len(workflows) != 2 {
+		t.Fatalf("List() with limit = %+v, %q, want 2 workflows and continue token", workflows, token)
+	}
+
+	workflows, _, err = client.List(context.Background(), ListOptions{Namespace: "chaos", Limit: 2, Continue: token})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(workflows) != 1 {
+		t.Errorf("List() of the next page = %+v, want 1 workflow", workflows)
+	}
+}
+
+func TestClient_Get(t *testing.T) {
+	t.Parallel()
+
+	_, client := newTestClient(t, testWorkflow("chaos", "a", v1alpha1.WorkflowRunning))
+
+	wf, err := client.Get(context.Background(), "chaos", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if wf.Name != "a" || wf.Namespace != "chaos" || wf.Status != "running" {
+		t.Errorf("Get() = %+v", wf)
+	}
+
+	_, err = client.Get(context.Background(), "chaos", "missing")
+
+	var apiErr *Error
+	if !errors.Is(err, event.ErrNotFound) || !errors.As(err, &apiErr) || apiErr.StatusCode != 404 {
+		t.Errorf("Get() of missing workflow error = %v, want %v", err, event.ErrNotFound)
+	}
+}
+
+func TestClient_Cancel(t *testing.T) {
+	t.Parallel()
+
+	_, client := newTestClient(t, testWorkflow("chaos", "a", v1alpha1.WorkflowRunning))
+
+	wf, err := client.Cancel(context.Background(), "chaos", "a", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if wf.Status != "failed" || wf.FinishedAt == nil {
+		t.Errorf("Cancel() = %+v", wf)
+	}
+
+	if _, err := client.Cancel(context.Background(), "chaos", "a", ""); !errors.Is(err, event.ErrConflict) {
+		t.Errorf("Cancel() of finished workflow error = %v, want %v", err, event.ErrConflict)
+	}
+}
+
+func TestClient_Watch(t *testing.T) {
+	t.Parallel()
+
+	fake, client := newTestClient(t, testWorkflow("chaos", "a", v1alpha1.WorkflowPending))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	reader, err := client.Watch(ctx, "chaos", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	fake.Play("chaos", "a",
+		argotest.Transition{Delay: 50 * time.Millisecond, Phase: v1alpha1.WorkflowRunning},
+		argotest.Transition{Delay: 50 * time.Millisecond, Phase: v1alpha1.WorkflowFailed})
+
+	var statuses []string
+	for {
+		ev, err := reader.Read()
+		if err != nil && err != event.ErrAllRead {
+			t.Fatal(err)
+		}
+
+		statuses = append(statuses, ev.Status)
+
+		if err == event.ErrAllRead {
+			break
+		}
+	}
+
+	if len(statuses) != 3 || statuses[0] != "pending" || statuses[1] != "running" || statuses[2] != "failed" {
+		t.Errorf("read statuses %v, want [pending running failed]", statuses)
+	}
+}
@@ -0,0 +1,85 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+)
+
+// shutdownEvent is sent by server before ending the stream due to restart.
+const shutdownEvent = "shutdown"
+
+// maxLineSize limits size of a single server-sent events line.
+const maxLineSize = 4 << 20
+
+// newLineReader returns scanner of server-sent events lines.
+func newLineReader(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxLineSize)
+	return scanner
+}
+
+// sseReader reads workflow events from server-sent events stream.
+type sseReader struct {
+	ctx   context.Context
+	body  io.Closer
+	lines *bufio.Scanner
+	// single is set when the stream watches one workflow and ends when it finishes.
+	single bool
+}
+
+func (s *sseReader) Read() (event.Workflow, error) {
+	var name string
+	var data []string
+	for s.lines.Scan() {
+		line := s.lines.Text()
+
+		// Empty line dispatches event. Comments and other fields are ignored.
+		if line == "" {
+			switch {
+			case name == shutdownEvent:
+				// Server is restarting, so the same request may succeed later.
+				return event.Workflow{}, event.ErrConnectionFailed
+			case name != "" || len(data) == 0:
+				name, data = "", nil
+				continue
+			}
+
+			return s.parse(strings.Join(data, "\n"))
+		}
+
+		if value := strings.TrimPrefix(line, "data:"); value != line {
+			data = append(data, strings.TrimPrefix(value, " "))
+		} else if value := strings.TrimPrefix(line, "event:"); value != line {
+			name = strings.TrimPrefix(value, " ")
+		}
+	}
+
+	if s.ctx.Err() != nil {
+		return event.Workflow{}, event.ErrDeadlineExceeded
+	}
+
+	return event.Workflow{}, event.ErrConnectionFailed
+}
+
+// parse returns event decoded from data field.
+func (s *sseReader) parse(data string) (event.Workflow, error) {
+	var ev event.Workflow
+	if err := json.Unmarshal([]byte(data), &ev); err != nil {
+		return event.Workflow{}, event.ErrInvalidEvent
+	}
+
+	if s.single && (ev.Type == "DELETED" || ev.Finished()) {
+		return ev, event.ErrAllRead
+	}
+
+	return ev, nil
+}
+
+func (s *sseReader) Close() error {
+	return s.body.Close()
+}
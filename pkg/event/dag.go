@@ -0,0 +1,273 @@
+package event
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+)
+
+// dependsResults lists task results which can be used in "depends" expressions, e.g. "a.Succeeded && b.Failed".
+var dependsResults = map[string]bool{
+	"Succeeded":    true,
+	"Failed":       true,
+	"Errored":      true,
+	"Skipped":      true,
+	"Omitted":      true,
+	"Daemoned":     true,
+	"AnySucceeded": true,
+	"AllFailed":    true,
+}
+
+// stagePhases lists step statuses in order of priority when deriving status of a DAG stage.
+var stagePhases = []string{"running", "pending", "error", "failed", "succeeded"}
+
+// buildDAGStages returns stages of a DAG template.
+// Tasks are grouped into stages by their level, which is the length of the longest chain of dependencies leading to a task.
+// Tasks which haven't started yet are not included.
+func buildDAGStages(dagNode v1alpha1.NodeStatus, ts []v1alpha1.Template, nodes nodes) ([]Stage, bool) {
+	var dependencies map[string][]string
+	if spec, ok := findStepSpec(dagNode, ts); ok && spec.DAG != nil {
+		dependencies = specDependencies(spec.DAG.Tasks)
+	} else {
+		dependencies = statusDependencies(dagNode, nodes)
+	}
+
+	levels, ok := dagLevels(dependencies)
+	if !ok {
+		return nil, false
+	}
+
+	taskNodes := make(map[string]v1alpha1.NodeStatus)
+	for _, n := range nodes {
+		if isDAGTask(dagNode, n) {
+			taskNodes[n.DisplayName] = n
+		}
+	}
+
+	stepsByLevel := make(map[int][]Step)
+	for task, level := range levels {
+		n, ok := taskNodes[task]
+		if !ok {
+			continue
+		}
+
+		step, ok := buildStep(n, ts, nodes)
+		if !ok {
+			return nil, false
+		}
+
+		step.Dependencies = dependencies[task]
+		stepsByLevel[level] = append(stepsByLevel[level], step)
+	}
+
+	stages := make([]Stage, 0)
+	for level := 0; level < len(levels); level++ {
+		steps, ok := stepsByLevel[level]
+		if !ok {
+			continue
+		}
+
+		sort.Slice(steps, func(i, j int) bool {
+			return steps[i].DisplayName < steps[j].DisplayName
+		})
+
+		stages = append(stages, newLevelStage(steps))
+	}
+
+	return stages, true
+}
+
+// isDAGTask returns whether node is a task of DAG. Nodes of tasks expanded with items or params are not tasks.
+func isDAGTask(dagNode v1alpha1.NodeStatus, n v1alpha1.NodeStatus) bool {
+	return n.BoundaryID == dagNode.ID && n.Name == fmt.Sprintf("%s.%s", dagNode.Name, n.DisplayName)
+}
+
+// hasExpandedChildren returns whether node children are expanded items or retry attempts of the same task.
+func hasExpandedChildren(n v1alpha1.NodeStatus) bool {
+	return n.Type == v1alpha1.NodeTypeTaskGroup || n.Type == v1alpha1.NodeTypeRetry
+}
+
+// specDependencies returns dependencies of DAG tasks declared in template spec.
+func specDependencies(tasks []v1alpha1.DAGTask) map[string][]string {
+	dependencies := make(map[string][]string, len(tasks))
+	for _, t := range tasks {
+		dependencies[t.Name] = append(append([]string(nil), t.Dependencies...), dependsTasks(t.Depends)...)
+	}
+
+	return cleanDependencies(dependencies)
+}
+
+// dependsTasks returns names of tasks used in "depends" expression.
+func dependsTasks(depends string) []string {
+	fields := strings.FieldsFunc(depends, func(r rune) bool {
+		return strings.ContainsRune("()&|! \t\n", r)
+	})
+
+	var tasks []string
+	for _, f := range fields {
+		if i := strings.LastIndex(f, "."); i != -1 && dependsResults[f[i+1:]] {
+			f = f[:i]
+		}
+
+		tasks = append(tasks, f)
+	}
+
+	return tasks
+}
+
+// statusDependencies returns dependencies of DAG tasks derived from node status when template spec isn't available.
+// Argo adds tasks as children of the tasks they depend on, and root tasks as children of DAG node.
+func statusDependencies(dagNode v1alpha1.NodeStatus, nodes nodes) map[string][]string {
+	// Expanded tasks and retry attempts are named like tasks, but they are children of other tasks.
+	expanded := make(map[string]bool)
+	for _, n := range nodes {
+		if isDAGTask(dagNode, n) && hasExpandedChildren(n) {
+			for _, id := range n.Children {
+				expanded[id] = true
+			}
+		}
+	}
+
+	isTask := func(n v1alpha1.NodeStatus) bool {
+		return isDAGTask(dagNode, n) && !expanded[n.ID]
+	}
+
+	dependencies := make(map[string][]string)
+	for _, n := range nodes {
+		if isTask(n) {
+			dependencies[n.DisplayName] = nil
+		}
+	}
+
+	for _, n := range nodes {
+		if !isTask(n) {
+			continue
+		}
+
+		successors := n.Children
+		if hasExpandedChildren(n) {
+			// Dependent tasks are children of expanded nodes.
+			successors = nil
+			for _, id := range n.Children {
+				successors = append(successors, nodes[id].Children...)
+			}
+		}
+
+		for _, id := range successors {
+			if child, ok := nodes[id]; ok && isTask(child) {
+				dependencies[child.DisplayName] = append(dependencies[child.DisplayName], n.DisplayName)
+			}
+		}
+	}
+
+	return cleanDependencies(dependencies)
+}
+
+// cleanDependencies removes duplicates and unknown tasks from dependencies and sorts them.
+func cleanDependencies(dependencies map[string][]string) map[string][]string {
+	for task, deps := range dependencies {
+		set := make(map[string]bool)
+		for _, dep := range deps {
+			if _, ok := dependencies[dep]; ok && dep != "" {
+				set[dep] = true
+			}
+		}
+
+		if len(set) == 0 {
+			dependencies[task] = nil
+		} else {
+			dependencies[task] = sortedKeys(set)
+		}
+	}
+
+	return dependencies
+}
+
+// dagLevels returns levels of DAG tasks. It returns false if dependencies contain a cycle.
+func dagLevels(dependencies map[string][]string) (map[string]int, bool) {
+	levels := make(map[string]int, len(dependencies))
+	visiting := make(map[string]bool)
+
+	var visit func(task string) bool
+	visit = func(task string) bool {
+		if _, ok := levels[task]; ok {
+			return true
+		}
+
+		if visiting[task] {
+			return false
+		}
+
+		visiting[task] = true
+
+		level := 0
+		for _, dep := range dependencies[task] {
+			if !visit(dep) {
+				return false
+			}
+
+			if levels[dep]+1 > level {
+				level = levels[dep] + 1
+			}
+		}
+
+		visiting[task] = false
+		levels[task] = level
+		return true
+	}
+
+	for task := range dependencies {
+		if !visit(task) {
+			return nil, false
+		}
+	}
+
+	return levels, true
+}
+
+// newLevelStage returns new Stage of DAG tasks on the same level.
+func newLevelStage(steps []Step) Stage {
+	stage := Stage{
+		Status: stageStatus(steps),
+		Steps:  steps,
+	}
+
+	finished := true
+	for _, s := range steps {
+		if !s.StartedAt.IsZero() && (stage.StartedAt.IsZero() || s.StartedAt.Before(stage.StartedAt)) {
+			stage.StartedAt = s.StartedAt
+		}
+
+		if s.FinishedAt == nil {
+			finished = false
+		} else if stage.FinishedAt == nil || s.FinishedAt.After(*stage.FinishedAt) {
+			finishedAt := *s.FinishedAt
+			stage.FinishedAt = &finishedAt
+		}
+	}
+
+	if !finished {
+		stage.FinishedAt = nil
+	}
+
+	return stage
+}
+
+// stageStatus returns status of a stage derived from statuses of its steps.
+func stageStatus(steps []Step) string {
+	for _, phase := range stagePhases {
+		for _, s := range steps {
+			if s.Status == phase {
+				return phase
+			}
+		}
+	}
+
+	if len(steps) != 0 {
+		return steps[0].Status
+	}
+
+	return ""
+}
@@ -0,0 +1,149 @@
+package event
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// testNode returns node status of a finished step.
+func testNode(id, name, displayName, boundaryID, template string, phase v1alpha1.NodePhase, children ...string) v1alpha1.NodeStatus {
+	start := time.Date(2021, 11, 1, 0, 0, 0, 0, time.UTC)
+	return v1alpha1.NodeStatus{
+		ID:           id,
+		Name:         name,
+		DisplayName:  displayName,
+		Type:         v1alpha1.NodeTypePod,
+		TemplateName: template,
+		Phase:        phase,
+		BoundaryID:   boundaryID,
+		Children:     children,
+		StartedAt:    v1.Time{Time: start},
+		FinishedAt:   v1.Time{Time: start.Add(time.Minute)},
+	}
+}
+
+// stageNames returns display names of steps in each stage.
+func stageNames(stages []Stage) [][]string {
+	var names [][]string
+	for _, stage := range stages {
+		var stageNames []string
+		for _, step := range stage.Steps {
+			stageNames = append(stageNames, step.DisplayName)
+		}
+
+		names = append(names, stageNames)
+	}
+
+	return names
+}
+
+// Test_buildNodesTree_dag tests grouping of DAG tasks into stages by levels.
+func Test_buildNodesTree_dag(t *testing.T) {
+	t.Parallel()
+
+	chaos := v1alpha1.Template{
+		Name:     "pod-delete",
+		Metadata: v1alpha1.Metadata{Annotations: map[string]string{typeKey: "pod-delete"}},
+	}
+	dag := v1alpha1.Template{
+		Name: "main",
+		DAG: &v1alpha1.DAGTemplate{Tasks: []v1alpha1.DAGTask{
+			{Name: "a", Template: "pod-delete"},
+			{Name: "b", Template: "pod-delete", Dependencies: []string{"a"}},
+			{Name: "c", Template: "pod-delete", Depends: "a.Succeeded || a.Failed"},
+			{Name: "d", Template: "pod-delete", Depends: "(b && c.Succeeded)"},
+			{Name: "e", Template: "pod-delete", Depends: "d"},
+		}},
+	}
+
+	root := testNode("wf", "wf", "wf", "", "main", v1alpha1.NodeRunning, "wf-a")
+	root.Type = v1alpha1.NodeTypeDAG
+	nodes := nodes{
+		"wf":   root,
+		"wf-a": testNode("wf-a", "wf.a", "a", "wf", "pod-delete", v1alpha1.NodeSucceeded, "wf-b", "wf-c"),
+		"wf-b": testNode("wf-b", "wf.b", "b", "wf", "pod-delete", v1alpha1.NodeSucceeded, "wf-d"),
+		"wf-c": testNode("wf-c", "wf.c", "c", "wf", "pod-delete", v1alpha1.NodeFailed, "wf-d"),
+		"wf-d": testNode("wf-d", "wf.d", "d", "wf", "pod-delete", v1alpha1.NodeRunning),
+	}
+	running := nodes["wf-d"]
+	running.FinishedAt = v1.Time{}
+	nodes["wf-d"] = running
+
+	wantNames := [][]string{{"a"}, {"b", "c"}, {"d"}}
+
+	for name, ts := range map[string][]v1alpha1.Template{
+		"dependencies from spec":   {dag, chaos},
+		"dependencies from status": {chaos},
+	} {
+		stages, ok := buildNodesTree(ts, nodes, "wf")
+		if !ok {
+			t.Fatalf("%s: couldn't build nodes tree", name)
+		}
+
+		if got := stageNames(stages); !reflect.DeepEqual(got, wantNames) {
+			t.Errorf("%s: stages = %v, want %v", name, got, wantNames)
+		}
+
+		if got := stages[2].Steps[0].Dependencies; !reflect.DeepEqual(got, []string{"b", "c"}) {
+			t.Errorf("%s: dependencies of d = %v, want [b c]", name, got)
+		}
+
+		if stages[1].Status != "failed" || stages[2].Status != "running" || stages[2].FinishedAt != nil {
+			t.Errorf("%s: unexpected stage statuses: %s, %s", name, stages[1].Status, stages[2].Status)
+		}
+
+		if stages[0].Steps[0].Type != "pod-delete" {
+			t.Errorf("%s: chaos annotations weren't read", name)
+		}
+	}
+
+	cyclic := v1alpha1.Template{
+		Name: "main",
+		DAG: &v1alpha1.DAGTemplate{Tasks: []v1alpha1.DAGTask{
+			{Name: "a", Depends: "b"},
+			{Name: "b", Depends: "a"},
+		}},
+	}
+	if _, ok := buildNodesTree([]v1alpha1.Template{cyclic}, nodes, "wf"); ok {
+		t.Error("cyclic dependencies were accepted")
+	}
+}
+
+// Test_buildNodesTree_steps tests that step groups of nested templates and missing templates don't break stages.
+func Test_buildNodesTree_steps(t *testing.T) {
+	t.Parallel()
+
+	root := testNode("wf", "wf", "wf", "", "main", v1alpha1.NodeSucceeded, "wf-0")
+	root.Type = v1alpha1.NodeTypeSteps
+	group := func(id, displayName, boundaryID string, children ...string) v1alpha1.NodeStatus {
+		n := testNode(id, id, displayName, boundaryID, "", v1alpha1.NodeSucceeded, children...)
+		n.Type = v1alpha1.NodeTypeStepGroup
+		return n
+	}
+
+	nodes := nodes{
+		"wf":         root,
+		"wf-0":       group("wf-0", "[0]", "wf", "wf-kill"),
+		"wf-kill":    testNode("wf-kill", "wf[0].kill", "kill", "wf", "pod-delete", v1alpha1.NodeSucceeded, "wf-1"),
+		"wf-1":       group("wf-1", "[1]", "wf", "wf-check", "wf-missing"),
+		"wf-check":   testNode("wf-check", "wf[1].check", "check", "wf", "check", v1alpha1.NodeSucceeded),
+		"wf-missing": testNode("wf-missing", "wf[1].missing", "missing", "wf", "missing", v1alpha1.NodeSucceeded),
+		"nested-0":   group("nested-0", "[0]", "nested"),
+	}
+
+	ts := []v1alpha1.Template{{Name: "pod-delete"}, {Name: "check"}}
+
+	stages, ok := buildNodesTree(ts, nodes, "wf")
+	if !ok {
+		t.Fatal("couldn't build nodes tree")
+	}
+
+	want := [][]string{{"kill"}, {"check", "missing"}}
+	if got := stageNames(stages); !reflect.DeepEqual(got, want) {
+		t.Errorf("stages = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,50 @@
+package event
+
+import (
+	"context"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+)
+
+// LogEntry is a line of step container output.
+type LogEntry struct {
+	PodName string `json:"podName"`
+	Content string `json:"content"`
+}
+
+// LogReader reads a sequence of log entries. Unlike Reader, it returns ErrAllRead with an empty entry.
+type LogReader interface {
+	Read() (LogEntry, error)
+	Close() error
+}
+
+// LogWriter writes a sequence of log entries.
+type LogWriter interface {
+	Write(ctx context.Context, entry LogEntry) error
+	Close() error
+}
+
+// StepPodName returns name of the pod running a step given node ID or display name of the step.
+// The latest started pod is returned if there are several steps with the same name, and the last attempt is used for retried steps.
+func StepPodName(w v1alpha1.Workflow, step string) (string, bool) {
+	n, ok := w.Status.Nodes[step]
+	if !ok {
+		found := false
+		for _, candidate := range w.Status.Nodes {
+			if candidate.DisplayName == step && (!found || candidate.StartedAt.After(n.StartedAt.Time)) {
+				n, found = candidate, true
+			}
+		}
+
+		if !found {
+			return "", false
+		}
+	}
+
+	if n.Type == v1alpha1.NodeTypeRetry && len(n.Children) != 0 {
+		n = w.Status.Nodes[n.Children[len(n.Children)-1]]
+	}
+
+	name := podName(n)
+	return name, name != ""
+}
@@ -0,0 +1,46 @@
+package event
+
+import (
+	"testing"
+	"time"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TestStepPodName tests finding pods of steps by node ID and display name.
+func TestStepPodName(t *testing.T) {
+	t.Parallel()
+
+	retry := testNode("wf-retry", "wf[0].kill", "kill", "wf", "pod-delete", v1alpha1.NodeSucceeded, "wf-retry-0", "wf-retry-1")
+	retry.Type = v1alpha1.NodeTypeRetry
+
+	later := testNode("wf-later", "wf[1].check", "check", "wf", "check", v1alpha1.NodeSucceeded)
+	later.StartedAt = v1.Time{Time: later.StartedAt.Add(time.Hour)}
+
+	w := v1alpha1.Workflow{Status: v1alpha1.WorkflowStatus{Nodes: v1alpha1.Nodes{
+		"wf-retry":   retry,
+		"wf-retry-0": testNode("wf-retry-0", "wf[0].kill(0)", "kill(0)", "wf", "pod-delete", v1alpha1.NodeFailed),
+		"wf-retry-1": testNode("wf-retry-1", "wf[0].kill(1)", "kill(1)", "wf", "pod-delete", v1alpha1.NodeSucceeded),
+		"wf-earlier": testNode("wf-earlier", "wf[0].check", "check", "wf", "check", v1alpha1.NodeSucceeded),
+		"wf-later":   later,
+	}}}
+
+	tests := []struct {
+		step   string
+		want   string
+		wantOk bool
+	}{
+		{step: "wf-retry-0", want: "wf-retry-0", wantOk: true},
+		{step: "kill", want: "wf-retry-1", wantOk: true},
+		{step: "check", want: "wf-later", wantOk: true},
+		{step: "missing", wantOk: false},
+	}
+
+	for _, tt := range tests {
+		got, ok := StepPodName(w, tt.step)
+		if got != tt.want || ok != tt.wantOk {
+			t.Errorf("StepPodName(%s) = %s, %v, want %s, %v", tt.step, got, ok, tt.want, tt.wantOk)
+		}
+	}
+}
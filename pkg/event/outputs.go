@@ -0,0 +1,82 @@
+package event
+
+import (
+	"strconv"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+)
+
+// Outputs are output parameters and artifacts of a step.
+type Outputs struct {
+	Parameters []OutputParameter `json:"parameters,omitempty"`
+	Artifacts  []ArtifactRef     `json:"artifacts,omitempty"`
+	// Result is an output of a script template.
+	Result *string `json:"result,omitempty"`
+}
+
+// OutputParameter is an output parameter of a step.
+type OutputParameter struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// ArtifactRef is a reference to an output artifact of a step.
+type ArtifactRef struct {
+	Name string `json:"name"`
+	// Path is a path of the artifact in the container.
+	Path string `json:"path,omitempty"`
+	// Key is a location of the artifact in the artifact repository.
+	Key string `json:"key,omitempty"`
+}
+
+// podName returns name of the pod running the node.
+// Argo names pods after node IDs unless POD_NAMES=v2 is set for the controller.
+func podName(n v1alpha1.NodeStatus) string {
+	if n.Type != v1alpha1.NodeTypePod {
+		return ""
+	}
+
+	return n.ID
+}
+
+// exitCode returns exit code of the main container or nil if it's unknown.
+func exitCode(o *v1alpha1.Outputs) *int {
+	if o == nil || o.ExitCode == nil {
+		return nil
+	}
+
+	code, err := strconv.Atoi(*o.ExitCode)
+	if err != nil {
+		return nil
+	}
+
+	return &code
+}
+
+// newOutputs returns new Outputs from v1alpha1.Outputs. It returns nil if there are no outputs.
+func newOutputs(o *v1alpha1.Outputs) *Outputs {
+	if o == nil || len(o.Parameters) == 0 && len(o.Artifacts) == 0 && o.Result == nil {
+		return nil
+	}
+
+	outputs := &Outputs{Result: o.Result}
+	for _, p := range o.Parameters {
+		parameter := OutputParameter{Name: p.Name}
+		if p.Value != nil {
+			parameter.Value = p.Value.String()
+		}
+
+		outputs.Parameters = append(outputs.Parameters, parameter)
+	}
+
+	for _, a := range o.Artifacts {
+		key, _ := a.GetKey()
+		outputs.Artifacts = append(outputs.Artifacts, ArtifactRef{
+			Name: a.Name,
+			Path: a.Path,
+			Key:  key,
+		})
+	}
+
+	return outputs
+}
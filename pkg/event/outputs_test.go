@@ -0,0 +1,52 @@
+package event
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+)
+
+// Test_newStep_diagnostics tests that fields used for diagnosis of failed steps are copied.
+func Test_newStep_diagnostics(t *testing.T) {
+	t.Parallel()
+
+	result, code := "deleted 3 pods", "137"
+	n := testNode("wf-123", "wf[0].kill", "kill", "wf", "pod-delete", v1alpha1.NodeFailed)
+	n.Message = "OOMKilled (exit code 137)"
+	n.HostNodeName = "worker-1"
+	n.Outputs = &v1alpha1.Outputs{
+		Parameters: []v1alpha1.Parameter{{Name: "verdict", Value: v1alpha1.AnyStringPtr("Fail")}},
+		Artifacts: []v1alpha1.Artifact{{
+			Name:             "report",
+			Path:             "/tmp/report.json",
+			ArtifactLocation: v1alpha1.ArtifactLocation{S3: &v1alpha1.S3Artifact{Key: "wf/report.json"}},
+		}},
+		Result:   &result,
+		ExitCode: &code,
+	}
+
+	step := newStep(v1alpha1.Metadata{}, n)
+	if step.Message != n.Message || step.PodName != "wf-123" || step.HostNodeName != "worker-1" {
+		t.Errorf("unexpected step: %+v", step)
+	}
+
+	if step.ExitCode == nil || *step.ExitCode != 137 {
+		t.Errorf("ExitCode = %v, want 137", step.ExitCode)
+	}
+
+	want := &Outputs{
+		Parameters: []OutputParameter{{Name: "verdict", Value: "Fail"}},
+		Artifacts:  []ArtifactRef{{Name: "report", Path: "/tmp/report.json", Key: "wf/report.json"}},
+		Result:     &result,
+	}
+	if !reflect.DeepEqual(step.Outputs, want) {
+		t.Errorf("Outputs = %+v, want %+v", step.Outputs, want)
+	}
+
+	n.Type, n.Outputs = v1alpha1.NodeTypeSteps, nil
+	step = newStep(v1alpha1.Metadata{}, n)
+	if step.PodName != "" || step.ExitCode != nil || step.Outputs != nil {
+		t.Errorf("unexpected step not backed by pod: %+v", step)
+	}
+}
@@ -0,0 +1,119 @@
+package event
+
+import (
+	"sort"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+)
+
+// Template is a summary of a workflow template experiments can be submitted from.
+type Template struct {
+	Name string `json:"name"`
+	// Namespace is empty for cluster templates.
+	Namespace  string      `json:"namespace,omitempty"`
+	Cluster    bool        `json:"cluster"`
+	Parameters []Parameter `json:"parameters"`
+	// Types and Severities list distinct chaos types and severities of template steps.
+	Types      []string       `json:"types"`
+	Severities []string       `json:"severities"`
+	Steps      []TemplateStep `json:"steps"`
+}
+
+// Parameter is an input parameter of a template.
+type Parameter struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	// Enum lists allowed values. Any value is allowed if it's empty.
+	Enum []string `json:"enum,omitempty"`
+}
+
+// TemplateStep is a chaos step declared in a template.
+type TemplateStep struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Severity string `json:"severity"`
+	Scale    string `json:"scale"`
+	Version  string `json:"version"`
+}
+
+// IsChaos returns whether template contains chaos steps.
+func (t Template) IsChaos() bool {
+	return len(t.Steps) != 0
+}
+
+func FromWorkflowTemplate(t v1alpha1.WorkflowTemplate) Template {
+	return newTemplate(t.Name, t.Namespace, false, t.Spec.WorkflowSpec)
+}
+
+func FromClusterWorkflowTemplate(t v1alpha1.ClusterWorkflowTemplate) Template {
+	return newTemplate(t.Name, "", true, t.Spec.WorkflowSpec)
+}
+
+// ChaosSteps returns chaos steps declared in workflow templates, including templates stored from referenced ones.
+func ChaosSteps(w v1alpha1.Workflow) []TemplateStep {
+	return newTemplate(w.Name, w.Namespace, false, v1alpha1.WorkflowSpec{Templates: workflowTemplates(w)}).Steps
+}
+
+// newTemplate returns new Template summarizing chaos annotations of spec templates.
+func newTemplate(name, namespace string, cluster bool, spec v1alpha1.WorkflowSpec) Template {
+	template := Template{
+		Name:       name,
+		Namespace:  namespace,
+		Cluster:    cluster,
+		Parameters: make([]Parameter, 0),
+		Types:      make([]string, 0),
+		Severities: make([]string, 0),
+		Steps:      make([]TemplateStep, 0),
+	}
+
+	for _, p := range spec.Arguments.Parameters {
+		parameter := Parameter{Name: p.Name}
+		if p.Value != nil {
+			parameter.Value = p.Value.String()
+		} else if p.Default != nil {
+			parameter.Value = p.Default.String()
+		}
+
+		for _, value := range p.Enum {
+			parameter.Enum = append(parameter.Enum, value.String())
+		}
+
+		template.Parameters = append(template.Parameters, parameter)
+	}
+
+	types, severities := make(map[string]bool), make(map[string]bool)
+	for _, t := range spec.Templates {
+		annotations := t.Metadata.Annotations
+		if annotations[typeKey] == "" {
+			continue
+		}
+
+		template.Steps = append(template.Steps, TemplateStep{
+			Name:     t.Name,
+			Type:     annotations[typeKey],
+			Severity: annotations[severityKey],
+			Scale:    annotations[scaleKey],
+			Version:  annotations[versionKey],
+		})
+
+		types[annotations[typeKey]] = true
+		if annotations[severityKey] != "" {
+			severities[annotations[severityKey]] = true
+		}
+	}
+
+	template.Types = append(template.Types, sortedKeys(types)...)
+	template.Severities = append(template.Severities, sortedKeys(severities)...)
+	return template
+}
+
+// sortedKeys returns keys of a set in ascending order.
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
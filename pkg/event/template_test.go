@@ -0,0 +1,72 @@
+package event
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TestFromWorkflowTemplate tests summarizing of chaos annotations.
+func TestFromWorkflowTemplate(t *testing.T) {
+	t.Parallel()
+
+	chaosStep := func(name, chaosType, severity string) v1alpha1.Template {
+		return v1alpha1.Template{
+			Name: name,
+			Metadata: v1alpha1.Metadata{Annotations: map[string]string{
+				typeKey:     chaosType,
+				severityKey: severity,
+				scaleKey:    "1",
+				versionKey:  "v1",
+			}},
+		}
+	}
+
+	template := v1alpha1.WorkflowTemplate{
+		ObjectMeta: v1.ObjectMeta{Name: "pod-chaos", Namespace: "litmus"},
+		Spec: v1alpha1.WorkflowTemplateSpec{WorkflowSpec: v1alpha1.WorkflowSpec{
+			Arguments: v1alpha1.Arguments{Parameters: []v1alpha1.Parameter{
+				{Name: "duration", Value: v1alpha1.AnyStringPtr("60s")},
+				{Name: "target", Default: v1alpha1.AnyStringPtr("api"), Enum: []v1alpha1.AnyString{"api", "db"}},
+			}},
+			Templates: []v1alpha1.Template{
+				{Name: "entrypoint"},
+				chaosStep("delete", "pod-delete", "critical"),
+				chaosStep("cpu", "pod-cpu-hog", "light"),
+				chaosStep("delete-again", "pod-delete", "critical"),
+			},
+		}},
+	}
+
+	got := FromWorkflowTemplate(template)
+	if got.Name != "pod-chaos" || got.Namespace != "litmus" || got.Cluster || !got.IsChaos() {
+		t.Errorf("unexpected template: %+v", got)
+	}
+
+	wantParameters := []Parameter{
+		{Name: "duration", Value: "60s"},
+		{Name: "target", Value: "api", Enum: []string{"api", "db"}},
+	}
+	if !reflect.DeepEqual(got.Parameters, wantParameters) {
+		t.Errorf("Parameters = %+v, want %+v", got.Parameters, wantParameters)
+	}
+
+	if !reflect.DeepEqual(got.Types, []string{"pod-cpu-hog", "pod-delete"}) {
+		t.Errorf("Types = %v", got.Types)
+	}
+
+	if !reflect.DeepEqual(got.Severities, []string{"critical", "light"}) {
+		t.Errorf("Severities = %v", got.Severities)
+	}
+
+	if len(got.Steps) != 3 || got.Steps[1] != (TemplateStep{Name: "cpu", Type: "pod-cpu-hog", Severity: "light", Scale: "1", Version: "v1"}) {
+		t.Errorf("Steps = %+v", got.Steps)
+	}
+
+	cluster := FromClusterWorkflowTemplate(v1alpha1.ClusterWorkflowTemplate{ObjectMeta: v1.ObjectMeta{Name: "empty"}})
+	if !cluster.Cluster || cluster.IsChaos() || cluster.Steps == nil || cluster.Parameters == nil {
+		t.Errorf("unexpected cluster template: %+v", cluster)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"math/rand"
 	"reflect"
+	"sort"
 	"strings"
 	"time"
 
@@ -26,6 +27,11 @@ var (
 	ErrDeadlineExceeded = errors.New("streaming was finished due to a timeout")
 	ErrInvalidEvent     = errors.New("event was in invalid format")
 	ErrConnectionFailed = errors.New("couldn't establish connection to external service")
+	ErrNotFound         = errors.New("resource not found")
+	ErrForbidden        = errors.New("access to resource is forbidden")
+	ErrConflict         = errors.New("resource is in a conflicting state")
+	ErrInvalidRequest   = errors.New("request is invalid")
+	ErrInternal         = errors.New("external service failed to handle request")
 )
 
 // GenerateTestError returns a random error from package.
@@ -51,12 +57,40 @@ func GenerateTestError(rand *rand.Rand) error {
 
 // Step is a part of a stage.
 type Step struct {
-	Name       string     `json:"name"`
-	Type       string     `json:"type"`
-	Severity   string     `json:"severity"`
-	Scale      string     `json:"scale"`
+	// Name is a name of the step template.
+	Name string `json:"name"`
+	// DisplayName is a name of the step or DAG task.
+	DisplayName string `json:"displayName"`
+	// Dependencies lists display names of DAG tasks the step depends on.
+	Dependencies []string   `json:"dependencies,omitempty"`
+	Type         string     `json:"type"`
+	Severity     string     `json:"severity"`
+	Scale        string     `json:"scale"`
+	Status       string     `json:"status"`
+	Version      string     `json:"version"`
+	StartedAt    time.Time  `json:"startedAt"`
+	FinishedAt   *time.Time `json:"finishedAt"`
+	// Message is a reason of step failure or another status message.
+	Message string `json:"message,omitempty"`
+	// PodName is a name of the pod running the step. It's empty for steps not backed by pods.
+	PodName      string `json:"podName,omitempty"`
+	HostNodeName string `json:"hostNodeName,omitempty"`
+	// ExitCode is an exit code of the main container.
+	ExitCode *int     `json:"exitCode,omitempty"`
+	Outputs  *Outputs `json:"outputs,omitempty"`
+	// Stages are stages of a nested steps or DAG template.
+	Stages []Stage `json:"stages,omitempty"`
+	// Attempts lists attempts of a step with retry strategy.
+	Attempts []Attempt `json:"attempts,omitempty"`
+}
+
+// Attempt is a single try of a step with retry strategy.
+type Attempt struct {
+	// Number starts from 1.
+	Number     int        `json:"number"`
 	Status     string     `json:"status"`
-	Version    string     `json:"version"`
+	Message    string     `json:"message,omitempty"`
+	PodName    string     `json:"podName,omitempty"`
 	StartedAt  time.Time  `json:"startedAt"`
 	FinishedAt *time.Time `json:"finishedAt"`
 }
@@ -67,20 +101,22 @@ func (s Step) Generate(rand *rand.Rand, _ int) reflect.Value {
 	}
 	finishedAt := time.Time{}.Add(-time.Duration(rand.Intn(10)) * time.Minute)
 	return reflect.ValueOf(Step{
-		Name:       f("name"),
-		Type:       f("type"),
-		Severity:   f("severity"),
-		Scale:      f("scale"),
-		Status:     f("status"),
-		Version:    f("version"),
-		StartedAt:  time.Time{}.Add(-time.Duration(rand.Intn(10)) * time.Hour),
-		FinishedAt: &finishedAt,
+		Name:        f("name"),
+		DisplayName: f("display-name"),
+		Type:        f("type"),
+		Severity:    f("severity"),
+		Scale:       f("scale"),
+		Status:      f("status"),
+		Version:     f("version"),
+		StartedAt:   time.Time{}.Add(-time.Duration(rand.Intn(10)) * time.Hour),
+		FinishedAt:  &finishedAt,
 	})
 }
 
 // Stage is a part of a workflow.
 type Stage struct {
 	Status     string     `json:"status"`
+	Message    string     `json:"message,omitempty"`
 	StartedAt  time.Time  `json:"startedAt"`
 	FinishedAt *time.Time `json:"finishedAt"`
 	Steps      []Step     `json:"steps"`
@@ -117,6 +153,40 @@ type Workflow struct {
 	Stages []Stage `json:"stages"`
 }
 
+// Finished returns whether workflow is completed.
+func (e Workflow) Finished() bool {
+	switch e.Status {
+	case "", "running", "pending":
+		return false
+	default:
+		return true
+	}
+}
+
+// HasStep returns whether workflow has a step with chaos type and severity, including steps of nested templates.
+// Empty chaos type or severity matches any value.
+func (e Workflow) HasStep(chaosType, severity string) bool {
+	return hasStep(e.Stages, chaosType, severity)
+}
+
+// hasStep returns whether any step in stages or in their nested stages matches chaos type and severity.
+func hasStep(stages []Stage, chaosType, severity string) bool {
+	for _, stage := range stages {
+		for _, step := range stage.Steps {
+			if (chaosType == "" || step.Type == chaosType) &&
+				(severity == "" || step.Severity == severity) {
+				return true
+			}
+
+			if hasStep(step.Stages, chaosType, severity) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 func (e Workflow) Generate(rand *rand.Rand, size int) reflect.Value {
 	f := func(prefix string) string {
 		return fmt.Sprintf("%s-%d", prefix, rand.Intn(10))
@@ -171,7 +241,7 @@ func (n nodes) Generate(r *rand.Rand, _ int) reflect.Value {
 }
 
 func FromWorkflow(w v1alpha1.Workflow) (Workflow, bool) {
-	stages, ok := buildNodesTree(w.Spec.Templates, nodes(w.Status.Nodes))
+	stages, ok := buildNodesTree(workflowTemplates(w), nodes(w.Status.Nodes), w.Name)
 	if !ok {
 		return Workflow{}, false
 	}
@@ -193,7 +263,7 @@ func FromWorkflow(w v1alpha1.Workflow) (Workflow, bool) {
 }
 
 func FromWorkflowEvent(e *workflow.WorkflowWatchEvent) (Workflow, bool) {
-	stages, ok := buildNodesTree(e.Object.Spec.Templates, nodes(e.Object.Status.Nodes))
+	stages, ok := buildNodesTree(workflowTemplates(*e.Object), nodes(e.Object.Status.Nodes), e.Object.Name)
 	if !ok {
 		return Workflow{}, false
 	}
@@ -214,55 +284,138 @@ func FromWorkflowEvent(e *workflow.WorkflowWatchEvent) (Workflow, bool) {
 	}, true
 }
 
+// workflowTemplates returns templates of a workflow including ones stored from referenced workflow templates.
+func workflowTemplates(w v1alpha1.Workflow) []v1alpha1.Template {
+	ts := append([]v1alpha1.Template(nil), w.Spec.Templates...)
+	if w.Status.StoredWorkflowSpec != nil {
+		ts = append(ts, w.Status.StoredWorkflowSpec.Templates...)
+	}
+
+	keys := make([]string, 0, len(w.Status.StoredTemplates))
+	for k := range w.Status.StoredTemplates {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	for _, k := range keys {
+		ts = append(ts, w.Status.StoredTemplates[k])
+	}
+
+	return ts
+}
+
 // buildNodesTree parses spec and status to build a hierarchy of stages and steps.
-func buildNodesTree(ts []v1alpha1.Template, nodes nodes) ([]Stage, bool) {
-	stagesIDs, stepsIDs := splitStagesAndSteps(nodes)
+// Root is an ID of the workflow entrypoint node, which is equal to the workflow name.
+func buildNodesTree(ts []v1alpha1.Template, nodes nodes, root string) ([]Stage, bool) {
+	rootNode, ok := nodes[root]
+	if !ok {
+		return make([]Stage, 0), true
+	}
+
+	stages, ok := nestedStages(rootNode, ts, nodes)
+	if stages == nil {
+		stages = make([]Stage, 0)
+	}
+
+	return stages, ok
+}
+
+// nestedStages returns stages of a steps or DAG node. It returns nil for other nodes.
+func nestedStages(n v1alpha1.NodeStatus, ts []v1alpha1.Template, nodes nodes) ([]Stage, bool) {
+	switch n.Type {
+	case v1alpha1.NodeTypeSteps:
+		return buildStepsStages(n, ts, nodes)
+	case v1alpha1.NodeTypeDAG:
+		return buildDAGStages(n, ts, nodes)
+	default:
+		return nil, true
+	}
+}
+
+// buildStepsStages returns stages of a steps template. Each step group is a stage.
+func buildStepsStages(stepsNode v1alpha1.NodeStatus, ts []v1alpha1.Template, nodes nodes) ([]Stage, bool) {
+	groups := make(map[string]v1alpha1.NodeStatus)
+	for _, n := range nodes {
+		if n.Type == v1alpha1.NodeTypeStepGroup && n.BoundaryID == stepsNode.ID {
+			groups[n.DisplayName] = n
+		}
+	}
 
 	stages := make([]Stage, 0)
 
 	// For each stage.
-	for i := 0; i < len(stagesIDs); i++ {
-		id := fmt.Sprintf("[%d]", i)
-		stageStatus := stagesIDs[id]
+	for i := 0; i < len(groups); i++ {
+		group, ok := groups[fmt.Sprintf("[%d]", i)]
+		if !ok {
+			break
+		}
 
 		steps := make([]Step, 0)
 
 		// For each step.
-		for _, stepID := range stageStatus.Children {
-			stepStatus := stepsIDs[stepID]
-			stepSpec, ok := findStepSpec(stepStatus, ts)
+		for _, stepID := range group.Children {
+			stepStatus, ok := nodes[stepID]
+			if !ok {
+				continue
+			}
+
+			step, ok := buildStep(stepStatus, ts, nodes)
 			if !ok {
 				return nil, false
 			}
 
-			steps = append(steps, newStep(stepSpec.Metadata, stepStatus))
+			steps = append(steps, step)
 		}
 
-		stages = append(stages, newStage(stageStatus, steps))
+		stages = append(stages, newStage(group, steps))
 	}
 
 	return stages, true
 }
 
-// splitStagesAndSteps separates stages and steps into two maps.
-func splitStagesAndSteps(nodes nodes) (map[string]v1alpha1.NodeStatus, map[string]v1alpha1.NodeStatus) {
-	stagesID := make(map[string]v1alpha1.NodeStatus)
-	stepsID := make(map[string]v1alpha1.NodeStatus)
+// buildStep returns Step of a node with its retry attempts and stages of nested steps or DAG templates.
+func buildStep(n v1alpha1.NodeStatus, ts []v1alpha1.Template, nodes nodes) (Step, bool) {
+	stepSpec, _ := findStepSpec(n, ts)
+	step := newStep(stepSpec.Metadata, n)
 
-	for _, n := range nodes {
-		if n.Type == "StepGroup" {
-			stagesID[n.DisplayName] = n
-		} else {
-			stepsID[n.ID] = n
+	if n.Type == v1alpha1.NodeTypeRetry {
+		for i, id := range n.Children {
+			if attempt, ok := nodes[id]; ok {
+				step.Attempts = append(step.Attempts, newAttempt(i+1, attempt))
+			}
+		}
+
+		// Nested stages are shown for the last attempt.
+		if len(n.Children) != 0 {
+			if last, ok := nodes[n.Children[len(n.Children)-1]]; ok {
+				n = last
+			}
 		}
 	}
-	return stagesID, stepsID
+
+	stages, ok := nestedStages(n, ts, nodes)
+	if !ok {
+		return Step{}, false
+	}
+
+	step.Stages = stages
+	return step, true
+}
+
+// templateName returns name of a template used by a node.
+func templateName(n v1alpha1.NodeStatus) string {
+	if n.TemplateName == "" && n.TemplateRef != nil {
+		return n.TemplateRef.Template
+	}
+
+	return n.TemplateName
 }
 
 // findStepSpec returns Step's spec given its v1alpha1.NodeStatus.
 func findStepSpec(stepStatus v1alpha1.NodeStatus, ts []v1alpha1.Template) (v1alpha1.Template, bool) {
+	name := templateName(stepStatus)
 	for _, t := range ts {
-		if t.Name == stepStatus.TemplateName {
+		if t.Name == name {
 			return t, true
 		}
 	}
@@ -278,12 +431,35 @@ func newStep(metadata v1alpha1.Metadata, n v1alpha1.NodeStatus) Step {
 	}
 
 	return Step{
-		Name:       n.TemplateName,
-		Type:       metadata.Annotations[typeKey],
-		Severity:   metadata.Annotations[severityKey],
-		Scale:      metadata.Annotations[scaleKey],
-		Version:    metadata.Annotations[versionKey],
+		Name:         templateName(n),
+		DisplayName:  n.DisplayName,
+		Type:         metadata.Annotations[typeKey],
+		Severity:     metadata.Annotations[severityKey],
+		Scale:        metadata.Annotations[scaleKey],
+		Version:      metadata.Annotations[versionKey],
+		Status:       strings.ToLower(string(n.Phase)),
+		StartedAt:    n.StartedAt.Time,
+		FinishedAt:   finishedAt,
+		Message:      n.Message,
+		PodName:      podName(n),
+		HostNodeName: n.HostNodeName,
+		ExitCode:     exitCode(n.Outputs),
+		Outputs:      newOutputs(n.Outputs),
+	}
+}
+
+// newAttempt returns new Attempt from its number and v1alpha1.NodeStatus.
+func newAttempt(number int, n v1alpha1.NodeStatus) Attempt {
+	finishedAt := &n.FinishedAt.Time
+	if *finishedAt == (time.Time{}) {
+		finishedAt = nil
+	}
+
+	return Attempt{
+		Number:     number,
 		Status:     strings.ToLower(string(n.Phase)),
+		Message:    n.Message,
+		PodName:    podName(n),
 		StartedAt:  n.StartedAt.Time,
 		FinishedAt: finishedAt,
 	}
@@ -298,6 +474,7 @@ func newStage(n v1alpha1.NodeStatus, steps []Step) Stage {
 
 	return Stage{
 		Status:     strings.ToLower(string(n.Phase)),
+		Message:    n.Message,
 		StartedAt:  n.StartedAt.Time,
 		FinishedAt: finishedAt,
 		Steps:      steps,
@@ -2,12 +2,15 @@ package event
 
 import (
 	"errors"
+	"fmt"
 	"math/rand"
+	"reflect"
 	"testing"
 	"testing/quick"
 	"time"
 
 	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func validateTime(start time.Time, finish *time.Time) error {
@@ -22,47 +25,403 @@ func validateTime(start time.Time, finish *time.Time) error {
 	return nil
 }
 
+// nodesTree is a steps or DAG workflow node tree rooted in "wf" node.
+type nodesTree struct {
+	templates []v1alpha1.Template
+	nodes     nodes
+	// steps is a number of steps in stages of the root node.
+	steps int
+}
+
+func (nodesTree) Generate(r *rand.Rand, _ int) reflect.Value {
+	nodePhases := []v1alpha1.NodePhase{v1alpha1.NodeSucceeded, v1alpha1.NodeFailed, v1alpha1.NodeError}
+	start := time.Now().Add(-time.Hour)
+
+	node := func(id, name, displayName, template string, nodeType v1alpha1.NodeType, children ...string) v1alpha1.NodeStatus {
+		startedAt := start.Add(time.Duration(r.Intn(30)) * time.Minute)
+		boundaryID := "wf"
+		if id == "wf" {
+			boundaryID = ""
+		}
+
+		return v1alpha1.NodeStatus{
+			ID:           id,
+			Name:         name,
+			DisplayName:  displayName,
+			Type:         nodeType,
+			TemplateName: template,
+			Phase:        nodePhases[r.Intn(len(nodePhases))],
+			BoundaryID:   boundaryID,
+			Children:     children,
+			StartedAt:    v1.Time{Time: startedAt},
+			FinishedAt:   v1.Time{Time: startedAt.Add(time.Duration(1+r.Intn(30)) * time.Minute)},
+		}
+	}
+
+	tree := nodesTree{
+		templates: []v1alpha1.Template{
+			{Name: "main"},
+			{Name: "pod-delete", Metadata: v1alpha1.Metadata{Annotations: map[string]string{typeKey: "pod-delete"}}},
+		},
+		nodes: make(nodes),
+	}
+
+	if r.Intn(2) == 0 {
+		// Step groups are children of the previous group steps, and the first one is a child of the root.
+		groups := 1 + r.Intn(5)
+		tree.nodes["wf"] = node("wf", "wf", "wf", "main", v1alpha1.NodeTypeSteps, "wf-0")
+
+		for i := 0; i < groups; i++ {
+			var next []string
+			if i+1 < groups {
+				next = []string{fmt.Sprintf("wf-%d", i+1)}
+			}
+
+			var steps []string
+			for j := 0; j <= r.Intn(3); j++ {
+				id := fmt.Sprintf("wf-%d-%d", i, j)
+				tree.nodes[id] = node(id, fmt.Sprintf("wf[%d].s%d", i, j), fmt.Sprintf("s%d", j), "pod-delete", v1alpha1.NodeTypePod, next...)
+				steps = append(steps, id)
+				tree.steps++
+			}
+
+			id := fmt.Sprintf("wf-%d", i)
+			tree.nodes[id] = node(id, fmt.Sprintf("wf[%d]", i), fmt.Sprintf("[%d]", i), "", v1alpha1.NodeTypeStepGroup, steps...)
+		}
+
+		return reflect.ValueOf(tree)
+	}
+
+	// Tasks depend only on previous tasks, so the graph has no cycles. Tasks without dependencies are children of the root.
+	tasks := 1 + r.Intn(10)
+	children := make(map[int][]string)
+	var roots []string
+	for i := 0; i < tasks; i++ {
+		id := fmt.Sprintf("wf-t%d", i)
+
+		dependent := false
+		for j := 0; j < i; j++ {
+			if r.Intn(3) == 0 {
+				children[j] = append(children[j], id)
+				dependent = true
+			}
+		}
+
+		if !dependent {
+			roots = append(roots, id)
+		}
+	}
+
+	for i := 0; i < tasks; i++ {
+		id := fmt.Sprintf("wf-t%d", i)
+		tree.nodes[id] = node(id, fmt.Sprintf("wf.t%d", i), fmt.Sprintf("t%d", i), "pod-delete", v1alpha1.NodeTypePod, children[i]...)
+		tree.steps++
+	}
+
+	tree.nodes["wf"] = node("wf", "wf", "wf", "main", v1alpha1.NodeTypeDAG, roots...)
+	return reflect.ValueOf(tree)
+}
+
 func Test_buildNodesTree(t *testing.T) {
 	t.Parallel()
 
 	r := rand.New(rand.NewSource(0))
-	f := func(ts []v1alpha1.Template, nodes nodes) bool {
-		stages, ok := buildNodesTree(ts, nodes)
-		if !ok {
-			t.Log("couldn't find step spec")
+	f := func(tree nodesTree) bool {
+		if !validStages(t, tree.templates, tree.nodes, "wf") {
 			return false
 		}
 
+		stages, _ := buildNodesTree(tree.templates, tree.nodes, "wf")
+
+		steps := 0
 		for _, stage := range stages {
-			if stage.Status == "" {
-				t.Log("stage phase must not be empty")
-				return false
+			steps += len(stage.Steps)
+		}
+
+		if steps != tree.steps {
+			t.Logf("stages contain %d steps, want %d", steps, tree.steps)
+			return false
+		}
+
+		return true
+	}
+
+	if err := quick.Check(f, &quick.Config{Rand: r}); err != nil {
+		t.Error(err)
+	}
+}
+
+// stepDependencies returns dependencies of steps in stages and nested stages by step display names.
+func stepDependencies(stages []Stage) map[string][]string {
+	dependencies := make(map[string][]string)
+	for _, stage := range stages {
+		for _, step := range stage.Steps {
+			if step.Dependencies != nil {
+				dependencies[step.DisplayName] = step.Dependencies
 			}
 
-			if err := validateTime(stage.StartedAt, stage.FinishedAt); err != nil {
-				t.Log(err)
-				return false
+			for name, deps := range stepDependencies(step.Stages) {
+				dependencies[name] = deps
 			}
+		}
+	}
 
-			for _, step := range stage.Steps {
-				if err := validateTime(step.StartedAt, step.FinishedAt); err != nil {
-					t.Log(err)
-					return false
-				}
+	return dependencies
+}
+
+// Test_buildNodesTree_graphs tests stages of steps and DAG workflows with dependencies from spec and status.
+func Test_buildNodesTree_graphs(t *testing.T) {
+	t.Parallel()
+
+	template := func(name string, dag ...v1alpha1.DAGTask) v1alpha1.Template {
+		tmpl := v1alpha1.Template{
+			Name:     name,
+			Metadata: v1alpha1.Metadata{Annotations: map[string]string{typeKey: name}},
+		}
+		if len(dag) != 0 {
+			tmpl.DAG = &v1alpha1.DAGTemplate{Tasks: dag}
+		}
 
-				if step.Name == "" ||
-					step.Status == "" ||
-					step.Type == "" {
-					t.Log("step name, phase and type must not be empty")
-					return false
+		return tmpl
+	}
+	withType := func(n v1alpha1.NodeStatus, nodeType v1alpha1.NodeType) v1alpha1.NodeStatus {
+		n.Type = nodeType
+		return n
+	}
+	dagRoot := withType(testNode("wf", "wf", "wf", "", "main", v1alpha1.NodeSucceeded, "wf-a"), v1alpha1.NodeTypeDAG)
+	// task returns node of a DAG task in workflow root.
+	task := func(name string, children ...string) v1alpha1.NodeStatus {
+		return testNode("wf-"+name, "wf."+name, name, "wf", "pod-delete", v1alpha1.NodeSucceeded, children...)
+	}
+
+	tests := []struct {
+		name             string
+		ts               []v1alpha1.Template
+		nodes            nodes
+		wantStages       [][]string
+		wantDependencies map[string][]string
+		// wantNested are stages of the first step.
+		wantNested [][]string
+	}{
+		{
+			name: "steps with parallel steps",
+			ts:   []v1alpha1.Template{template("main"), template("pod-delete")},
+			nodes: nodes{
+				"wf":   withType(testNode("wf", "wf", "wf", "", "main", v1alpha1.NodeSucceeded, "wf-0"), v1alpha1.NodeTypeSteps),
+				"wf-0": withType(testNode("wf-0", "wf[0]", "[0]", "wf", "", v1alpha1.NodeSucceeded, "wf-a"), v1alpha1.NodeTypeStepGroup),
+				"wf-a": testNode("wf-a", "wf[0].a", "a", "wf", "pod-delete", v1alpha1.NodeSucceeded, "wf-1"),
+				"wf-1": withType(testNode("wf-1", "wf[1]", "[1]", "wf", "", v1alpha1.NodeSucceeded, "wf-b", "wf-c"), v1alpha1.NodeTypeStepGroup),
+				"wf-b": testNode("wf-b", "wf[1].b", "b", "wf", "pod-delete", v1alpha1.NodeSucceeded),
+				"wf-c": testNode("wf-c", "wf[1].c", "c", "wf", "pod-delete", v1alpha1.NodeSucceeded),
+			},
+			wantStages:       [][]string{{"a"}, {"b", "c"}},
+			wantDependencies: map[string][]string{},
+		},
+		{
+			name: "dag with depends expressions",
+			ts: []v1alpha1.Template{
+				template("main",
+					v1alpha1.DAGTask{Name: "a", Template: "pod-delete"},
+					v1alpha1.DAGTask{Name: "b", Template: "pod-delete", Depends: "a.Succeeded"},
+					v1alpha1.DAGTask{Name: "c", Template: "pod-delete", Depends: "!a.Failed && (b.Succeeded || b.Skipped)"},
+					v1alpha1.DAGTask{Name: "d", Template: "pod-delete", Dependencies: []string{"c"}, Depends: "a.AnySucceeded"},
+				),
+				template("pod-delete"),
+			},
+			nodes: nodes{
+				"wf":   dagRoot,
+				"wf-a": task("a"),
+				"wf-b": task("b"),
+				"wf-c": task("c"),
+				"wf-d": task("d"),
+			},
+			wantStages:       [][]string{{"a"}, {"b"}, {"c"}, {"d"}},
+			wantDependencies: map[string][]string{"b": {"a"}, "c": {"a", "b"}, "d": {"a", "c"}},
+		},
+		{
+			name: "dag diamond from status",
+			ts:   []v1alpha1.Template{template("pod-delete")},
+			nodes: nodes{
+				"wf":   dagRoot,
+				"wf-a": task("a", "wf-b", "wf-c"),
+				"wf-b": task("b", "wf-d"),
+				"wf-c": task("c", "wf-d"),
+				"wf-d": task("d"),
+			},
+			wantStages:       [][]string{{"a"}, {"b", "c"}, {"d"}},
+			wantDependencies: map[string][]string{"b": {"a"}, "c": {"a"}, "d": {"b", "c"}},
+		},
+		{
+			name: "dag with expanded task from status",
+			ts:   []v1alpha1.Template{template("pod-delete")},
+			nodes: nodes{
+				"wf":      dagRoot,
+				"wf-a":    task("a", "wf-b"),
+				"wf-b":    withType(task("b", "wf-b(0)", "wf-b(1)"), v1alpha1.NodeTypeTaskGroup),
+				"wf-b(0)": testNode("wf-b(0)", "wf.b(0:x)", "b(0:x)", "wf", "pod-delete", v1alpha1.NodeSucceeded, "wf-c"),
+				"wf-b(1)": testNode("wf-b(1)", "wf.b(1:y)", "b(1:y)", "wf", "pod-delete", v1alpha1.NodeSucceeded, "wf-c"),
+				"wf-c":    task("c"),
+			},
+			wantStages:       [][]string{{"a"}, {"b"}, {"c"}},
+			wantDependencies: map[string][]string{"b": {"a"}, "c": {"b"}},
+		},
+		{
+			name: "dag with retried task from status",
+			ts:   []v1alpha1.Template{template("pod-delete")},
+			nodes: nodes{
+				"wf":      dagRoot,
+				"wf-a":    withType(task("a", "wf-a(0)", "wf-a(1)"), v1alpha1.NodeTypeRetry),
+				"wf-a(0)": testNode("wf-a(0)", "wf.a(0)", "a(0)", "wf", "pod-delete", v1alpha1.NodeFailed),
+				"wf-a(1)": testNode("wf-a(1)", "wf.a(1)", "a(1)", "wf", "pod-delete", v1alpha1.NodeSucceeded, "wf-b"),
+				"wf-b":    task("b"),
+			},
+			wantStages:       [][]string{{"a"}, {"b"}},
+			wantDependencies: map[string][]string{"b": {"a"}},
+		},
+		{
+			name: "steps with nested dag",
+			ts: []v1alpha1.Template{
+				template("main"),
+				template("inner",
+					v1alpha1.DAGTask{Name: "x", Template: "pod-delete"},
+					v1alpha1.DAGTask{Name: "y", Template: "pod-delete", Depends: "x.Succeeded || x.Failed"},
+				),
+				template("pod-delete"),
+			},
+			nodes: nodes{
+				"wf":         withType(testNode("wf", "wf", "wf", "", "main", v1alpha1.NodeSucceeded, "wf-0"), v1alpha1.NodeTypeSteps),
+				"wf-0":       withType(testNode("wf-0", "wf[0]", "[0]", "wf", "", v1alpha1.NodeSucceeded, "wf-inner"), v1alpha1.NodeTypeStepGroup),
+				"wf-inner":   withType(testNode("wf-inner", "wf[0].inner", "inner", "wf", "inner", v1alpha1.NodeSucceeded, "wf-inner-x"), v1alpha1.NodeTypeDAG),
+				"wf-inner-x": testNode("wf-inner-x", "wf[0].inner.x", "x", "wf-inner", "pod-delete", v1alpha1.NodeSucceeded, "wf-inner-y"),
+				"wf-inner-y": testNode("wf-inner-y", "wf[0].inner.y", "y", "wf-inner", "pod-delete", v1alpha1.NodeSucceeded),
+			},
+			wantStages:       [][]string{{"inner"}},
+			wantDependencies: map[string][]string{"y": {"x"}},
+			wantNested:       [][]string{{"x"}, {"y"}},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if !validStages(t, tt.ts, tt.nodes, "wf") {
+				t.Fatal("invalid stages")
+			}
+
+			stages, _ := buildNodesTree(tt.ts, tt.nodes, "wf")
+
+			if got := stageNames(stages); !reflect.DeepEqual(got, tt.wantStages) {
+				t.Errorf("stages = %v, want %v", got, tt.wantStages)
+			}
+
+			if got := stepDependencies(stages); !reflect.DeepEqual(got, tt.wantDependencies) {
+				t.Errorf("dependencies = %v, want %v", got, tt.wantDependencies)
+			}
+
+			if tt.wantNested != nil {
+				if got := stageNames(stages[0].Steps[0].Stages); !reflect.DeepEqual(got, tt.wantNested) {
+					t.Errorf("nested stages = %v, want %v", got, tt.wantNested)
 				}
 			}
+		})
+	}
+}
+
+// validStages returns whether stages built from nodes have valid timings, statuses and names.
+func validStages(t *testing.T, ts []v1alpha1.Template, nodes nodes, root string) bool {
+	t.Helper()
+
+	stages, ok := buildNodesTree(ts, nodes, root)
+	if !ok {
+		t.Log("couldn't build nodes tree")
+		return false
+	}
+
+	for _, stage := range stages {
+		if stage.Status == "" {
+			t.Log("stage phase must not be empty")
+			return false
 		}
 
-		return true
+		if err := validateTime(stage.StartedAt, stage.FinishedAt); err != nil {
+			t.Log(err)
+			return false
+		}
+
+		for _, step := range stage.Steps {
+			if err := validateTime(step.StartedAt, step.FinishedAt); err != nil {
+				t.Log(err)
+				return false
+			}
+
+			if step.Name == "" ||
+				step.Status == "" ||
+				step.Type == "" {
+				t.Log("step name, phase and type must not be empty")
+				return false
+			}
+		}
 	}
 
-	if err := quick.Check(f, &quick.Config{Rand: r}); err != nil {
-		t.Error(err)
+	return true
+}
+
+// Test_buildStep tests nested stages and retry attempts of steps.
+func Test_buildStep(t *testing.T) {
+	t.Parallel()
+
+	retry := testNode("wf-kill", "wf[0].kill", "kill", "wf", "pod-delete", v1alpha1.NodeSucceeded, "wf-kill-0", "wf-kill-1", "wf-kill-2")
+	retry.Type = v1alpha1.NodeTypeRetry
+
+	failed := func(id, name string) v1alpha1.NodeStatus {
+		n := testNode(id, name, name, "wf", "pod-delete", v1alpha1.NodeFailed)
+		n.Message = "pod deleted too early"
+		return n
+	}
+
+	nested := testNode("wf-nested", "wf[0].nested", "nested", "wf", "nested", v1alpha1.NodeSucceeded)
+	nested.Type = v1alpha1.NodeTypeSteps
+
+	group := testNode("wf-nested-0", "wf[0].nested[0]", "[0]", "wf-nested", "", v1alpha1.NodeSucceeded, "wf-nested-check")
+	group.Type = v1alpha1.NodeTypeStepGroup
+
+	nodes := nodes{
+		"wf-kill":         retry,
+		"wf-kill-0":       failed("wf-kill-0", "kill(0)"),
+		"wf-kill-1":       failed("wf-kill-1", "kill(1)"),
+		"wf-kill-2":       testNode("wf-kill-2", "kill(2)", "kill(2)", "wf", "pod-delete", v1alpha1.NodeSucceeded),
+		"wf-nested":       nested,
+		"wf-nested-0":     group,
+		"wf-nested-check": testNode("wf-nested-check", "wf[0].nested[0].check", "check", "wf-nested", "check", v1alpha1.NodeSucceeded),
+	}
+
+	step, ok := buildStep(retry, nil, nodes)
+	if !ok {
+		t.Fatal("couldn't build step")
+	}
+
+	if len(step.Attempts) != 3 || step.Status != "succeeded" {
+		t.Fatalf("unexpected step: %+v", step)
+	}
+
+	for i, attempt := range step.Attempts {
+		if attempt.Number != i+1 {
+			t.Errorf("attempt %d has number %d", i, attempt.Number)
+		}
+	}
+
+	if step.Attempts[0].Status != "failed" || step.Attempts[0].Message != "pod deleted too early" ||
+		step.Attempts[2].Status != "succeeded" || step.Attempts[2].FinishedAt == nil {
+		t.Errorf("unexpected attempts: %+v", step.Attempts)
+	}
+
+	step, ok = buildStep(nested, nil, nodes)
+	if !ok {
+		t.Fatal("couldn't build step")
+	}
+
+	if got := stageNames(step.Stages); !reflect.DeepEqual(got, [][]string{{"check"}}) {
+		t.Errorf("nested stages = %v, want [[check]]", got)
 	}
 }
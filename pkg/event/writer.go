@@ -12,6 +12,17 @@ type Writer interface {
 	Close() error
 }
 
+// ShutdownNotifier is implemented by writers able to tell clients why the stream was ended.
+type ShutdownNotifier interface {
+	Shutdown(ctx context.Context, reason string) error
+}
+
+// DisconnectNotifier is implemented by writers able to detect that client went away.
+type DisconnectNotifier interface {
+	// Done returns channel closed when client disconnects.
+	Done() <-chan struct{}
+}
+
 // TestWriter mocks Writer interface.
 type TestWriter struct {
 	Events   []Workflow
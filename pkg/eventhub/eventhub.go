@@ -0,0 +1,284 @@
+// Package eventhub shares workflow event streams between many readers.
+package eventhub
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+	"go.uber.org/zap"
+)
+
+// bufferSize is a number of events kept for a slow subscriber before the oldest ones are dropped.
+// Each event holds a full workflow state, so skipping intermediate ones is safe.
+const bufferSize = 16
+
+// ReaderFactory creates workflow events readers.
+type ReaderFactory interface {
+	New(ctx context.Context, namespace, name string) (event.Reader, error)
+}
+
+// Hub keeps one upstream reader per workflow and broadcasts its events to all subscribers.
+// Late subscribers receive the latest workflow state first.
+// Upstream reader is closed when the last subscriber leaves.
+type Hub struct {
+	factory ReaderFactory
+	// partition separates subscribers that must not share upstream, e.g. users with different tokens.
+	partition func(ctx context.Context) string
+	logger    *zap.SugaredLogger
+
+	m      *sync.Mutex
+	topics map[string]*topic
+}
+
+// topic is a single upstream reader with its subscribers.
+type topic struct {
+	key string
+
+	// ready is closed when upstream reader was created or failed to be created.
+	ready  chan struct{}
+	err    error
+	reader event.Reader
+	cancel context.CancelFunc
+
+	subs   map[*subscription]struct{}
+	latest *event.Workflow
+	done   bool
+}
+
+// item is a result of reading from upstream.
+type item struct {
+	ev  event.Workflow
+	err error
+}
+
+func NewHub(factory ReaderFactory, partition func(ctx context.Context) string, logger *zap.SugaredLogger) Hub {
+	return Hub{
+		factory:   factory,
+		partition: partition,
+		logger:    logger,
+		m:         &sync.Mutex{},
+		topics:    make(map[string]*topic),
+	}
+}
+
+func (h Hub) New(ctx context.Context, namespace, name string) (event.Reader, error) {
+	key := fmt.Sprintf("%s/%s", namespace, name)
+	if h.partition != nil {
+		key = fmt.Sprintf("%s/%s", key, h.partition(ctx))
+	}
+
+	for {
+		h.m.Lock()
+		t, ok := h.topics[key]
+		if !ok {
+			t = &topic{
+				key:   key,
+				ready: make(chan struct{}),
+				subs:  make(map[*subscription]struct{}),
+			}
+			h.topics[key] = t
+		}
+		h.m.Unlock()
+
+		if !ok {
+			h.open(ctx, t, namespace, name)
+		}
+
+		select {
+		case <-t.ready:
+		case <-ctx.Done():
+			return nil, event.ErrDeadlineExceeded
+		}
+
+		if t.err != nil {
+			return nil, t.err
+		}
+
+		if ctx.Err() != nil {
+			h.release(t)
+			return nil, event.ErrDeadlineExceeded
+		}
+
+		h.m.Lock()
+		if t.done {
+			// Upstream finished before we subscribed, so start a new one.
+			h.m.Unlock()
+			continue
+		}
+
+		s := &subscription{
+			hub:   h,
+			topic: t,
+			ctx:   ctx,
+			ch:    make(chan item, bufferSize),
+			once:  &sync.Once{},
+		}
+		if t.latest != nil {
+			s.push(item{ev: *t.latest})
+		}
+		t.subs[s] = struct{}{}
+		h.m.Unlock()
+
+		h.logger.Debugw("subscribed to workflow events", "key", key, "subscribers", len(t.subs))
+		return s, nil
+	}
+}
+
+// open creates upstream reader for topic and starts broadcasting its events.
+func (h Hub) open(ctx context.Context, t *topic, namespace, name string) {
+	defer close(t.ready)
+
+	// Upstream must outlive the subscriber who created it, but keep values like auth tokens.
+	upstreamCtx, cancel := context.WithCancel(detached{parent: ctx})
+	reader, err := h.factory.New(upstreamCtx, namespace, name)
+
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	if err != nil {
+		cancel()
+		t.err, t.done = err, true
+		h.remove(t)
+		return
+	}
+
+	t.reader, t.cancel = reader, cancel
+	h.logger.Debugw("upstream reader opened", "key", t.key)
+	go h.broadcast(t)
+}
+
+// broadcast reads events from upstream and passes them to all subscribers.
+func (h Hub) broadcast(t *topic) {
+	for {
+		ev, err := t.reader.Read()
+
+		h.m.Lock()
+		if err == nil || err == event.ErrAllRead {
+			t.latest = &ev
+		}
+
+		for s := range t.subs {
+			s.push(item{ev: ev, err: err})
+		}
+
+		if err == nil {
+			h.m.Unlock()
+			continue
+		}
+
+		t.done = true
+		h.remove(t)
+		h.m.Unlock()
+
+		t.cancel()
+		if err := t.reader.Close(); err != nil {
+			h.logger.Error(err)
+		}
+
+		h.logger.Debugw("upstream reader closed", "key", t.key, "reason", err)
+		return
+	}
+}
+
+// release closes upstream reader if topic has no subscribers.
+func (h Hub) release(t *topic) {
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	if len(t.subs) != 0 || t.done || t.cancel == nil {
+		return
+	}
+
+	t.done = true
+	h.remove(t)
+	t.cancel()
+}
+
+// remove deletes topic from the hub. Must be called with lock held.
+func (h Hub) remove(t *topic) {
+	if h.topics[t.key] == t {
+		delete(h.topics, t.key)
+	}
+}
+
+// Close stops all upstream readers.
+func (h Hub) Close() error {
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	for _, t := range h.topics {
+		if t.cancel != nil {
+			t.cancel()
+		}
+	}
+
+	return nil
+}
+
+// subscription reads events broadcast by topic.
+type subscription struct {
+	hub   Hub
+	topic *topic
+	ctx   context.Context
+	ch    chan item
+	once  *sync.Once
+}
+
+// push adds item to subscription buffer, dropping the oldest item if the buffer is full.
+// Must be called with hub lock held.
+func (s *subscription) push(it item) {
+	select {
+	case s.ch <- it:
+	default:
+		select {
+		case <-s.ch:
+		default:
+		}
+		s.ch <- it
+	}
+}
+
+func (s *subscription) Read() (event.Workflow, error) {
+	select {
+	case it := <-s.ch:
+		return it.ev, it.err
+	case <-s.ctx.Done():
+		return event.Workflow{}, event.ErrDeadlineExceeded
+	}
+}
+
+func (s *subscription) Close() error {
+	s.once.Do(func() {
+		s.hub.m.Lock()
+		delete(s.topic.subs, s)
+		s.hub.m.Unlock()
+
+		s.hub.release(s.topic)
+	})
+
+	return nil
+}
+
+// detached keeps values of the parent context but is never cancelled.
+type detached struct {
+	parent context.Context
+}
+
+func (d detached) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (d detached) Done() <-chan struct{} {
+	return nil
+}
+
+func (d detached) Err() error {
+	return nil
+}
+
+func (d detached) Value(key interface{}) interface{} {
+	return d.parent.Value(key)
+}
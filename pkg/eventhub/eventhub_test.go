@@ -0,0 +1,169 @@
+package eventhub
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+	"go.uber.org/zap"
+)
+
+// testUpstream is a reader producing events sent to its channel.
+type testUpstream struct {
+	ctx    context.Context
+	events chan event.Workflow
+	closed chan struct{}
+}
+
+func (t *testUpstream) Read() (event.Workflow, error) {
+	select {
+	case ev := <-t.events:
+		if ev.Status == "succeeded" {
+			return ev, event.ErrAllRead
+		}
+
+		return ev, nil
+	case <-t.ctx.Done():
+		return event.Workflow{}, event.ErrDeadlineExceeded
+	}
+}
+
+func (t *testUpstream) Close() error {
+	close(t.closed)
+	return nil
+}
+
+// testFactory counts created upstream readers.
+type testFactory struct {
+	m         sync.Mutex
+	upstreams []*testUpstream
+}
+
+func (t *testFactory) New(ctx context.Context, _, _ string) (event.Reader, error) {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	u := &testUpstream{ctx: ctx, events: make(chan event.Workflow), closed: make(chan struct{})}
+	t.upstreams = append(t.upstreams, u)
+	return u, nil
+}
+
+func (t *testFactory) count() int {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	return len(t.upstreams)
+}
+
+func readStatus(t *testing.T, r event.Reader, want string, wantErr error) {
+	t.Helper()
+
+	ev, err := r.Read()
+	if err != wantErr {
+		t.Fatalf("Read() error = %v, want %v", err, wantErr)
+	}
+
+	if ev.Status != want {
+		t.Fatalf("Read() status = %s, want %s", ev.Status, want)
+	}
+}
+
+// TestHub tests sharing of upstream readers between subscribers.
+func TestHub(t *testing.T) {
+	t.Parallel()
+
+	factory := &testFactory{}
+	hub := NewHub(factory, nil, zap.NewNop().Sugar())
+	ctx := context.Background()
+
+	first, err := hub.New(ctx, "namespace", "name")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := hub.New(ctx, "namespace", "name")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if factory.count() != 1 {
+		t.Fatalf("subscribers of the same workflow used %d upstream readers", factory.count())
+	}
+
+	upstream := factory.upstreams[0]
+	upstream.events <- event.Workflow{Status: "pending"}
+	readStatus(t, first, "pending", nil)
+	readStatus(t, second, "pending", nil)
+
+	upstream.events <- event.Workflow{Status: "running"}
+	readStatus(t, first, "running", nil)
+
+	// Late joiner receives the latest state first.
+	late, err := hub.New(ctx, "namespace", "name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	readStatus(t, late, "running", nil)
+
+	upstream.events <- event.Workflow{Status: "succeeded"}
+	readStatus(t, first, "succeeded", event.ErrAllRead)
+	readStatus(t, second, "running", nil)
+	readStatus(t, second, "succeeded", event.ErrAllRead)
+	readStatus(t, late, "succeeded", event.ErrAllRead)
+
+	select {
+	case <-upstream.closed:
+	case <-time.After(time.Second):
+		t.Fatal("upstream wasn't closed after the workflow finished")
+	}
+
+	// Finished workflow gets a new upstream.
+	another, err := hub.New(ctx, "namespace", "name")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if factory.count() != 2 {
+		t.Fatal("finished upstream was reused")
+	}
+
+	// Upstream is torn down when the last subscriber leaves.
+	_ = another.Close()
+	select {
+	case <-factory.upstreams[1].closed:
+	case <-time.After(time.Second):
+		t.Fatal("upstream wasn't closed after the last subscriber left")
+	}
+
+	for _, r := range []event.Reader{first, second, late} {
+		_ = r.Close()
+	}
+}
+
+// TestHub_partition tests that partitions don't share upstream readers.
+func TestHub_partition(t *testing.T) {
+	t.Parallel()
+
+	type tokenKey struct{}
+
+	factory := &testFactory{}
+	hub := NewHub(factory, func(ctx context.Context) string {
+		token, _ := ctx.Value(tokenKey{}).(string)
+		return token
+	}, zap.NewNop().Sugar())
+
+	for _, token := range []string{"alice", "bob", "alice"} {
+		ctx := context.WithValue(context.Background(), tokenKey{}, token)
+		r, err := hub.New(ctx, "namespace", "name")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+	}
+
+	if factory.count() != 2 {
+		t.Fatalf("created %d upstream readers for 2 partitions", factory.count())
+	}
+}
@@ -0,0 +1,237 @@
+// Package eventrec records workflow event streams to JSONL files and replays them.
+package eventrec
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrInvalidID = errors.New("invalid recording id")
+	ErrNotFound  = errors.New("recording not found")
+)
+
+// extension is an extension of recording files.
+const extension = ".jsonl"
+
+// validID matches recording IDs which are safe to use as file names.
+var validID = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// Entry is a single line of a recording.
+type Entry struct {
+	// Offset is a time passed since the stream start.
+	Offset time.Duration  `json:"offset"`
+	Event  event.Workflow `json:"event"`
+}
+
+// recordingWriter passes events to writer and appends them to a recording.
+type recordingWriter struct {
+	writer  event.Writer
+	file    io.WriteCloser
+	encoder *json.Encoder
+	start   time.Time
+	logger  *zap.SugaredLogger
+}
+
+// NewWriter returns writer passing events to writer and recording them to file.
+// Recording errors are only logged, so they don't break the stream. File is closed with the returned writer.
+func NewWriter(writer event.Writer, file io.WriteCloser, logger *zap.SugaredLogger) event.Writer {
+	return recordingWriter{
+		writer:  writer,
+		file:    file,
+		encoder: json.NewEncoder(file),
+		start:   time.Now(),
+		logger:  logger,
+	}
+}
+
+func (w recordingWriter) Write(ctx context.Context, ev event.Workflow) error {
+	offset := time.Since(w.start)
+
+	if err := w.writer.Write(ctx, ev); err != nil {
+		return err
+	}
+
+	if err := w.encoder.Encode(Entry{Offset: offset, Event: ev}); err != nil {
+		w.logger.Infof("error recording event: %v", err)
+	}
+
+	return nil
+}
+
+// Shutdown tells client that the stream was ended by server shutdown if the wrapped writer supports it.
+func (w recordingWriter) Shutdown(ctx context.Context, reason string) error {
+	if notifier, ok := w.writer.(event.ShutdownNotifier); ok {
+		return notifier.Shutdown(ctx, reason)
+	}
+
+	return nil
+}
+
+// Done returns channel closed when client disconnects if the wrapped writer supports it, and nil channel otherwise.
+func (w recordingWriter) Done() <-chan struct{} {
+	if notifier, ok := w.writer.(event.DisconnectNotifier); ok {
+		return notifier.Done()
+	}
+
+	return nil
+}
+
+func (w recordingWriter) Close() error {
+	if err := w.file.Close(); err != nil {
+		w.logger.Infof("error closing recording: %v", err)
+	}
+
+	return w.writer.Close()
+}
+
+// replayReader reads events from a recording keeping intervals between them.
+type replayReader struct {
+	ctx     context.Context
+	file    io.ReadCloser
+	decoder *json.Decoder
+	speed   float64
+	logger  *zap.SugaredLogger
+
+	start time.Time
+	next  *Entry
+}
+
+// NewReader returns reader replaying recording from file. Intervals between events are divided by speed,
+// and events are returned without delays if speed is zero. File is closed with the returned reader.
+func NewReader(ctx context.Context, file io.ReadCloser, speed float64, logger *zap.SugaredLogger) event.Reader {
+	return &replayReader{
+		ctx:     ctx,
+		file:    file,
+		decoder: json.NewDecoder(file),
+		speed:   speed,
+		logger:  logger,
+	}
+}
+
+func (r *replayReader) Read() (event.Workflow, error) {
+	if r.start.IsZero() {
+		r.start = time.Now()
+
+		next, err := r.decode()
+		if err == io.EOF {
+			r.logger.Info("recording is empty")
+			return event.Workflow{}, event.ErrInvalidEvent
+		} else if err != nil {
+			return event.Workflow{}, err
+		}
+
+		r.next = next
+	}
+
+	current := r.next
+	if current == nil {
+		return event.Workflow{}, event.ErrAllRead
+	}
+
+	next, err := r.decode()
+	if err != nil && err != io.EOF {
+		return event.Workflow{}, err
+	}
+
+	r.next = next
+
+	if r.speed > 0 {
+		delay := time.Until(r.start.Add(time.Duration(float64(current.Offset) / r.speed)))
+
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		select {
+		case <-r.ctx.Done():
+			return event.Workflow{}, event.ErrDeadlineExceeded
+		case <-timer.C:
+		}
+	}
+
+	if r.next == nil {
+		return current.Event, event.ErrAllRead
+	}
+
+	return current.Event, nil
+}
+
+// decode returns the next entry of recording or io.EOF if there are no more entries.
+func (r *replayReader) decode() (*Entry, error) {
+	var e Entry
+	if err := r.decoder.Decode(&e); err == io.EOF {
+		return nil, io.EOF
+	} else if err != nil {
+		r.logger.Error(err.Error())
+		return nil, event.ErrInvalidEvent
+	}
+
+	return &e, nil
+}
+
+func (r *replayReader) Close() error {
+	return r.file.Close()
+}
+
+// Dir keeps recordings in a directory. Recording ID is a file name without extension.
+type Dir struct {
+	path string
+}
+
+// NewDir returns Dir at path, creating directory if it doesn't exist.
+func NewDir(path string) (Dir, error) {
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return Dir{}, fmt.Errorf("error creating recordings directory %s: %w", path, err)
+	}
+
+	return Dir{path: path}, nil
+}
+
+// Create creates a new recording of workflow events and returns its ID.
+// Empty namespace or name means all namespaces or workflows were watched.
+func (d Dir) Create(namespace, name string) (string, io.WriteCloser, error) {
+	parts := []string{"all", "all", fmt.Sprint(time.Now().UnixNano())}
+	if namespace != "" {
+		parts[0] = namespace
+	}
+
+	if name != "" {
+		parts[1] = name
+	}
+
+	id := strings.Join(parts, "_")
+
+	f, err := os.OpenFile(filepath.Join(d.path, id+extension), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", nil, fmt.Errorf("error creating recording %s: %w", id, err)
+	}
+
+	return id, f, nil
+}
+
+// Open opens recording with the ID.
+func (d Dir) Open(id string) (io.ReadCloser, error) {
+	if !validID.MatchString(id) {
+		return nil, ErrInvalidID
+	}
+
+	f, err := os.Open(filepath.Join(d.path, id+extension))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("error opening recording %s: %w", id, err)
+	}
+
+	return f, nil
+}
@@ -0,0 +1,145 @@
+package eventrec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+	"go.uber.org/zap"
+)
+
+// buffer is an in-memory recording file.
+type buffer struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *buffer) Close() error {
+	b.closed = true
+	return nil
+}
+
+// Test_recordAndReplay tests that replayed events are the same as recorded ones.
+func Test_recordAndReplay(t *testing.T) {
+	t.Parallel()
+
+	logger := zap.NewNop().Sugar()
+
+	f := func(events []event.Workflow) bool {
+		if len(events) == 0 {
+			return true
+		}
+
+		file := &buffer{}
+		inner := &event.TestWriter{}
+		writer := NewWriter(inner, file, logger)
+		for _, ev := range events {
+			if err := writer.Write(context.Background(), ev); err != nil {
+				return false
+			}
+		}
+
+		if err := writer.Close(); err != nil || !file.closed || !inner.Closed || len(inner.Events) != len(events) {
+			return false
+		}
+
+		reader := NewReader(context.Background(), io.NopCloser(&file.Buffer), 0, logger)
+		for i, want := range events {
+			got, err := reader.Read()
+
+			last := i == len(events)-1
+			if last && err != event.ErrAllRead || !last && err != nil {
+				return false
+			}
+
+			// Compare JSON, as it's the only representation clients see.
+			a, _ := json.Marshal(got)
+			b, _ := json.Marshal(want)
+			if !bytes.Equal(a, b) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestNewReader_speed tests that intervals between events are divided by speed.
+func TestNewReader_speed(t *testing.T) {
+	t.Parallel()
+
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	for _, offset := range []time.Duration{0, time.Second} {
+		if err := encoder.Encode(Entry{Offset: offset, Event: event.Workflow{Name: "a"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reader := NewReader(context.Background(), io.NopCloser(&b), 20, zap.NewNop().Sugar())
+
+	start := time.Now()
+	if _, err := reader.Read(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := reader.Read(); err != event.ErrAllRead {
+		t.Fatalf("last read returned %v, want %v", err, event.ErrAllRead)
+	}
+
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond || elapsed > time.Second {
+		t.Errorf("replay took %v, want about 50ms", elapsed)
+	}
+}
+
+// TestDir tests creating and opening recordings.
+func TestDir(t *testing.T) {
+	t.Parallel()
+
+	d, err := NewDir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, f, err := d.Create("chaos", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.Write([]byte("{}\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := d.Open(id)
+	if err != nil {
+		t.Fatalf("Open(%q) error = %v", id, err)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for id, want := range map[string]error{
+		"../secret":  ErrInvalidID,
+		".hidden":    ErrInvalidID,
+		"chaos_all":  ErrNotFound,
+		"dir/nested": ErrInvalidID,
+	} {
+		if _, err := d.Open(id); err != want {
+			t.Errorf("Open(%q) error = %v, want %v", id, err, want)
+		}
+	}
+}
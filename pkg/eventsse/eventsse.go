@@ -0,0 +1,213 @@
+// Package eventsse uses server-sent events to send workflow events.
+package eventsse
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+	"go.uber.org/zap"
+)
+
+// eventSSE is a server-sent events stream for sending workflow events.
+type eventSSE struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	logger  *zap.SugaredLogger
+
+	m    *sync.Mutex
+	id   *int
+	done chan struct{}
+}
+
+func (es eventSSE) Write(ctx context.Context, ev event.Workflow) error {
+	return es.writeJSON(ctx, ev)
+}
+
+// writeJSON sends value as JSON event.
+func (es eventSSE) writeJSON(ctx context.Context, v interface{}) error {
+	if ctx.Err() != nil {
+		return event.ErrDeadlineExceeded
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		es.logger.Error(err)
+		return event.ErrInvalidEvent
+	}
+
+	es.m.Lock()
+	defer es.m.Unlock()
+
+	*es.id++
+	if _, err := fmt.Fprintf(es.w, "id: %d\ndata: %s\n\n", *es.id, b); err != nil {
+		es.logger.Error(err)
+		return event.ErrConnectionFailed
+	}
+
+	es.flusher.Flush()
+	return nil
+}
+
+// Shutdown sends "shutdown" event telling client that the server is restarting. Clients reconnect after retry interval.
+func (es eventSSE) Shutdown(ctx context.Context, reason string) error {
+	if ctx.Err() != nil {
+		return event.ErrDeadlineExceeded
+	}
+
+	b, err := json.Marshal(struct {
+		Reason string `json:"reason"`
+	}{reason})
+	if err != nil {
+		es.logger.Error(err)
+		return event.ErrInvalidEvent
+	}
+
+	es.m.Lock()
+	defer es.m.Unlock()
+
+	if _, err := fmt.Fprintf(es.w, "event: shutdown\ndata: %s\n\n", b); err != nil {
+		es.logger.Error(err)
+		return event.ErrConnectionFailed
+	}
+
+	es.flusher.Flush()
+	return nil
+}
+
+// heartbeat periodically sends comments to keep idle connection open through proxies.
+func (es eventSSE) heartbeat(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-es.done:
+			return
+		case <-ticker.C:
+			if err := es.comment("heartbeat"); err != nil {
+				es.logger.Warnf("heartbeat failed: %s", err)
+				return
+			}
+		}
+	}
+}
+
+// comment writes a comment line ignored by clients.
+func (es eventSSE) comment(text string) error {
+	es.m.Lock()
+	defer es.m.Unlock()
+
+	// Response writer must not be used after the stream was closed.
+	select {
+	case <-es.done:
+		return nil
+	default:
+	}
+
+	if _, err := fmt.Fprintf(es.w, ": %s\n\n", text); err != nil {
+		return err
+	}
+
+	es.flusher.Flush()
+	return nil
+}
+
+func (es eventSSE) Close() error {
+	es.m.Lock()
+	defer es.m.Unlock()
+
+	close(es.done)
+	return nil
+}
+
+// logSSE is a server-sent events stream for sending log entries.
+type logSSE struct {
+	eventSSE
+}
+
+func (ls logSSE) Write(ctx context.Context, entry event.LogEntry) error {
+	return ls.writeJSON(ctx, entry)
+}
+
+type SSEFactory struct {
+	heartbeat time.Duration
+	retry     time.Duration
+	logger    *zap.SugaredLogger
+}
+
+func (sf SSEFactory) New(w http.ResponseWriter, r *http.Request) (event.Writer, error) {
+	es, err := sf.open(w, r)
+	if err != nil {
+		return nil, err
+	}
+
+	return es, nil
+}
+
+// NewLogWriter returns writer of log entries.
+func (sf SSEFactory) NewLogWriter(w http.ResponseWriter, r *http.Request) (event.LogWriter, error) {
+	es, err := sf.open(w, r)
+	if err != nil {
+		return nil, err
+	}
+
+	return logSSE{eventSSE: es}, nil
+}
+
+// open starts server-sent events stream.
+func (sf SSEFactory) open(w http.ResponseWriter, r *http.Request) (eventSSE, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		sf.logger.Error("response writer doesn't support flushing")
+		return eventSSE{}, event.ErrConnectionFailed
+	}
+
+	// Continue numbering after the last event received by reconnected client.
+	id, _ := strconv.Atoi(r.Header.Get("Last-Event-ID"))
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", sf.retry.Milliseconds()); err != nil {
+		sf.logger.Error(err)
+		return eventSSE{}, event.ErrConnectionFailed
+	}
+
+	flusher.Flush()
+
+	es := eventSSE{
+		w:       w,
+		flusher: flusher,
+		logger:  sf.logger.Named("sse"),
+		m:       &sync.Mutex{},
+		id:      &id,
+		done:    make(chan struct{}),
+	}
+
+	if sf.heartbeat > 0 {
+		go es.heartbeat(sf.heartbeat)
+	}
+
+	return es, nil
+}
+
+func (sf SSEFactory) Close() error {
+	return nil
+}
+
+func NewSSEFactory(heartbeat, retry time.Duration, logger *zap.SugaredLogger) SSEFactory {
+	return SSEFactory{
+		heartbeat: heartbeat,
+		retry:     retry,
+		logger:    logger,
+	}
+}
@@ -0,0 +1,151 @@
+package eventsse
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+	"go.uber.org/zap"
+)
+
+// sseMessage is a parsed server-sent event.
+type sseMessage struct {
+	ID, Data, Retry string
+	Comment         bool
+}
+
+// parseSSE splits event stream into messages.
+func parseSSE(body string) []sseMessage {
+	var (
+		messages []sseMessage
+		current  sseMessage
+		empty    = true
+	)
+
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if !empty {
+				messages = append(messages, current)
+			}
+			current, empty = sseMessage{}, true
+			continue
+		case strings.HasPrefix(line, ":"):
+			current.Comment = true
+		case strings.HasPrefix(line, "id: "):
+			current.ID = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			current.Data = strings.TrimPrefix(line, "data: ")
+		case strings.HasPrefix(line, "retry: "):
+			current.Retry = strings.TrimPrefix(line, "retry: ")
+		}
+		empty = false
+	}
+
+	return messages
+}
+
+// TestSSEFactory tests sending workflow events as server-sent events.
+func TestSSEFactory(t *testing.T) {
+	t.Parallel()
+	r := rand.New(rand.NewSource(0))
+
+	f := func(events []event.Workflow) bool {
+		factory := NewSSEFactory(time.Millisecond, 5*time.Second, zap.NewNop().Sugar())
+
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.Header.Set("Last-Event-ID", "10")
+		recorder := httptest.NewRecorder()
+
+		writer, err := factory.New(recorder, request)
+		if err != nil {
+			t.Error(err)
+			return false
+		}
+
+		for _, ev := range events {
+			if err := writer.Write(context.Background(), ev); err != nil {
+				t.Error(err)
+				return false
+			}
+		}
+
+		time.Sleep(5 * time.Millisecond)
+
+		if err := writer.Close(); err != nil {
+			t.Error(err)
+			return false
+		}
+
+		if ct := recorder.Header().Get("Content-Type"); ct != "text/event-stream" {
+			t.Errorf("content type = %s", ct)
+			return false
+		}
+
+		messages := parseSSE(recorder.Body.String())
+		if len(messages) == 0 || messages[0].Retry != "5000" {
+			t.Error("retry hint wasn't sent first")
+			return false
+		}
+
+		var received []event.Workflow
+		heartbeats := 0
+		for _, msg := range messages[1:] {
+			if msg.Comment {
+				heartbeats++
+				continue
+			}
+
+			var ev event.Workflow
+			if err := json.Unmarshal([]byte(msg.Data), &ev); err != nil {
+				t.Error(err)
+				return false
+			}
+
+			if want := 11 + len(received); msg.ID != strconv.Itoa(want) {
+				t.Errorf("event id = %s, want %d", msg.ID, want)
+				return false
+			}
+
+			received = append(received, ev)
+		}
+
+		if heartbeats == 0 {
+			t.Error("no heartbeats were sent")
+			return false
+		}
+
+		if len(received) != len(events) {
+			t.Errorf("received %d events, sent %d", len(received), len(events))
+			return false
+		}
+
+		for i := range events {
+			want, _ := json.Marshal(events[i])
+			got, _ := json.Marshal(received[i])
+			if !bytes.Equal(want, got) {
+				t.Errorf("event %d was changed in transmission", i)
+				return false
+			}
+		}
+
+		return true
+	}
+
+	if err := quick.Check(f, &quick.Config{Rand: r, MaxCount: 20}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -4,34 +4,113 @@ package eventws
 import (
 	"context"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/iskorotkov/chaos-workflows/pkg/event"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
+var tracer = otel.Tracer("github.com/iskorotkov/chaos-workflows/pkg/eventws")
+
+// controlWriteTimeout limits time spent on sending a control frame.
+const controlWriteTimeout = time.Second
+
 // eventWebsocket is a websocket wrapper for sending workflow events.
 type eventWebsocket struct {
-	conn   *websocket.Conn
+	conn *websocket.Conn
+	// done is closed when the connection is closed by client or fails.
+	done   chan struct{}
 	logger *zap.SugaredLogger
 }
 
 func (ew eventWebsocket) Write(ctx context.Context, ev event.Workflow) error {
+	return ew.writeJSON(ctx, ev)
+}
+
+// writeJSON sends value as JSON message. Every write is recorded as a span.
+func (ew eventWebsocket) writeJSON(ctx context.Context, v interface{}) error {
+	_, span := tracer.Start(ctx, "websocket write", trace.WithSpanKind(trace.SpanKindProducer))
+	defer span.End()
+
 	if deadline, ok := ctx.Deadline(); ok {
 		if err := ew.conn.SetWriteDeadline(deadline); err != nil {
 			ew.logger.Error(err)
+			span.SetStatus(codes.Error, err.Error())
 			return event.ErrConnectionFailed
 		}
 	}
 
-	if err := ew.conn.WriteJSON(ev); err != nil {
+	if err := ew.conn.WriteJSON(v); err != nil {
 		ew.logger.Error(err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return event.ErrConnectionFailed
+	}
+
+	return nil
+}
+
+// Shutdown sends close frame telling client that the server is restarting, so it can reconnect to another instance.
+func (ew eventWebsocket) Shutdown(ctx context.Context, reason string) error {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(controlWriteTimeout)
+	}
+
+	msg := websocket.FormatCloseMessage(websocket.CloseServiceRestart, reason)
+	if err := ew.conn.WriteControl(websocket.CloseMessage, msg, deadline); err != nil {
+		ew.logger.Warn(err)
 		return event.ErrConnectionFailed
 	}
 
 	return nil
 }
 
+// Done returns channel closed when client disconnects.
+func (ew eventWebsocket) Done() <-chan struct{} {
+	return ew.done
+}
+
+// readLoop reads from the connection until client closes it or it fails, e.g. when pong wasn't received in time.
+// Control frames are processed only while reading, so it's required to detect that client went away.
+// Messages from client are discarded.
+func (ew eventWebsocket) readLoop() {
+	defer close(ew.done)
+
+	for {
+		_, _, err := ew.conn.NextReader()
+		if closeErr, ok := err.(*websocket.CloseError); ok {
+			ew.logger.Infow("websocket was closed by client", "code", closeErr.Code, "reason", closeErr.Text)
+			return
+		} else if err != nil {
+			ew.logger.Infof("websocket read failed: %v", err)
+			return
+		}
+	}
+}
+
+// pingLoop sends ping frames every interval until the connection fails.
+func (ew eventWebsocket) pingLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ew.done:
+			return
+		case <-ticker.C:
+			if err := ew.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(controlWriteTimeout)); err != nil {
+				ew.logger.Infof("websocket ping failed: %v", err)
+				return
+			}
+		}
+	}
+}
+
 func (ew eventWebsocket) Close() error {
 	if err := ew.conn.Close(); err != nil {
 		ew.logger.Warnf("websocket was closed with error: %s", err)
@@ -39,29 +118,83 @@ func (ew eventWebsocket) Close() error {
 	return nil
 }
 
+// logWebsocket is a websocket wrapper for sending log entries.
+type logWebsocket struct {
+	eventWebsocket
+}
+
+func (lw logWebsocket) Write(ctx context.Context, entry event.LogEntry) error {
+	return lw.writeJSON(ctx, entry)
+}
+
 type WebsocketFactory struct {
-	upgrader websocket.Upgrader
-	logger   *zap.SugaredLogger
+	upgrader     websocket.Upgrader
+	pingInterval time.Duration
+	pongTimeout  time.Duration
+	logger       *zap.SugaredLogger
 }
 
 func (wf WebsocketFactory) New(w http.ResponseWriter, r *http.Request) (event.Writer, error) {
-	conn, err := wf.upgrader.Upgrade(w, r, nil)
+	ew, err := wf.upgrade(w, r)
+	if err != nil {
+		return nil, err
+	}
+
+	return ew, nil
+}
+
+// NewLogWriter returns writer of log entries.
+func (wf WebsocketFactory) NewLogWriter(w http.ResponseWriter, r *http.Request) (event.LogWriter, error) {
+	ew, err := wf.upgrade(w, r)
+	if err != nil {
+		return nil, err
+	}
+
+	return logWebsocket{eventWebsocket: ew}, nil
+}
+
+// upgrade upgrades connection to websocket connection. Headers already set on w are sent in the upgrade response.
+func (wf WebsocketFactory) upgrade(w http.ResponseWriter, r *http.Request) (eventWebsocket, error) {
+	conn, err := wf.upgrader.Upgrade(w, r, w.Header())
 	if err != nil {
 		wf.logger.Error(err)
-		return nil, event.ErrConnectionFailed
+		return eventWebsocket{}, event.ErrConnectionFailed
 	}
 
-	return eventWebsocket{
+	ew := eventWebsocket{
 		conn:   conn,
+		done:   make(chan struct{}),
 		logger: wf.logger.Named("websocket"),
-	}, nil
+	}
+
+	// Read fails and the session ends if client doesn't respond to pings in time.
+	if wf.pingInterval > 0 {
+		if err := conn.SetReadDeadline(time.Now().Add(wf.pongTimeout)); err != nil {
+			wf.logger.Error(err)
+			_ = conn.Close()
+			return eventWebsocket{}, event.ErrConnectionFailed
+		}
+
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(wf.pongTimeout))
+		})
+
+		go ew.pingLoop(wf.pingInterval)
+	}
+
+	go ew.readLoop()
+
+	return ew, nil
 }
 
 func (wf WebsocketFactory) Close() error {
 	return nil
 }
 
-func NewWebsocketFactory(logger *zap.SugaredLogger) WebsocketFactory {
+// NewWebsocketFactory returns factory of websocket writers sending ping frames every pingInterval. Connection is closed
+// if client doesn't respond with pong within pongTimeout, so it must be greater than pingInterval.
+// Pings are disabled if pingInterval is 0.
+func NewWebsocketFactory(pingInterval, pongTimeout time.Duration, logger *zap.SugaredLogger) WebsocketFactory {
 	return WebsocketFactory{
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:    1024,
@@ -71,6 +204,8 @@ func NewWebsocketFactory(logger *zap.SugaredLogger) WebsocketFactory {
 				return true
 			},
 		},
-		logger: logger,
+		pingInterval: pingInterval,
+		pongTimeout:  pongTimeout,
+		logger:       logger,
 	}
 }
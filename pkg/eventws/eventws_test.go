@@ -0,0 +1,156 @@
+package eventws
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+	"go.uber.org/zap"
+)
+
+// dial opens websocket connection to a test server using factory and returns both ends of the connection.
+func dial(t *testing.T, factory WebsocketFactory) (eventWebsocket, *websocket.Conn) {
+	t.Helper()
+
+	writers := make(chan event.Writer, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writer, err := factory.New(w, r)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		writers <- writer
+	}))
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	writer := (<-writers).(eventWebsocket)
+	t.Cleanup(func() {
+		_ = writer.Close()
+	})
+
+	return writer, conn
+}
+
+// TestWebsocketFactory_liveness tests that connections are ended when clients go away or close them.
+func TestWebsocketFactory_liveness(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		pingInterval time.Duration
+		pongTimeout  time.Duration
+		// client acts as a client after the connection was opened.
+		client     func(t *testing.T, conn *websocket.Conn)
+		wait       time.Duration
+		wantClosed bool
+	}{
+		{
+			name:         "pongs keep connection open",
+			pingInterval: 10 * time.Millisecond,
+			pongTimeout:  50 * time.Millisecond,
+			client: func(t *testing.T, conn *websocket.Conn) {
+				// Pongs are sent by default ping handler while reading.
+				go func() {
+					for {
+						if _, _, err := conn.ReadMessage(); err != nil {
+							return
+						}
+					}
+				}()
+			},
+			wait:       200 * time.Millisecond,
+			wantClosed: false,
+		},
+		{
+			name:         "missing pongs close connection",
+			pingInterval: 10 * time.Millisecond,
+			pongTimeout:  50 * time.Millisecond,
+			client:       func(t *testing.T, conn *websocket.Conn) {},
+			wait:         5 * time.Second,
+			wantClosed:   true,
+		},
+		{
+			name:         "close frame closes connection",
+			pingInterval: 0,
+			client: func(t *testing.T, conn *websocket.Conn) {
+				msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "tab closed")
+				if err := conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second)); err != nil {
+					t.Error(err)
+				}
+			},
+			wait:       5 * time.Second,
+			wantClosed: true,
+		},
+		{
+			name:         "idle connection is kept open without pings",
+			pingInterval: 0,
+			client:       func(t *testing.T, conn *websocket.Conn) {},
+			wait:         100 * time.Millisecond,
+			wantClosed:   false,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			writer, conn := dial(t, NewWebsocketFactory(tt.pingInterval, tt.pongTimeout, zap.NewNop().Sugar()))
+			tt.client(t, conn)
+
+			closed := false
+			select {
+			case <-writer.Done():
+				closed = true
+			case <-time.After(tt.wait):
+			}
+
+			if closed != tt.wantClosed {
+				t.Errorf("connection closed = %v, want %v", closed, tt.wantClosed)
+			}
+		})
+	}
+}
+
+// TestWebsocketFactory_pings tests that pings are sent periodically.
+func TestWebsocketFactory_pings(t *testing.T) {
+	t.Parallel()
+
+	_, conn := dial(t, NewWebsocketFactory(10*time.Millisecond, time.Second, zap.NewNop().Sugar()))
+
+	var pings int32
+	conn.SetPingHandler(func(data string) error {
+		atomic.AddInt32(&pings, 1)
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+	})
+
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&pings) < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("received %d pings, want at least 3", atomic.LoadInt32(&pings))
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,293 @@
+// Package history stores finished workflows in a local file.
+package history
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+	"go.etcd.io/bbolt"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrStorage = errors.New("couldn't access history storage")
+)
+
+// workflowsBucket is a bucket with JSON encoded workflows keyed by namespace, name and start time.
+var workflowsBucket = []byte("workflows")
+
+// Query filters stored workflows.
+type Query struct {
+	// Namespace to return workflows of. All namespaces are used if empty.
+	Namespace string
+	// ChaosType and Severity must match at least one step if set.
+	ChaosType string
+	Severity  string
+	// StartedAfter and StartedBefore limit workflow start time if set.
+	StartedAfter  time.Time
+	StartedBefore time.Time
+	// Limit is a max number of workflows to return. All workflows are returned if zero.
+	Limit int
+}
+
+// matches returns whether workflow satisfies query filters except namespace.
+func (q Query) matches(w event.Workflow) bool {
+	if !q.StartedAfter.IsZero() && !w.StartedAt.After(q.StartedAfter) ||
+		!q.StartedBefore.IsZero() && !w.StartedAt.Before(q.StartedBefore) {
+		return false
+	}
+
+	if q.ChaosType == "" && q.Severity == "" {
+		return true
+	}
+
+	return w.HasStep(q.ChaosType, q.Severity)
+}
+
+// Store keeps finished workflows in BoltDB file.
+type Store struct {
+	db       *bbolt.DB
+	recorded *recordedRuns
+	logger   *zap.SugaredLogger
+}
+
+// recordedRuns keeps keys of recorded runs, so events of the same run coming from several subscribers
+// or list requests are written only once. Values tell whether run was recorded with stages.
+type recordedRuns struct {
+	mu   sync.Mutex
+	keys map[string]bool
+}
+
+// add marks a run as recorded and returns whether it needs to be written.
+// Run recorded without stages is written again when its stages become known.
+func (r *recordedRuns) add(k string, withStages bool) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if recordedWithStages, ok := r.keys[k]; ok && (recordedWithStages || !withStages) {
+		return false
+	}
+
+	r.keys[k] = withStages
+	return true
+}
+
+// remove unmarks runs, e.g. after failed write or pruning.
+func (r *recordedRuns) remove(keys []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, k := range keys {
+		delete(r.keys, k)
+	}
+}
+
+// NewStore opens or creates store file at path.
+func NewStore(path string, logger *zap.SugaredLogger) (Store, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: time.Second * 5})
+	if err != nil {
+		return Store{}, fmt.Errorf("error opening history file %s: %w", path, err)
+	}
+
+	if err := db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(workflowsBucket)
+		return err
+	}); err != nil {
+		_ = db.Close()
+		return Store{}, fmt.Errorf("error creating history bucket: %w", err)
+	}
+
+	return Store{db: db, recorded: &recordedRuns{keys: make(map[string]bool)}, logger: logger}, nil
+}
+
+// key returns a key of a workflow run. Workflows with the same name can be submitted again, so start time is included.
+func key(w event.Workflow) []byte {
+	return []byte(fmt.Sprintf("%s/%s/%s", w.Namespace, w.Name, w.StartedAt.UTC().Format(time.RFC3339Nano)))
+}
+
+// Save stores a workflow, replacing previously stored state of the same run.
+// Stored state with stages isn't replaced with state without them, e.g. with archived workflow from list.
+func (s Store) Save(w event.Workflow) error {
+	return s.SaveAll([]event.Workflow{w})
+}
+
+// SaveAll stores workflows in a single transaction the same way as Save.
+func (s Store) SaveAll(workflows []event.Workflow) error {
+	if len(workflows) == 0 {
+		return nil
+	}
+
+	values := make([][]byte, len(workflows))
+	for i, w := range workflows {
+		// Type is only meaningful for events in a stream.
+		w.Type = ""
+
+		value, err := json.Marshal(w)
+		if err != nil {
+			s.logger.Error(err.Error())
+			return ErrStorage
+		}
+
+		values[i] = value
+	}
+
+	if err := s.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(workflowsBucket)
+
+		for i, w := range workflows {
+			if len(w.Stages) == 0 {
+				if old := b.Get(key(w)); old != nil {
+					var stored event.Workflow
+					if err := json.Unmarshal(old, &stored); err == nil && len(stored.Stages) != 0 {
+						continue
+					}
+				}
+			}
+
+			if err := b.Put(key(w), values[i]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		s.logger.Error(err.Error())
+		return ErrStorage
+	}
+
+	return nil
+}
+
+// Record saves finished workflows in a single transaction. Errors are only logged, so it can be called when passing events to clients.
+// Each run is written once, so repeated events of the same run don't cause repeated writes.
+func (s Store) Record(workflows ...event.Workflow) {
+	if _, err := s.recordAll(workflows); err != nil {
+		s.logger.Infow("workflows weren't recorded", "count", len(workflows), "error", err)
+	}
+}
+
+// recordAll saves finished workflows which weren't recorded yet in a single transaction and returns a number of written workflows.
+func (s Store) recordAll(workflows []event.Workflow) (int, error) {
+	var (
+		finished []event.Workflow
+		keys     []string
+	)
+
+	for _, w := range workflows {
+		if !w.Finished() {
+			continue
+		}
+
+		if k := string(key(w)); s.recorded.add(k, len(w.Stages) != 0) {
+			finished = append(finished, w)
+			keys = append(keys, k)
+		}
+	}
+
+	if err := s.SaveAll(finished); err != nil {
+		s.recorded.remove(keys)
+		return 0, err
+	}
+
+	return len(finished), nil
+}
+
+// List returns stored workflows matching query sorted by start time in descending order.
+func (s Store) List(q Query) ([]event.Workflow, error) {
+	var prefix []byte
+	if q.Namespace != "" {
+		prefix = []byte(q.Namespace + "/")
+	}
+
+	workflows := make([]event.Workflow, 0)
+	if err := s.db.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(workflowsBucket).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var w event.Workflow
+			if err := json.Unmarshal(v, &w); err != nil {
+				return fmt.Errorf("error unmarshaling workflow %s: %w", k, err)
+			}
+
+			if q.matches(w) {
+				workflows = append(workflows, w)
+			}
+		}
+
+		return nil
+	}); err != nil {
+		s.logger.Error(err.Error())
+		return nil, ErrStorage
+	}
+
+	sort.SliceStable(workflows, func(i, j int) bool {
+		return workflows[i].StartedAt.After(workflows[j].StartedAt)
+	})
+
+	if q.Limit != 0 && len(workflows) > q.Limit {
+		workflows = workflows[:q.Limit]
+	}
+
+	return workflows, nil
+}
+
+// Prune deletes workflows finished before t and returns a number of deleted workflows.
+// Start time is used for workflows without finish time.
+func (s Store) Prune(t time.Time) (int, error) {
+	deleted := 0
+	if err := s.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(workflowsBucket)
+
+		var keys [][]byte
+		if err := b.ForEach(func(k, v []byte) error {
+			var w event.Workflow
+			if err := json.Unmarshal(v, &w); err != nil {
+				return fmt.Errorf("error unmarshaling workflow %s: %w", k, err)
+			}
+
+			finishedAt := w.StartedAt
+			if w.FinishedAt != nil {
+				finishedAt = *w.FinishedAt
+			}
+
+			if finishedAt.Before(t) {
+				keys = append(keys, append([]byte(nil), k...))
+			}
+
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		// Keys can't be deleted while iterating over bucket.
+		for _, k := range keys {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		pruned := make([]string, len(keys))
+		for i, k := range keys {
+			pruned[i] = string(k)
+		}
+		s.recorded.remove(pruned)
+
+		deleted = len(keys)
+		return nil
+	}); err != nil {
+		s.logger.Error(err.Error())
+		return 0, ErrStorage
+	}
+
+	return deleted, nil
+}
+
+// Close closes store file.
+func (s Store) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,400 @@
+package history
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	"github.com/iskorotkov/chaos-workflows/pkg/argo"
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+	"go.uber.org/zap"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestStore(t *testing.T) Store {
+	t.Helper()
+
+	s, err := NewStore(filepath.Join(t.TempDir(), "history.db"), zap.NewNop().Sugar())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		if err := s.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	return s
+}
+
+func testWorkflow(namespace, name string, startedAt time.Time, chaosType string) event.Workflow {
+	finishedAt := startedAt.Add(time.Minute)
+	return event.Workflow{
+		Name:       name,
+		Namespace:  namespace,
+		StartedAt:  startedAt,
+		FinishedAt: &finishedAt,
+		Status:     "succeeded",
+		Stages: []event.Stage{{
+			Status: "succeeded",
+			Steps:  []event.Step{{Name: "step", Type: chaosType, Severity: "harmless", Status: "succeeded"}},
+		}},
+	}
+}
+
+func names(workflows []event.Workflow) []string {
+	var res []string
+	for _, w := range workflows {
+		res = append(res, w.Namespace+"/"+w.Name)
+	}
+
+	return res
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// TestStore_List tests filtering and sorting of stored workflows.
+func TestStore_List(t *testing.T) {
+	t.Parallel()
+
+	s := newTestStore(t)
+	start := time.Date(2021, 11, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, w := range []event.Workflow{
+		testWorkflow("chaos", "a", start, "pod-delete"),
+		testWorkflow("chaos", "b", start.Add(time.Hour), "node-cpu-hog"),
+		testWorkflow("chaos-2", "c", start.Add(2*time.Hour), "pod-delete"),
+		// The same workflow submitted again.
+		testWorkflow("chaos", "a", start.Add(3*time.Hour), "pod-delete"),
+	} {
+		if err := s.Save(w); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []string
+	}{
+		{"all", Query{}, []string{"chaos/a", "chaos-2/c", "chaos/b", "chaos/a"}},
+		{"namespace", Query{Namespace: "chaos"}, []string{"chaos/a", "chaos/b", "chaos/a"}},
+		{"chaos type", Query{ChaosType: "pod-delete"}, []string{"chaos/a", "chaos-2/c", "chaos/a"}},
+		{"severity", Query{Severity: "critical"}, nil},
+		{"time range", Query{StartedAfter: start, StartedBefore: start.Add(3 * time.Hour)}, []string{"chaos-2/c", "chaos/b"}},
+		{"limit", Query{Limit: 2}, []string{"chaos/a", "chaos-2/c"}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := s.List(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !equal(names(got), tt.want) {
+				t.Errorf("List() = %v, want %v", names(got), tt.want)
+			}
+		})
+	}
+}
+
+// TestStore_Save tests that stages aren't replaced with an empty state of the same run.
+func TestStore_Save(t *testing.T) {
+	t.Parallel()
+
+	s := newTestStore(t)
+	w := testWorkflow("chaos", "a", time.Now(), "pod-delete")
+
+	stageless := w
+	stageless.Stages = nil
+
+	for _, saved := range []event.Workflow{w, stageless} {
+		if err := s.Save(saved); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := s.List(Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 1 || len(got[0].Stages) != 1 {
+		t.Errorf("stored workflows = %+v, want one workflow with stages", got)
+	}
+}
+
+// TestStore_Record tests that only finished workflows are recorded.
+func TestStore_Record(t *testing.T) {
+	t.Parallel()
+
+	s := newTestStore(t)
+	start := time.Now()
+
+	running := testWorkflow("chaos", "a", start, "pod-delete")
+	running.Status = "running"
+	running.Type = "MODIFIED"
+	s.Record(running)
+
+	finished := testWorkflow("chaos", "b", start, "pod-delete")
+	finished.Type = "MODIFIED"
+	s.Record(finished)
+
+	got, err := s.List(Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !equal(names(got), []string{"chaos/b"}) || got[0].Type != "" {
+		t.Errorf("recorded workflows = %+v, want finished workflow without event type", got)
+	}
+}
+
+// TestStore_Record_once tests that workflows are recorded in a batch and each run is written once.
+func TestStore_Record_once(t *testing.T) {
+	t.Parallel()
+
+	start := time.Now()
+	stageless := func(w event.Workflow) event.Workflow {
+		w.Stages = nil
+		return w
+	}
+
+	tests := []struct {
+		name string
+		// batches are passed to Record one after another.
+		batches    [][]event.Workflow
+		wantNames  []string
+		wantStatus string
+	}{
+		{
+			name: "batch",
+			batches: [][]event.Workflow{{
+				testWorkflow("chaos", "a", start, "pod-delete"),
+				testWorkflow("chaos", "b", start.Add(time.Second), "pod-delete"),
+			}},
+			wantNames:  []string{"chaos/b", "chaos/a"},
+			wantStatus: "succeeded",
+		},
+		{
+			name: "repeated event isn't written again",
+			batches: [][]event.Workflow{
+				{testWorkflow("chaos", "a", start, "pod-delete")},
+				{func() event.Workflow {
+					w := testWorkflow("chaos", "a", start, "pod-delete")
+					w.Status = "failed"
+					return w
+				}()},
+			},
+			wantNames:  []string{"chaos/a"},
+			wantStatus: "succeeded",
+		},
+		{
+			name: "stages replace stageless state",
+			batches: [][]event.Workflow{
+				{stageless(testWorkflow("chaos", "a", start, "pod-delete"))},
+				{func() event.Workflow {
+					w := testWorkflow("chaos", "a", start, "pod-delete")
+					w.Status = "failed"
+					return w
+				}()},
+			},
+			wantNames:  []string{"chaos/a"},
+			wantStatus: "failed",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := newTestStore(t)
+			for _, batch := range tt.batches {
+				s.Record(batch...)
+			}
+
+			got, err := s.List(Query{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !equal(names(got), tt.wantNames) {
+				t.Fatalf("recorded workflows = %v, want %v", names(got), tt.wantNames)
+			}
+
+			if got[0].Status != tt.wantStatus {
+				t.Errorf("recorded status = %q, want %q", got[0].Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+// TestStore_Prune tests that workflows finished before the time are deleted.
+func TestStore_Prune(t *testing.T) {
+	t.Parallel()
+
+	s := newTestStore(t)
+	now := time.Now()
+
+	for _, w := range []event.Workflow{
+		testWorkflow("chaos", "old", now.Add(-48*time.Hour), "pod-delete"),
+		testWorkflow("chaos", "new", now.Add(-time.Hour), "pod-delete"),
+	} {
+		if err := s.Save(w); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deleted, err := s.Prune(now.Add(-24 * time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.List(Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if deleted != 1 || !equal(names(got), []string{"chaos/new"}) {
+		t.Errorf("Prune() deleted %d, left %v, want 1 deleted and [chaos/new] left", deleted, names(got))
+	}
+}
+
+// testLister returns unfinished workflows in a single page and pages of finished workflows.
+type testLister struct {
+	unfinished []v1alpha1.Workflow
+	pages      [][]v1alpha1.Workflow
+	// opts are options of finished workflows list calls.
+	opts []argo.ListOptions
+}
+
+func (t *testLister) List(_ context.Context, opts argo.ListOptions) ([]v1alpha1.Workflow, string, error) {
+	if len(opts.Phases) == len(unfinishedPhases) && opts.Phases[0] == unfinishedPhases[0] {
+		return t.unfinished, "", nil
+	}
+
+	t.opts = append(t.opts, opts)
+
+	page := len(t.opts) - 1
+	if page+1 < len(t.pages) {
+		return t.pages[page], "next", nil
+	}
+
+	return t.pages[page], "", nil
+}
+
+func testArgoWorkflow(name string, phase v1alpha1.WorkflowPhase, startedAt time.Time) v1alpha1.Workflow {
+	wf := v1alpha1.Workflow{
+		ObjectMeta: v1.ObjectMeta{Name: name, Namespace: "chaos"},
+		Status: v1alpha1.WorkflowStatus{
+			Phase:     phase,
+			StartedAt: v1.Time{Time: startedAt},
+		},
+	}
+
+	if phase != v1alpha1.WorkflowRunning {
+		wf.Status.FinishedAt = v1.Time{Time: startedAt.Add(time.Minute)}
+	}
+
+	return wf
+}
+
+// TestSweeper_Sweep tests that all pages of finished workflows are saved and expired ones are pruned.
+func TestSweeper_Sweep(t *testing.T) {
+	t.Parallel()
+
+	s := newTestStore(t)
+	now := time.Now()
+
+	if err := s.Save(testWorkflow("chaos", "expired", now.Add(-48*time.Hour), "pod-delete")); err != nil {
+		t.Fatal(err)
+	}
+
+	finished := func(name string) v1alpha1.Workflow {
+		return testArgoWorkflow(name, v1alpha1.WorkflowSucceeded, now.Add(-time.Hour))
+	}
+
+	lister := &testLister{pages: [][]v1alpha1.Workflow{{finished("a")}, {finished("b")}}}
+	NewSweeper(lister, s, time.Minute, 24*time.Hour, zap.NewNop().Sugar()).Sweep(context.Background())
+
+	if len(lister.opts) != 2 || lister.opts[1].Continue != "next" || len(lister.opts[0].Phases) != len(finishedPhases) {
+		t.Errorf("unexpected list calls %+v", lister.opts)
+	}
+
+	got, err := s.List(Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 2 || got[0].Name == "expired" || got[1].Name == "expired" {
+		t.Errorf("stored workflows = %v, want swept workflows only", names(got))
+	}
+}
+
+// TestSweeper_sweep tests that archived workflows are listed since the previous cutoff and the next cutoff
+// is start time of the oldest unfinished workflow.
+func TestSweeper_sweep(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	since := now.Add(-time.Hour)
+	oldest := now.Add(-30 * time.Minute)
+
+	lister := &testLister{
+		unfinished: []v1alpha1.Workflow{
+			testArgoWorkflow("new", v1alpha1.WorkflowRunning, now.Add(-time.Minute)),
+			testArgoWorkflow("old", v1alpha1.WorkflowRunning, oldest),
+		},
+		pages: [][]v1alpha1.Workflow{nil},
+	}
+
+	next, ok := NewSweeper(lister, newTestStore(t), time.Minute, 0, zap.NewNop().Sugar()).sweep(context.Background(), since)
+	if !ok {
+		t.Error("sweep() = false, want true")
+	}
+
+	if !next.Equal(oldest) {
+		t.Errorf("sweep() next = %v, want %v", next, oldest)
+	}
+
+	if len(lister.opts) != 1 || !lister.opts[0].StartedAfter.Equal(since) {
+		t.Errorf("unexpected list calls %+v", lister.opts)
+	}
+}
+
+// TestSweeper_sweep_recorded tests that runs already recorded aren't written again by the next sweep.
+func TestSweeper_sweep_recorded(t *testing.T) {
+	t.Parallel()
+
+	s := newTestStore(t)
+	wf := testArgoWorkflow("a", v1alpha1.WorkflowSucceeded, time.Now().Add(-time.Hour))
+	sweeper := NewSweeper(&testLister{pages: [][]v1alpha1.Workflow{{wf}}}, s, time.Minute, 0, zap.NewNop().Sugar())
+
+	if _, saved, err := sweeper.save(context.Background(), time.Time{}); err != nil || saved != 1 {
+		t.Fatalf("save() saved %d, %v, want 1 workflow", saved, err)
+	}
+
+	sweeper.lister = &testLister{pages: [][]v1alpha1.Workflow{{wf}}}
+	if _, saved, err := sweeper.save(context.Background(), time.Time{}); err != nil || saved != 0 {
+		t.Errorf("save() saved %d, %v, want no workflows", saved, err)
+	}
+}
@@ -0,0 +1,167 @@
+package history
+
+import (
+	"context"
+	"time"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	"github.com/iskorotkov/chaos-workflows/pkg/argo"
+	"github.com/iskorotkov/chaos-workflows/pkg/event"
+	"go.uber.org/zap"
+)
+
+// sweepPageSize is a number of workflows requested from Argo at once during sweep.
+const sweepPageSize = 100
+
+// finishedPhases lists phases of finished workflows.
+var finishedPhases = []string{"Succeeded", "Failed", "Error"}
+
+// unfinishedPhases lists phases of started workflows which haven't finished yet.
+var unfinishedPhases = []string{"Pending", "Running"}
+
+// Lister lists workflows.
+type Lister interface {
+	List(ctx context.Context, opts argo.ListOptions) ([]v1alpha1.Workflow, string, error)
+}
+
+// Sweeper periodically saves finished workflows nobody watched and deletes workflows older than retention period.
+// Runs already recorded by the store aren't written again.
+type Sweeper struct {
+	lister   Lister
+	store    Store
+	interval time.Duration
+	// retention is a period to keep workflows for after they finished. Workflows are kept forever if zero.
+	retention time.Duration
+	logger    *zap.SugaredLogger
+}
+
+func NewSweeper(lister Lister, store Store, interval, retention time.Duration, logger *zap.SugaredLogger) Sweeper {
+	return Sweeper{
+		lister:    lister,
+		store:     store,
+		interval:  interval,
+		retention: retention,
+		logger:    logger,
+	}
+}
+
+// Run sweeps workflows immediately and then once per interval until context is cancelled.
+// After the first successful sweep, only archived workflows started after the oldest workflow that was unfinished
+// during the previous successful sweep are listed, so workflows archived and garbage-collected between sweeps are saved too.
+func (s Sweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	var since time.Time
+	for {
+		if next, ok := s.sweep(ctx, since); ok {
+			since = next
+		}
+
+		select {
+		case <-ctx.Done():
+			s.logger.Debug("sweeper stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep saves all finished workflows and prunes expired ones.
+func (s Sweeper) Sweep(ctx context.Context) {
+	s.sweep(ctx, time.Time{})
+}
+
+// sweep saves finished workflows, skipping archived ones started before since unless it's zero, and prunes expired ones.
+// It returns start time of the oldest unfinished workflow to use as since in the next sweep and whether all workflows were saved.
+func (s Sweeper) sweep(ctx context.Context, since time.Time) (time.Time, bool) {
+	next, saved, saveErr := s.save(ctx, since)
+	if saveErr != nil {
+		s.logger.Infof("error saving finished workflows: %v", saveErr)
+	}
+
+	pruned := 0
+	if s.retention != 0 {
+		var err error
+		pruned, err = s.store.Prune(time.Now().Add(-s.retention))
+		if err != nil {
+			s.logger.Infof("error pruning expired workflows: %v", err)
+		}
+	}
+
+	s.logger.Debugw("sweep completed", "saved", saved, "pruned", pruned, "since", since)
+	return next, saveErr == nil
+}
+
+// save lists finished workflows page by page and saves each page in a single transaction.
+// It returns start time of the oldest unfinished workflow and a number of saved workflows.
+func (s Sweeper) save(ctx context.Context, since time.Time) (time.Time, int, error) {
+	// Sweeps aren't made on behalf of users, so the service token is used.
+	ctx, cancel := context.WithTimeout(argo.WithServiceToken(ctx), s.interval)
+	defer cancel()
+
+	// Unfinished workflows are listed first, so every workflow missing from both lists either finished
+	// before this sweep or starts after the returned time.
+	oldest, err := s.oldestUnfinished(ctx)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+
+	saved := 0
+	opts := argo.ListOptions{Phases: finishedPhases, Limit: sweepPageSize, StartedAfter: since}
+	for {
+		dtos, next, err := s.lister.List(ctx, opts)
+		if err != nil {
+			return time.Time{}, saved, err
+		}
+
+		workflows := make([]event.Workflow, 0, len(dtos))
+		for _, dto := range dtos {
+			w, ok := event.FromWorkflow(dto)
+			if !ok {
+				s.logger.Infow("skipping workflow: error converting raw workflow to custom type", "namespace", dto.Namespace, "name", dto.Name)
+				continue
+			}
+
+			workflows = append(workflows, w)
+		}
+
+		written, err := s.store.recordAll(workflows)
+		if err != nil {
+			return time.Time{}, saved, err
+		}
+
+		saved += written
+
+		if next == "" {
+			return oldest, saved, nil
+		}
+
+		opts.Continue = next
+	}
+}
+
+// oldestUnfinished returns start time of the oldest unfinished workflow or the current time if there are none.
+func (s Sweeper) oldestUnfinished(ctx context.Context) (time.Time, error) {
+	oldest := time.Now()
+
+	opts := argo.ListOptions{Phases: unfinishedPhases, Limit: sweepPageSize}
+	for {
+		dtos, next, err := s.lister.List(ctx, opts)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		for _, dto := range dtos {
+			if startedAt := dto.Status.StartedAt.Time; !startedAt.IsZero() && startedAt.Before(oldest) {
+				oldest = startedAt
+			}
+		}
+
+		if next == "" {
+			return oldest, nil
+		}
+
+		opts.Continue = next
+	}
+}